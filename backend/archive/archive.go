@@ -0,0 +1,317 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+	"workout-tracker/uploads"
+)
+
+// SchemaVersion 导出文件格式版本，数据结构不兼容时递增
+const SchemaVersion = 1
+
+const (
+	manifestFile  = "manifest.json"
+	exercisesFile = "exercises.json"
+	workoutsFile  = "workouts.json"
+	sessionsFile  = "sessions.json"
+	taxonomyFile  = "taxonomy.json" // 早期的导出包中没有
+	uploadsPrefix = "uploads/"
+)
+
+// 导入包的大小上限，避免超大请求或压缩炸弹占满磁盘和内存
+const (
+	MaxImportSize       = 2 << 30 // 上传的 zip 文件
+	MaxUncompressedSize = 4 << 30 // 解压后所有文件的总大小
+)
+
+// ErrTooLarge 导入包超过大小上限
+var ErrTooLarge = errors.New("archive too large")
+
+// Manifest 导出包清单
+type Manifest struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	ExportedAt    time.Time      `json:"exportedAt"`
+	Counts        map[string]int `json:"counts"`
+	Files         []ManifestFile `json:"files"`
+}
+
+// ManifestFile 导出包中的文件及校验和
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Service 全量数据的导出与导入
+type Service struct {
+	repo  *repository.FileRepository
+	store *uploads.Store
+}
+
+func NewService(repo *repository.FileRepository, store *uploads.Store) *Service {
+	return &Service{repo: repo, store: store}
+}
+
+// Export 将全部数据和引用的上传文件以 zip 格式写入 w
+func (s *Service) Export(ctx context.Context, w io.Writer) error {
+	repo := s.repo.WithContext(ctx)
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return err
+	}
+	workouts, err := repo.GetAllWorkouts()
+	if err != nil {
+		return err
+	}
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		return err
+	}
+	terms, err := repo.GetAllTaxonomyTerms()
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	manifest := Manifest{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    time.Now(),
+		Counts: map[string]int{
+			"exercises": len(exercises),
+			"workouts":  len(workouts),
+			"sessions":  len(sessions),
+			"taxonomy":  len(terms),
+		},
+	}
+
+	if exercises == nil {
+		exercises = []models.Exercise{}
+	}
+	if workouts == nil {
+		workouts = []models.Workout{}
+	}
+	if sessions == nil {
+		sessions = []models.WorkoutSession{}
+	}
+	if terms == nil {
+		terms = []models.TaxonomyTerm{}
+	}
+	entries := []struct {
+		name string
+		data interface{}
+	}{
+		{exercisesFile, exercises},
+		{workoutsFile, workouts},
+		{sessionsFile, sessions},
+		{taxonomyFile, terms},
+	}
+	for _, entry := range entries {
+		bytes, err := json.MarshalIndent(entry.data, "", "  ")
+		if err != nil {
+			return err
+		}
+		file, err := writeEntry(zw, entry.name, strings.NewReader(string(bytes)))
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	uploadCount := 0
+	for _, name := range referencedUploads(exercises) {
+		src, _, err := s.store.Open(ctx, name)
+		if err != nil {
+			if errors.Is(err, uploads.ErrNotFound) {
+				continue
+			}
+			return err
+		}
+		file, err := writeEntry(zw, uploadsPrefix+name, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+		uploadCount++
+	}
+	manifest.Counts["uploads"] = uploadCount
+
+	// 清单最后写入，包含前面所有文件的校验和
+	mw, err := zw.Create(manifestFile)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(mw)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeEntry(zw *zip.Writer, name string, r io.Reader) (ManifestFile, error) {
+	w, err := zw.Create(name)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, hash), r)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{Path: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// 动作引用的上传文件名，包括图片的缩放版本和演示视频的封面
+func referencedUploads(exercises []models.Exercise) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(url string) {
+		name, ok := uploads.NameFromURL(url)
+		if ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, exercise := range exercises {
+		add(exercise.ImageURL)
+		for _, variant := range exercise.ImageVariants {
+			add(variant.URL)
+		}
+		for _, clip := range exercise.Clips {
+			add(clip.URL)
+			add(clip.Poster)
+		}
+	}
+	return names
+}
+
+// 读取并校验导出包
+type bundle struct {
+	manifest  Manifest
+	exercises []models.Exercise
+	workouts  []models.Workout
+	sessions  []models.WorkoutSession
+	taxonomy  []models.TaxonomyTerm // 导出包中没有分类时为 nil
+	uploads   map[string]*zip.File
+	checksums map[string]string // 上传文件名 -> SHA256
+}
+
+func readBundle(zr *zip.Reader) (*bundle, error) {
+	// 按文件头中的大小计算，读取时超出声明大小的条目会被 archive/zip 拒绝
+	var total uint64
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		if f.UncompressedSize64 > MaxUncompressedSize-total {
+			return nil, fmt.Errorf("%w: more than %d bytes uncompressed", ErrTooLarge, MaxUncompressedSize)
+		}
+		total += f.UncompressedSize64
+		files[f.Name] = f
+	}
+
+	mf, ok := files[manifestFile]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s", manifestFile)
+	}
+	b := &bundle{uploads: make(map[string]*zip.File), checksums: make(map[string]string)}
+	if err := readJSON(mf, &b.manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if b.manifest.SchemaVersion < 1 || b.manifest.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d", b.manifest.SchemaVersion)
+	}
+
+	listed := make(map[string]bool)
+	for _, entry := range b.manifest.Files {
+		listed[entry.Path] = true
+		f, ok := files[entry.Path]
+		if !ok {
+			return nil, fmt.Errorf("file %s listed in manifest is missing", entry.Path)
+		}
+		if err := verify(f, entry); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(entry.Path, uploadsPrefix) {
+			name := strings.TrimPrefix(entry.Path, uploadsPrefix)
+			if name == "" || name != path.Base(name) || name == ".." {
+				return nil, fmt.Errorf("invalid upload path %s", entry.Path)
+			}
+			b.uploads[name] = f
+			b.checksums[name] = entry.SHA256
+		}
+	}
+
+	for name, target := range map[string]interface{}{
+		exercisesFile: &b.exercises,
+		workoutsFile:  &b.workouts,
+		sessionsFile:  &b.sessions,
+	} {
+		f, ok := files[name]
+		if !ok || !listed[name] {
+			return nil, fmt.Errorf("archive has no verified %s", name)
+		}
+		if err := readJSON(f, target); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	if f, ok := files[taxonomyFile]; ok && listed[taxonomyFile] {
+		b.taxonomy = []models.TaxonomyTerm{}
+		if err := readJSON(f, &b.taxonomy); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", taxonomyFile, err)
+		}
+	}
+	return b, nil
+}
+
+func readJSON(f *zip.File, v interface{}) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
+
+func verify(f *zip.File, entry ManifestFile) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, r)
+	if err != nil {
+		return err
+	}
+	if size != entry.Size || hex.EncodeToString(hash.Sum(nil)) != entry.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", entry.Path)
+	}
+	return nil
+}
+
+// 存储中已有文件的 SHA256
+func (s *Service) storedSHA256(ctx context.Context, name string) (string, error) {
+	f, _, err := s.store.Open(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
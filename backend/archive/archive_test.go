@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"workout-tracker/uploads"
+)
+
+// 生成只有文件头的 zip，条目声明的解压大小为 sizes
+func zipWithSizes(t *testing.T, sizes ...uint64) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, size := range sizes {
+		_, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               "uploads/" + string(rune('a'+i)) + ".jpg",
+			Method:             zip.Deflate,
+			UncompressedSize64: size,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestReadBundleLimitsUncompressedSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		sizes  []uint64
+		tooBig bool
+	}{
+		{"single entry over limit", []uint64{MaxUncompressedSize + 1}, true},
+		{"entries add up over limit", []uint64{MaxUncompressedSize / 2, MaxUncompressedSize / 2, 1}, true},
+		{"overflowing sizes", []uint64{1, math.MaxUint64}, true},
+		{"within limit", []uint64{MaxUncompressedSize / 2, MaxUncompressedSize / 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readBundle(zipWithSizes(t, tt.sizes...))
+			if errors.Is(err, ErrTooLarge) != tt.tooBig {
+				t.Errorf("err = %v, too large = %v", err, tt.tooBig)
+			}
+			// 未超过上限时因为没有清单而失败
+			if !tt.tooBig && err == nil {
+				t.Error("archive without manifest accepted")
+			}
+		})
+	}
+}
+
+func TestCheckUploadSniffsContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name    string
+		content []byte
+		err     error
+	}{
+		{"a.png", png, nil},
+		{"a.jpg", png, uploads.ErrExtensionMismatch},
+		{"a.png", []byte("<html><script>alert(1)</script></html>"), uploads.ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create(uploadsPrefix + tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(tt.content)
+		zw.Close()
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = checkUpload(zr.File[0], tt.name)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+	"workout-tracker/effort"
+	"workout-tracker/models"
+	"workout-tracker/prescription"
+	"workout-tracker/records"
+	"workout-tracker/taxonomy"
+	"workout-tracker/tracking"
+	"workout-tracker/uploads"
+
+	"github.com/google/uuid"
+)
+
+// 导入模式
+const (
+	ModeMerge   = "merge"   // 与现有数据合并，ID冲突且内容不同时分配新ID
+	ModeReplace = "replace" // 用导入数据替换全部现有数据
+)
+
+// EntityReport 单类数据的导入统计
+type EntityReport struct {
+	Created  int `json:"created"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`  // 与现有数据完全一致
+	Remapped int `json:"remapped"` // ID冲突后分配了新ID
+	Deleted  int `json:"deleted"`  // 替换模式下被删除的现有数据
+}
+
+// ImportReport 导入结果，dry-run 时只生成报告不写入
+type ImportReport struct {
+	Mode          string            `json:"mode"`
+	DryRun        bool              `json:"dryRun"`
+	SchemaVersion int               `json:"schemaVersion"`
+	ExportedAt    string            `json:"exportedAt"`
+	Exercises     EntityReport      `json:"exercises"`
+	Workouts      EntityReport      `json:"workouts"`
+	Sessions      EntityReport      `json:"sessions"`
+	Taxonomy      EntityReport      `json:"taxonomy"`
+	Uploads       EntityReport      `json:"uploads"`
+	Remapped      map[string]string `json:"remapped"` // 原ID或文件名 -> 新ID或文件名
+	Warnings      []string          `json:"warnings"`
+}
+
+// Import 导入 zip 导出包
+func (s *Service) Import(ctx context.Context, zr *zip.Reader, mode string, dryRun bool) (*ImportReport, error) {
+	if mode == "" {
+		mode = ModeMerge
+	}
+	if mode != ModeMerge && mode != ModeReplace {
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	b, err := readBundle(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		Mode:          mode,
+		DryRun:        dryRun,
+		SchemaVersion: b.manifest.SchemaVersion,
+		ExportedAt:    b.manifest.ExportedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Remapped:      make(map[string]string),
+		Warnings:      []string{},
+	}
+
+	repo := s.repo.WithContext(ctx)
+	existingExercises, err := repo.GetAllExercises()
+	if err != nil {
+		return nil, err
+	}
+	existingWorkouts, err := repo.GetAllWorkouts()
+	if err != nil {
+		return nil, err
+	}
+	existingSessions, err := repo.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+	existingTerms, err := repo.GetAllTaxonomyTerms()
+	if err != nil {
+		return nil, err
+	}
+
+	// 上传文件：同名且内容相同则跳过，内容不同则重命名
+	uploadNames := make(map[string]string)
+	for name, f := range b.uploads {
+		// 与直接上传一样只接受允许的图片和视频
+		if err := checkUpload(f, name); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("upload %s skipped: %v", name, err))
+			delete(b.uploads, name)
+			continue
+		}
+
+		target := name
+		localHash, err := s.storedSHA256(ctx, name)
+		switch {
+		case errors.Is(err, uploads.ErrNotFound):
+			report.Uploads.Created++
+		case err != nil:
+			return nil, err
+		case localHash == b.checksums[name]:
+			report.Uploads.Skipped++
+		default:
+			// 与上传一样按内容命名
+			target = b.checksums[name] + filepath.Ext(name)
+			report.Remapped[name] = target
+			report.Uploads.Remapped++
+		}
+		uploadNames[name] = target
+	}
+
+	// 分类按ID合并：合并模式下保留本地已有的分类，替换模式下使用导出包中的分类
+	terms, known := s.importTaxonomy(b, existingTerms, mode, report)
+
+	exerciseIDs := make(map[string]string)
+	exercises := make([]models.Exercise, 0, len(b.exercises))
+	existingExerciseByID := make(map[string]models.Exercise)
+	for _, exercise := range existingExercises {
+		existingExerciseByID[exercise.ID] = exercise
+	}
+	for _, exercise := range b.exercises {
+		originalID := exercise.ID
+		if name, ok := uploads.NameFromURL(exercise.ImageURL); ok {
+			if target, ok := uploadNames[name]; ok {
+				exercise.ImageURL = uploads.URL(target)
+			} else if !s.store.Exists(ctx, name) {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("exercise %s references missing file %s", exercise.ID, exercise.ImageURL))
+			}
+		}
+		exercise.ImageVariants = s.resolveVariants(ctx, exercise.ImageVariants, uploadNames)
+		exercise.Clips = s.resolveClips(ctx, exercise, uploadNames, report)
+		// 早期导出的动作没有分类，按 bodyPart 填写主要肌群
+		if exercise.PrimaryMuscles == nil && exercise.BodyPart != "" {
+			exercise.PrimaryMuscles = known.MapBodyPart(exercise.BodyPart)
+		}
+		if removed := known.Prune(&exercise); len(removed) > 0 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("exercise %s references unknown taxonomy terms %s", exercise.ID, strings.Join(removed, ", ")))
+		}
+		if exercise.Tracking != "" && !tracking.IsMode(exercise.Tracking) {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("exercise %s has unknown tracking mode %q, using %s", exercise.ID, exercise.Tracking, models.TrackRepsWeight))
+			exercise.Tracking = models.TrackRepsWeight
+		}
+
+		existing, collides := existingExerciseByID[exercise.ID]
+		switch {
+		case !collides:
+			report.Exercises.Created++
+		case sameJSON(existing, exercise):
+			report.Exercises.Skipped++
+			exerciseIDs[originalID] = exercise.ID
+			continue
+		case mode == ModeReplace:
+			report.Exercises.Updated++
+		default:
+			exercise.ID = uuid.New().String()
+			report.Remapped[originalID] = exercise.ID
+			report.Exercises.Remapped++
+		}
+		exerciseIDs[originalID] = exercise.ID
+		exercises = append(exercises, exercise)
+	}
+
+	// 导入后每个动作的记录方式
+	modes := tracking.ByExercise(existingExercises)
+	for _, exercise := range exercises {
+		modes[exercise.ID] = tracking.Mode(exercise)
+	}
+
+	workoutIDs := make(map[string]string)
+	workouts := make([]models.Workout, 0, len(b.workouts))
+	existingWorkoutByID := make(map[string]models.Workout)
+	for _, workout := range existingWorkouts {
+		existingWorkoutByID[workout.ID] = workout
+	}
+	for _, workout := range b.workouts {
+		originalID := workout.ID
+		workout.Exercises = append([]models.ExerciseSet{}, workout.Exercises...)
+		for i, set := range workout.Exercises {
+			workout.Exercises[i].ExerciseID = s.resolveExercise(set.ExerciseID, exerciseIDs, existingExerciseByID, mode, report, "workout "+originalID)
+		}
+		// 早期导出的训练计划没有每组的目标
+		if err := prescription.NormalizeWorkout(&workout, modes); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("workout %s has invalid sets: %v", originalID, err))
+		}
+
+		existing, collides := existingWorkoutByID[workout.ID]
+		switch {
+		case !collides:
+			report.Workouts.Created++
+		case sameJSON(existing, workout):
+			report.Workouts.Skipped++
+			workoutIDs[originalID] = workout.ID
+			continue
+		case mode == ModeReplace:
+			report.Workouts.Updated++
+		default:
+			workout.ID = uuid.New().String()
+			report.Remapped[originalID] = workout.ID
+			report.Workouts.Remapped++
+		}
+		workoutIDs[originalID] = workout.ID
+		workouts = append(workouts, workout)
+	}
+
+	sessions := make([]models.WorkoutSession, 0, len(b.sessions))
+	existingSessionByID := make(map[string]models.WorkoutSession)
+	for _, session := range existingSessions {
+		existingSessionByID[session.ID] = session
+	}
+	for _, session := range b.sessions {
+		originalID := session.ID
+		if id, ok := workoutIDs[session.WorkoutID]; ok {
+			session.WorkoutID = id
+		} else if _, ok := existingWorkoutByID[session.WorkoutID]; !ok || mode == ModeReplace {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("session %s references unknown workout %s", originalID, session.WorkoutID))
+		}
+		session.Exercises = append([]models.CompletedExercise{}, session.Exercises...)
+		for i, completed := range session.Exercises {
+			session.Exercises[i].ExerciseID = s.resolveExercise(completed.ExerciseID, exerciseIDs, existingExerciseByID, mode, report, "session "+originalID)
+		}
+		if err := effort.ValidateSession(&session); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("session %s has invalid set logs: %v", originalID, err))
+		}
+
+		existing, collides := existingSessionByID[session.ID]
+		switch {
+		case !collides:
+			report.Sessions.Created++
+		case sameJSON(existing, session):
+			report.Sessions.Skipped++
+			continue
+		case mode == ModeReplace:
+			report.Sessions.Updated++
+		default:
+			session.ID = uuid.New().String()
+			report.Remapped[originalID] = session.ID
+			report.Sessions.Remapped++
+		}
+		sessions = append(sessions, session)
+	}
+
+	if mode == ModeReplace {
+		report.Exercises.Deleted = countMissing(existingExercises, b.exercises, func(e models.Exercise) string { return e.ID })
+		report.Workouts.Deleted = countMissing(existingWorkouts, b.workouts, func(w models.Workout) string { return w.ID })
+		report.Sessions.Deleted = countMissing(existingSessions, b.sessions, func(s models.WorkoutSession) string { return s.ID })
+		// 替换模式下未变化的数据也需要保留
+		exercises = keepSkipped(exercises, b.exercises, existingExerciseByID, func(e models.Exercise) string { return e.ID })
+		workouts = keepSkipped(workouts, b.workouts, existingWorkoutByID, func(w models.Workout) string { return w.ID })
+		sessions = keepSkipped(sessions, b.sessions, existingSessionByID, func(s models.WorkoutSession) string { return s.ID })
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for name, f := range b.uploads {
+		target := uploadNames[name]
+		if report.Remapped[name] == "" && s.store.Exists(ctx, target) {
+			continue
+		}
+		if err := s.extract(ctx, f, target); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(terms) > 0 || (mode == ModeReplace && b.taxonomy != nil) {
+		if err := repo.ImportTaxonomy(terms, mode == ModeReplace && b.taxonomy != nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.ImportData(exercises, workouts, sessions, mode == ModeReplace); err != nil {
+		return nil, err
+	}
+	// 个人记录不在导出包中，按导入后的训练记录重新计算
+	if _, err := records.Rebuild(repo); err != nil {
+		return nil, err
+	}
+	// 记录导入图片的缩放版本，之后修改动作时不会丢失
+	for _, exercise := range exercises {
+		if len(exercise.ImageVariants) == 0 {
+			continue
+		}
+		if err := repo.SaveMedia(models.Media{URL: exercise.ImageURL, Variants: exercise.ImageVariants, CreatedAt: time.Now()}); err != nil {
+			return nil, err
+		}
+	}
+	// 没有上传记录的演示视频补充记录，已有的保留原记录
+	for _, exercise := range exercises {
+		for _, clip := range exercise.Clips {
+			existing, err := repo.GetMedia(clip.URL)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				continue
+			}
+			item := models.Media{URL: clip.URL, Type: clip.Type, Width: clip.Width, Height: clip.Height, Size: clip.Size,
+				Duration: clip.Duration, Poster: clip.Poster, CreatedAt: time.Now()}
+			if err := repo.SaveMedia(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// 按导入后的文件名更新演示视频和封面的地址，视频文件不存在时丢弃并警告，封面不存在时清空
+// 返回需要写入的分类和导入后可用的全部分类。导出包中没有分类时保留现有分类
+func (s *Service) importTaxonomy(b *bundle, existing []models.TaxonomyTerm, mode string, report *ImportReport) ([]models.TaxonomyTerm, taxonomy.Index) {
+	existingByID := taxonomy.NewIndex(existing)
+	if b.taxonomy == nil {
+		return nil, existingByID
+	}
+
+	var terms []models.TaxonomyTerm
+	for _, term := range b.taxonomy {
+		current, collides := existingByID[term.ID]
+		switch {
+		case !collides:
+			report.Taxonomy.Created++
+		case sameJSON(current, term):
+			report.Taxonomy.Skipped++
+			continue
+		case mode == ModeReplace:
+			report.Taxonomy.Updated++
+		default:
+			// 分类ID表示同一个含义，合并时以本地修改过的名称和别名为准
+			report.Taxonomy.Skipped++
+			continue
+		}
+		terms = append(terms, term)
+	}
+
+	if mode == ModeReplace {
+		report.Taxonomy.Deleted = countMissing(existing, b.taxonomy, func(t models.TaxonomyTerm) string { return t.ID })
+		return b.taxonomy, taxonomy.NewIndex(b.taxonomy)
+	}
+	known := taxonomy.NewIndex(existing)
+	for _, term := range terms {
+		known[term.ID] = term
+	}
+	return terms, known
+}
+
+func (s *Service) resolveClips(ctx context.Context, exercise models.Exercise, uploadNames map[string]string, report *ImportReport) []models.Clip {
+	resolve := func(url string) (string, bool) {
+		name, ok := uploads.NameFromURL(url)
+		if !ok {
+			return url, false
+		}
+		if target, ok := uploadNames[name]; ok {
+			return uploads.URL(target), true
+		}
+		return url, s.store.Exists(ctx, name)
+	}
+
+	var clips []models.Clip
+	for _, clip := range exercise.Clips {
+		url, ok := resolve(clip.URL)
+		if !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("exercise %s references missing file %s", exercise.ID, clip.URL))
+			continue
+		}
+		clip.URL = url
+		if clip.Poster != "" {
+			if poster, ok := resolve(clip.Poster); ok {
+				clip.Poster = poster
+			} else {
+				clip.Poster = ""
+			}
+		}
+		clips = append(clips, clip)
+	}
+	return clips
+}
+
+// 按导入后的文件名更新缩放版本的地址，文件不存在的版本直接丢弃
+func (s *Service) resolveVariants(ctx context.Context, variants []models.ImageVariant, uploadNames map[string]string) []models.ImageVariant {
+	var resolved []models.ImageVariant
+	for _, variant := range variants {
+		name, ok := uploads.NameFromURL(variant.URL)
+		if !ok {
+			continue
+		}
+		if target, ok := uploadNames[name]; ok {
+			variant.URL = uploads.URL(target)
+		} else if !s.store.Exists(ctx, name) {
+			continue
+		}
+		resolved = append(resolved, variant)
+	}
+	return resolved
+}
+
+// 解析导入数据中引用的动作ID
+func (s *Service) resolveExercise(id string, imported map[string]string, existing map[string]models.Exercise, mode string, report *ImportReport, owner string) string {
+	if newID, ok := imported[id]; ok {
+		return newID
+	}
+	if _, ok := existing[id]; !ok || mode == ModeReplace {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s references unknown exercise %s", owner, id))
+	}
+	return id
+}
+
+func countMissing[T any](existing, imported []T, id func(T) string) int {
+	kept := make(map[string]bool)
+	for _, item := range imported {
+		kept[id(item)] = true
+	}
+	count := 0
+	for _, item := range existing {
+		if !kept[id(item)] {
+			count++
+		}
+	}
+	return count
+}
+
+func keepSkipped[T any](result, imported []T, existing map[string]T, id func(T) string) []T {
+	included := make(map[string]bool)
+	for _, item := range result {
+		included[id(item)] = true
+	}
+	for _, item := range imported {
+		if old, ok := existing[id(item)]; ok && !included[id(item)] {
+			result = append(result, old)
+		}
+	}
+	return result
+}
+
+func sameJSON(a, b interface{}) bool {
+	left, err1 := json.Marshal(a)
+	right, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(left) == string(right)
+}
+
+func checkUpload(f *zip.File, name string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	head := make([]byte, uploads.SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	_, err = uploads.Detect(head[:n], name)
+	return err
+}
+
+func (s *Service) extract(ctx context.Context, f *zip.File, target string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.store.Save(ctx, target, r, int64(f.UncompressedSize64))
+}
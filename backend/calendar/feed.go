@@ -0,0 +1,301 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/prescription"
+	"workout-tracker/tracking"
+)
+
+// 日历应用重新拉取订阅的建议间隔
+const refreshInterval = "PT1H"
+
+// 未设置时长的计划默认60分钟
+const defaultDuration = 60
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// FeedRequest 创建日历订阅的请求
+type FeedRequest struct {
+	Name string `json:"name" binding:"required"` // 订阅人，便于区分和撤销
+}
+
+// FeedResponse 创建订阅的响应，令牌只在此时返回
+type FeedResponse struct {
+	models.CalendarFeed
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// NewToken 生成订阅令牌，返回令牌和需要保存的哈希
+func NewToken() (string, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateSchedule 校验训练计划的每周安排
+func ValidateSchedule(schedule *models.WorkoutSchedule) error {
+	if schedule == nil {
+		return nil
+	}
+	if len(schedule.Weekdays) == 0 {
+		return fmt.Errorf("schedule.weekdays must not be empty")
+	}
+	seen := make(map[int]bool)
+	for _, day := range schedule.Weekdays {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("schedule.weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if seen[day] {
+			return fmt.Errorf("schedule.weekdays contains duplicate day %d", day)
+		}
+		seen[day] = true
+	}
+	if _, err := time.Parse("15:04", schedule.Time); err != nil {
+		return fmt.Errorf("schedule.time must be HH:MM")
+	}
+	if schedule.Duration < 0 || schedule.Duration > 24*60 {
+		return fmt.Errorf("schedule.duration must be between 0 and 1440 minutes")
+	}
+	return nil
+}
+
+// Feed 日历订阅的数据
+type Feed struct {
+	Name      string
+	Workouts  []models.Workout
+	Sessions  []models.WorkoutSession
+	Exercises []models.Exercise
+}
+
+// Write 输出 iCalendar：有每周安排的训练计划作为重复事件，已完成的训练记录作为单次事件
+func (f *Feed) Write(out io.Writer, now time.Time) error {
+	w := &icsWriter{w: out}
+	exercises := make(map[string]models.Exercise)
+	for _, exercise := range f.Exercises {
+		exercises[exercise.ID] = exercise
+	}
+	workoutNames := make(map[string]string)
+	for _, workout := range f.Workouts {
+		workoutNames[workout.ID] = workout.Name
+	}
+
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//workout-tracker//calendar//ZH")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", escapeText("训练 - "+f.Name))
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", refreshInterval)
+	w.line("X-PUBLISHED-TTL", refreshInterval)
+
+	for _, workout := range f.Workouts {
+		if workout.Schedule == nil || ValidateSchedule(workout.Schedule) != nil {
+			continue
+		}
+		f.writeWorkout(w, workout, exercises, now)
+	}
+
+	sessions := append([]models.WorkoutSession{}, f.Sessions...)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].StartTime.Before(sessions[j].StartTime)
+	})
+	for _, session := range sessions {
+		if !session.IsCompleted {
+			continue
+		}
+		writeSession(w, session, workoutNames[session.WorkoutID], exercises, now)
+	}
+
+	w.line("END", "VCALENDAR")
+	return w.err
+}
+
+func (f *Feed) writeWorkout(w *icsWriter, workout models.Workout, exercises map[string]models.Exercise, now time.Time) {
+	schedule := workout.Schedule
+	clock, _ := time.Parse("15:04", schedule.Time)
+	duration := schedule.Duration
+	if duration == 0 {
+		duration = defaultDuration
+	}
+
+	var days []string
+	for _, day := range schedule.Weekdays {
+		days = append(days, weekdayCodes[day])
+	}
+
+	var description []string
+	if workout.Description != "" {
+		description = append(description, workout.Description, "")
+	}
+	blocks := make(map[string]models.WorkoutBlock)
+	for _, block := range workout.Blocks {
+		blocks[block.ID] = block
+	}
+	previous := ""
+	for _, set := range workout.Exercises {
+		// 训练块的第一个动作之前输出训练块的说明
+		if block, ok := blocks[set.Block]; ok && set.Block != previous {
+			description = append(description, describeBlock(block))
+		}
+		previous = set.Block
+
+		exercise := exercises[set.ExerciseID]
+		name := exercise.Name
+		if name == "" {
+			name = set.ExerciseID
+		}
+		fields := tracking.FieldsOf(tracking.Mode(exercise))
+		line := fmt.Sprintf("%s %d组", name, set.Sets)
+		if fields.Reps {
+			line += fmt.Sprintf(" × %d次", set.Reps)
+		}
+		if fields.Duration {
+			line += fmt.Sprintf(" × %d秒", set.Duration)
+		}
+		if fields.Distance {
+			line += fmt.Sprintf(" × %g米", set.Distance)
+		}
+		if fields.Weight && set.Weight > 0 {
+			unit := set.Unit
+			if unit == "" {
+				unit = models.UnitKg
+			}
+			line += fmt.Sprintf(" · %g%s", set.Weight, unit)
+		}
+		if set.RestTime > 0 {
+			line += fmt.Sprintf(" · 休息%d秒", set.RestTime)
+		}
+		description = append(description, line)
+	}
+
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", "workout-"+workout.ID+"@workout-tracker")
+	w.line("DTSTAMP", formatUTC(now))
+	w.line("DTSTART", formatFloating(firstOccurrence(workout.CreatedAt, schedule.Weekdays, clock)))
+	w.line("DURATION", fmt.Sprintf("PT%dM", duration))
+	w.line("RRULE", "FREQ=WEEKLY;BYDAY="+strings.Join(days, ","))
+	w.line("SUMMARY", escapeText(workout.Name))
+	w.line("DESCRIPTION", escapeText(strings.Join(description, "\n")))
+	if workout.BodyPart != "" {
+		w.line("CATEGORIES", escapeText(workout.BodyPart))
+	}
+	w.line("END", "VEVENT")
+}
+
+// 重复事件的开始时间：计划创建当天或之后第一个符合安排的日期
+func firstOccurrence(from time.Time, weekdays []int, clock time.Time) time.Time {
+	from = from.Local()
+	day := time.Date(from.Year(), from.Month(), from.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+	for i := 0; i < 7; i++ {
+		candidate := day.AddDate(0, 0, i)
+		for _, weekday := range weekdays {
+			if int(candidate.Weekday()) == weekday {
+				return candidate
+			}
+		}
+	}
+	return day
+}
+
+func writeSession(w *icsWriter, session models.WorkoutSession, workoutName string, exercises map[string]models.Exercise, now time.Time) {
+	start := session.StartTime
+	if start.IsZero() {
+		start = session.Date
+	}
+	end := session.EndTime
+	if end.Before(start) || end.IsZero() {
+		end = start.Add(time.Duration(session.TotalTime) * time.Second)
+	}
+	if workoutName == "" {
+		workoutName = "训练"
+	}
+
+	description := []string{
+		fmt.Sprintf("时长 %d 分钟", int(end.Sub(start).Minutes())),
+		fmt.Sprintf("消耗 %.0f 千卡", session.TotalCalories),
+	}
+	for _, completed := range session.Exercises {
+		if completed.CompletedSets == 0 {
+			continue
+		}
+		exercise := exercises[completed.ExerciseID]
+		name := exercise.Name
+		if name == "" {
+			name = completed.ExerciseID
+		}
+		fields := tracking.FieldsOf(tracking.Mode(exercise))
+		sets := make([]string, 0, completed.CompletedSets)
+		for i := 0; i < completed.CompletedSets; i++ {
+			sets = append(sets, loggedSet(completed, fields, i))
+		}
+		description = append(description, fmt.Sprintf("%s %d组: %s", name, completed.CompletedSets, strings.Join(sets, " / ")))
+	}
+	if session.Notes != "" {
+		description = append(description, "", session.Notes)
+	}
+
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", "session-"+session.ID+"@workout-tracker")
+	w.line("DTSTAMP", formatUTC(now))
+	w.line("DTSTART", formatUTC(start))
+	w.line("DTEND", formatUTC(end))
+	w.line("SUMMARY", escapeText("✓ "+workoutName))
+	w.line("DESCRIPTION", escapeText(strings.Join(description, "\n")))
+	w.line("END", "VEVENT")
+}
+
+// 第 i 组按记录方式记录的结果，例如 "12次"、"60秒"、"300秒 1000米"
+func loggedSet(completed models.CompletedExercise, fields tracking.Fields, i int) string {
+	var parts []string
+	if fields.Reps && i < len(completed.CompletedReps) {
+		parts = append(parts, fmt.Sprintf("%d次", completed.CompletedReps[i]))
+	}
+	if fields.Weight && i < len(completed.CompletedWeights) && completed.CompletedWeights[i] > 0 {
+		parts = append(parts, fmt.Sprintf("%gkg", completed.CompletedWeights[i]))
+	}
+	if fields.Duration && i < len(completed.CompletedDurations) {
+		parts = append(parts, fmt.Sprintf("%d秒", completed.CompletedDurations[i]))
+	}
+	if fields.Distance && i < len(completed.CompletedDistances) {
+		parts = append(parts, fmt.Sprintf("%g米", completed.CompletedDistances[i]))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+// 例如 "A 超级组 · 3轮 · 每轮后休息90秒"
+func describeBlock(block models.WorkoutBlock) string {
+	line := strings.TrimSpace(block.Name + " " + prescription.BlockTypeNames[block.Type])
+	if block.Rounds > 0 {
+		line += fmt.Sprintf(" · %d轮", block.Rounds)
+	}
+	if block.Interval > 0 {
+		line += fmt.Sprintf(" · 每%d秒一轮", block.Interval)
+	}
+	if block.TimeCap > 0 {
+		line += fmt.Sprintf(" · 限时%d秒", block.TimeCap)
+	}
+	if block.RoundRest > 0 {
+		line += fmt.Sprintf(" · 每轮后休息%d秒", block.RoundRest)
+	}
+	return line
+}
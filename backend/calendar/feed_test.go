@@ -0,0 +1,23 @@
+package calendar
+
+import (
+	"testing"
+	"workout-tracker/models"
+)
+
+func TestDescribeBlock(t *testing.T) {
+	tests := []struct {
+		block models.WorkoutBlock
+		want  string
+	}{
+		{models.WorkoutBlock{Name: "A", Type: models.BlockSuperset, Rounds: 3, RoundRest: 90}, "A 超级组 · 3轮 · 每轮后休息90秒"},
+		{models.WorkoutBlock{Type: models.BlockEMOM, Rounds: 10, Interval: 60}, "EMOM · 10轮 · 每60秒一轮"},
+		{models.WorkoutBlock{Name: "收尾", Type: models.BlockAMRAP, TimeCap: 600}, "收尾 AMRAP · 限时600秒"},
+		{models.WorkoutBlock{Type: models.BlockStraight}, "常规组"},
+	}
+	for _, tt := range tests {
+		if got := describeBlock(tt.block); got != tt.want {
+			t.Errorf("describeBlock(%+v) = %q, want %q", tt.block, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package calendar
+
+import (
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 内容行最长75字节，超出部分换行并以空格开头 (RFC 5545 3.1)
+const maxLineOctets = 75
+
+// icsWriter 按 RFC 5545 输出内容行
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *icsWriter) line(name, value string) {
+	if w.err != nil {
+		return
+	}
+
+	content := name + ":" + value
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(content) > limit {
+		// 不能在多字节字符中间折行
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+	_, w.err = io.WriteString(w.w, b.String())
+}
+
+// 文本值中的反斜杠、逗号、分号和换行需要转义
+func escapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		",", `\,`,
+		";", `\;`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// 不带时区的本地时间，订阅方按自己所在时区显示
+func formatFloating(t time.Time) string {
+	return t.Format("20060102T150405")
+}
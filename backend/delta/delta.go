@@ -0,0 +1,541 @@
+package delta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"workout-tracker/effort"
+	"workout-tracker/events"
+	"workout-tracker/models"
+	"workout-tracker/prescription"
+	"workout-tracker/records"
+	"workout-tracker/repository"
+	"workout-tracker/taxonomy"
+	"workout-tracker/tracking"
+)
+
+// 变更处理结果
+const (
+	StatusApplied   = "applied"   // 全部字段已应用
+	StatusMerged    = "merged"    // 部分字段因冲突保留了服务端的值
+	StatusConflict  = "conflict"  // 整个变更因冲突被拒绝
+	StatusRejected  = "rejected"  // 变更无效
+	StatusDuplicate = "duplicate" // 变更已处理过
+)
+
+// Mutation 客户端离线时排队的一次变更
+type Mutation struct {
+	MutationID string          `json:"mutationId"`
+	Entity     string          `json:"entity"` // exercise / workout / session
+	Op         string          `json:"op"`     // upsert / delete
+	ID         string          `json:"id"`
+	Data       json.RawMessage `json:"data,omitempty"` // 需要修改的字段
+	ClientTime time.Time       `json:"clientTime"`
+}
+
+// Request 同步请求
+type Request struct {
+	ClientID  string     `json:"clientId"`
+	SyncToken string     `json:"syncToken"`
+	Mutations []Mutation `json:"mutations"`
+}
+
+// FieldConflict 字段冲突
+type FieldConflict struct {
+	Field       string          `json:"field"`
+	ClientValue json.RawMessage `json:"clientValue"`
+	ServerValue json.RawMessage `json:"serverValue"`
+	Winner      string          `json:"winner"` // client / server
+}
+
+// MutationResult 单个变更的处理结果
+type MutationResult struct {
+	MutationID string          `json:"mutationId"`
+	Entity     string          `json:"entity"`
+	ID         string          `json:"id"`
+	Status     string          `json:"status"`
+	Conflicts  []FieldConflict `json:"conflicts,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Change 服务端变更
+type Change struct {
+	Seq       int64           `json:"seq"`
+	Entity    string          `json:"entity"`
+	ID        string          `json:"id"`
+	Op        string          `json:"op"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	ChangedAt time.Time       `json:"changedAt"`
+}
+
+// Response 同步响应
+type Response struct {
+	SyncToken string           `json:"syncToken"`
+	Results   []MutationResult `json:"results"`
+	Changes   []Change         `json:"changes"`
+}
+
+// Service 处理离线客户端的增量同步
+// 冲突按字段以最后写入者为准 (LWW)，时间相同时保留服务端的值
+type Service struct {
+	repo *repository.FileRepository
+	bus  *events.Bus
+	mu   sync.Mutex
+}
+
+func NewService(repo *repository.FileRepository, bus *events.Bus) *Service {
+	return &Service{repo: repo, bus: bus}
+}
+
+// Sync 依次应用客户端变更，然后返回同步令牌之后的所有服务端变更
+func (s *Service) Sync(ctx context.Context, req Request) (*Response, error) {
+	since, err := parseToken(req.SyncToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.withContext(ctx).sync(req, since)
+}
+
+// 绑定请求 context 的副本，只在持有锁时使用
+func (s *Service) withContext(ctx context.Context) *Service {
+	return &Service{repo: s.repo.WithContext(ctx), bus: s.bus}
+}
+
+func (s *Service) sync(req Request, since int64) (*Response, error) {
+	resp := &Response{Results: []MutationResult{}, Changes: []Change{}}
+	for _, mutation := range req.Mutations {
+		result, err := s.apply(mutation)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	records, lastSeq, err := s.repo.GetChangesSince(since)
+	if err != nil {
+		return nil, err
+	}
+	// 首次同步没有令牌，返回全部数据
+	if req.SyncToken == "" {
+		if records, err = s.snapshot(lastSeq); err != nil {
+			return nil, err
+		}
+	}
+	for _, record := range records {
+		change := Change{
+			Seq:       record.Seq,
+			Entity:    record.Entity,
+			ID:        record.EntityID,
+			Op:        record.Op,
+			ChangedAt: record.ChangedAt,
+		}
+		if record.Op == models.ChangeUpsert {
+			entity, err := s.load(record.Entity, record.EntityID)
+			if err != nil {
+				return nil, err
+			}
+			if entity == nil {
+				continue
+			}
+			if change.Data, err = json.Marshal(entity); err != nil {
+				return nil, err
+			}
+		}
+		resp.Changes = append(resp.Changes, change)
+	}
+	resp.SyncToken = strconv.FormatInt(lastSeq, 10)
+
+	return resp, nil
+}
+
+// 以当前所有实体生成变更记录，用于首次同步
+func (s *Service) snapshot(seq int64) ([]models.ChangeRecord, error) {
+	var records []models.ChangeRecord
+	add := func(entity, id string) {
+		records = append(records, models.ChangeRecord{Seq: seq, Entity: entity, EntityID: id, Op: models.ChangeUpsert})
+	}
+
+	exercises, err := s.repo.GetAllExercises()
+	if err != nil {
+		return nil, err
+	}
+	for _, exercise := range exercises {
+		add(models.EntityExercise, exercise.ID)
+	}
+
+	workouts, err := s.repo.GetAllWorkouts()
+	if err != nil {
+		return nil, err
+	}
+	for _, workout := range workouts {
+		add(models.EntityWorkout, workout.ID)
+	}
+
+	sessions, err := s.repo.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		add(models.EntitySession, session.ID)
+	}
+	return records, nil
+}
+
+func parseToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, fmt.Errorf("invalid sync token")
+	}
+	return seq, nil
+}
+
+func (s *Service) apply(m Mutation) (MutationResult, error) {
+	result := MutationResult{MutationID: m.MutationID, Entity: m.Entity, ID: m.ID}
+
+	if m.MutationID == "" || m.ID == "" {
+		result.Status = StatusRejected
+		result.Error = "mutationId and id are required"
+		return result, nil
+	}
+	if m.Entity != models.EntityExercise && m.Entity != models.EntityWorkout && m.Entity != models.EntitySession {
+		result.Status = StatusRejected
+		result.Error = "unknown entity " + m.Entity
+		return result, nil
+	}
+
+	processed, err := s.repo.IsMutationProcessed(m.MutationID)
+	if err != nil {
+		return result, err
+	}
+	if processed {
+		result.Status = StatusDuplicate
+		return result, nil
+	}
+
+	// 客户端时间不能晚于服务端当前时间，避免时钟偏差导致永远获胜
+	now := time.Now()
+	clientTime := m.ClientTime
+	if clientTime.IsZero() || clientTime.After(now) {
+		clientTime = now
+	}
+
+	switch m.Op {
+	case models.ChangeUpsert:
+		err = s.applyUpsert(m, clientTime, &result)
+	case models.ChangeDelete:
+		err = s.applyDelete(m, clientTime, &result)
+	default:
+		result.Status = StatusRejected
+		result.Error = "unknown op " + m.Op
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if result.Status != StatusRejected {
+		if err := s.repo.MarkMutationProcessed(m.MutationID); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) applyUpsert(m Mutation, clientTime time.Time, result *MutationResult) error {
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(m.Data, &patch); err != nil {
+		result.Status = StatusRejected
+		result.Error = "data must be a JSON object"
+		return nil
+	}
+
+	existing, err := s.load(m.Entity, m.ID)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]json.RawMessage)
+	if existing != nil {
+		bytes, err := json.Marshal(existing)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(bytes, &current); err != nil {
+			return err
+		}
+	}
+
+	clocks, err := s.repo.GetFieldClocks(m.Entity, m.ID)
+	if err != nil {
+		return err
+	}
+
+	// 按字段名排序，保证冲突结果稳定
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	applied := make(map[string]time.Time)
+	for _, field := range fields {
+		value := patch[field]
+		if isProtected(m.Entity, field) {
+			continue
+		}
+		if existing != nil {
+			serverValue := current[field]
+			if jsonEqual(serverValue, value) {
+				continue
+			}
+			if serverTime, ok := clocks[field]; ok && !clientTime.After(serverTime) {
+				result.Conflicts = append(result.Conflicts, FieldConflict{
+					Field:       field,
+					ClientValue: value,
+					ServerValue: serverValue,
+					Winner:      "server",
+				})
+				continue
+			}
+		}
+		current[field] = value
+		applied[field] = clientTime
+	}
+
+	result.Status = StatusApplied
+	if len(result.Conflicts) > 0 {
+		result.Status = StatusMerged
+	}
+	if len(applied) == 0 && existing != nil {
+		return nil
+	}
+
+	current["id"], _ = json.Marshal(m.ID)
+	merged, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if err := s.save(m.Entity, merged, existing == nil, clientTime); err != nil {
+		result.Status = StatusRejected
+		result.Error = err.Error()
+		result.Conflicts = nil
+		return nil
+	}
+	if err := s.repo.SetFieldClocks(m.Entity, m.ID, applied); err != nil {
+		return err
+	}
+
+	return s.publishUpsert(m.Entity, m.ID, existing)
+}
+
+// 发布实体新建或修改的事件
+func (s *Service) publishUpsert(entity, id string, existing interface{}) error {
+	current, err := s.load(entity, id)
+	if err != nil || current == nil {
+		return err
+	}
+
+	switch entity {
+	case models.EntityExercise:
+		eventType := events.ExerciseUpdated
+		if existing == nil {
+			eventType = events.ExerciseCreated
+		}
+		s.bus.Publish(events.New(eventType, "", current))
+	case models.EntityWorkout:
+		eventType := events.WorkoutUpdated
+		if existing == nil {
+			eventType = events.WorkoutCreated
+		}
+		s.bus.Publish(events.New(eventType, "", current))
+	case models.EntitySession:
+		var before *models.WorkoutSession
+		if existing != nil {
+			previous := existing.(models.WorkoutSession)
+			before = &previous
+		}
+		session := current.(models.WorkoutSession)
+		s.bus.Publish(events.SessionChanged(before, session)...)
+		found, err := records.Detect(s.repo, before, session)
+		if err != nil {
+			return err
+		}
+		for _, record := range records.Visible(found, records.DefaultFormula) {
+			s.bus.Publish(events.New(events.RecordAchieved, session.ID, record))
+		}
+	}
+	return nil
+}
+
+func (s *Service) applyDelete(m Mutation, clientTime time.Time, result *MutationResult) error {
+	existing, err := s.load(m.Entity, m.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		result.Status = StatusApplied
+		return nil
+	}
+
+	// 服务端在客户端删除之后又修改过，保留服务端数据
+	clocks, err := s.repo.GetFieldClocks(m.Entity, m.ID)
+	if err != nil {
+		return err
+	}
+	for field, serverTime := range clocks {
+		if !clientTime.After(serverTime) {
+			result.Status = StatusConflict
+			result.Error = fmt.Sprintf("field %s was modified on the server after the delete", field)
+			return nil
+		}
+	}
+
+	switch m.Entity {
+	case models.EntityExercise:
+		if err = s.repo.DeleteExercise(m.ID); err == nil {
+			s.bus.Publish(events.New(events.ExerciseDeleted, "", map[string]string{"id": m.ID}))
+		}
+	case models.EntityWorkout:
+		if err = s.repo.DeleteWorkout(m.ID); err == nil {
+			s.bus.Publish(events.New(events.WorkoutDeleted, "", map[string]string{"id": m.ID}))
+		}
+	case models.EntitySession:
+		// 之后训练的个人记录可能依赖被删除的训练
+		if err = s.repo.DeleteSession(m.ID); err == nil {
+			_, err = records.Rebuild(s.repo)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	result.Status = StatusApplied
+	return nil
+}
+
+// 服务端管理的字段，不接受客户端修改
+func isProtected(entity, field string) bool {
+	switch field {
+	case "id", "createdAt":
+		return true
+	case "date":
+		return entity == models.EntitySession
+	}
+	return false
+}
+
+// 加载实体，不存在时返回 nil
+func (s *Service) load(entity, id string) (interface{}, error) {
+	switch entity {
+	case models.EntityExercise:
+		exercises, err := s.repo.GetAllExercises()
+		if err != nil {
+			return nil, err
+		}
+		for _, exercise := range exercises {
+			if exercise.ID == id {
+				return exercise, nil
+			}
+		}
+	case models.EntityWorkout:
+		workouts, err := s.repo.GetAllWorkouts()
+		if err != nil {
+			return nil, err
+		}
+		for _, workout := range workouts {
+			if workout.ID == id {
+				return workout, nil
+			}
+		}
+	case models.EntitySession:
+		sessions, err := s.repo.GetAllSessions()
+		if err != nil {
+			return nil, err
+		}
+		for _, session := range sessions {
+			if session.ID == id {
+				return session, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown entity %s", entity)
+	}
+	return nil, nil
+}
+
+func (s *Service) save(entity string, data []byte, created bool, clientTime time.Time) error {
+	switch entity {
+	case models.EntityExercise:
+		var exercise models.Exercise
+		if err := json.Unmarshal(data, &exercise); err != nil {
+			return err
+		}
+		if created {
+			exercise.CreatedAt = clientTime
+		}
+		if err := taxonomy.Resolve(s.repo, &exercise); err != nil {
+			return err
+		}
+		if err := tracking.Validate(&exercise); err != nil {
+			return err
+		}
+		if err := s.repo.ResolveExerciseMedia(&exercise); err != nil {
+			return err
+		}
+		return s.repo.SaveExercise(exercise)
+	case models.EntityWorkout:
+		var workout models.Workout
+		if err := json.Unmarshal(data, &workout); err != nil {
+			return err
+		}
+		if created {
+			workout.CreatedAt = clientTime
+		}
+		exercises, err := s.repo.GetAllExercises()
+		if err != nil {
+			return err
+		}
+		if err := prescription.NormalizeWorkout(&workout, tracking.ByExercise(exercises)); err != nil {
+			return err
+		}
+		return s.repo.SaveWorkout(workout)
+	case models.EntitySession:
+		var session models.WorkoutSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		if err := effort.ValidateSession(&session); err != nil {
+			return err
+		}
+		if created {
+			if session.StartTime.IsZero() {
+				session.StartTime = clientTime
+			}
+			session.Date = session.StartTime
+		}
+		if session.IsCompleted && session.EndTime.IsZero() {
+			session.EndTime = clientTime
+			session.TotalTime = int(session.EndTime.Sub(session.StartTime).Seconds())
+		}
+		return s.repo.SaveSession(session)
+	}
+	return fmt.Errorf("unknown entity %s", entity)
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var left, right interface{}
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return false
+	}
+	l, _ := json.Marshal(left)
+	r, _ := json.Marshal(right)
+	return string(l) == string(r)
+}
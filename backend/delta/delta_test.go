@@ -0,0 +1,193 @@
+package delta
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	"workout-tracker/events"
+	"workout-tracker/repository"
+)
+
+// 一次同步中发送的变更及期望的处理结果
+type step struct {
+	mutation  Mutation
+	status    string
+	conflicts []string // 以服务端为准的字段
+}
+
+func workoutMutation(id, mutationID string, at time.Time, data string) Mutation {
+	return Mutation{MutationID: mutationID, Entity: "workout", Op: "upsert", ID: id, Data: json.RawMessage(data), ClientTime: at}
+}
+
+func TestSyncMerge(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	future := time.Now().Add(time.Hour)
+	create := workoutMutation("w1", "m0", at(0), `{"name":"Push","description":"chest"}`)
+
+	tests := []struct {
+		name  string
+		steps []step
+		want  map[string]string // 同步后训练计划的字段
+	}{
+		{
+			name: "newer edit wins",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m1", at(10), `{"name":"Push A"}`), StatusApplied, nil},
+			},
+			want: map[string]string{"name": "Push A", "description": "chest"},
+		},
+		{
+			name: "older edit of the same field loses",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m1", at(10), `{"name":"Push A"}`), StatusApplied, nil},
+				{workoutMutation("w1", "m2", at(5), `{"name":"Push B","description":"chest and triceps"}`), StatusMerged, []string{"name"}},
+			},
+			want: map[string]string{"name": "Push A", "description": "chest and triceps"},
+		},
+		{
+			name: "same time keeps server value",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m1", at(0), `{"name":"Other"}`), StatusMerged, []string{"name"}},
+			},
+			want: map[string]string{"name": "Push"},
+		},
+		{
+			name: "unchanged value is not a conflict",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m1", at(-5), `{"name":"Push"}`), StatusApplied, nil},
+			},
+			want: map[string]string{"name": "Push"},
+		},
+		{
+			// 两次都按各自的处理时间计算，后一次更新
+			name: "future client time is capped to now",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m1", future, `{"name":"Future"}`), StatusApplied, nil},
+				{workoutMutation("w1", "m2", future, `{"name":"Later"}`), StatusApplied, nil},
+			},
+			want: map[string]string{"name": "Later"},
+		},
+		{
+			name: "protected fields are ignored",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m1", at(10), `{"id":"w2","createdAt":"2000-01-01T00:00:00Z"}`), StatusApplied, nil},
+			},
+			want: map[string]string{"id": "w1", "name": "Push"},
+		},
+		{
+			name: "replayed mutation is a duplicate",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m0", at(10), `{"name":"Again"}`), StatusDuplicate, nil},
+			},
+			want: map[string]string{"name": "Push"},
+		},
+		{
+			name: "delete before a server edit conflicts",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{workoutMutation("w1", "m1", at(10), `{"name":"Push A"}`), StatusApplied, nil},
+				{Mutation{MutationID: "m2", Entity: "workout", Op: "delete", ID: "w1", ClientTime: at(5)}, StatusConflict, nil},
+			},
+			want: map[string]string{"name": "Push A"},
+		},
+		{
+			// 保存时服务端计算的字段按保存时间记录，删除要晚于保存
+			name: "delete after the last edit applies",
+			steps: []step{
+				{create, StatusApplied, nil},
+				{Mutation{MutationID: "m1", Entity: "workout", Op: "delete", ID: "w1", ClientTime: future}, StatusApplied, nil},
+			},
+		},
+		{
+			name: "invalid mutations are rejected",
+			steps: []step{
+				{Mutation{MutationID: "m1", Entity: "workout", Op: "upsert"}, StatusRejected, nil},
+				{Mutation{MutationID: "m2", Entity: "plan", Op: "upsert", ID: "w1"}, StatusRejected, nil},
+				{Mutation{MutationID: "m3", Entity: "workout", Op: "rename", ID: "w1"}, StatusRejected, nil},
+				{workoutMutation("w1", "m4", at(0), `["name"]`), StatusRejected, nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewFileRepository(t.TempDir())
+			service := NewService(repo, events.NewBus())
+			for i, step := range tt.steps {
+				resp, err := service.Sync(context.Background(), Request{ClientID: "phone", SyncToken: "0", Mutations: []Mutation{step.mutation}})
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				result := resp.Results[0]
+				if result.Status != step.status {
+					t.Fatalf("step %d: status %s (%s), want %s", i, result.Status, result.Error, step.status)
+				}
+				if len(result.Conflicts) != len(step.conflicts) {
+					t.Fatalf("step %d: conflicts %+v, want %v", i, result.Conflicts, step.conflicts)
+				}
+				for j, conflict := range result.Conflicts {
+					if conflict.Field != step.conflicts[j] || conflict.Winner != "server" {
+						t.Errorf("step %d: conflict %+v, want server wins %s", i, conflict, step.conflicts[j])
+					}
+				}
+			}
+
+			workouts, err := repo.GetAllWorkouts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if len(workouts) != 0 {
+					t.Fatalf("workouts = %+v, want none", workouts)
+				}
+				return
+			}
+			if len(workouts) != 1 {
+				t.Fatalf("got %d workouts", len(workouts))
+			}
+			got := map[string]string{"id": workouts[0].ID, "name": workouts[0].Name, "description": workouts[0].Description}
+			for field, want := range tt.want {
+				if got[field] != want {
+					t.Errorf("%s = %q, want %q", field, got[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncReturnsChangesSinceToken(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	service := NewService(repo, events.NewBus())
+	ctx := context.Background()
+
+	first, err := service.Sync(ctx, Request{Mutations: []Mutation{workoutMutation("w1", "m1", time.Now(), `{"name":"Push"}`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 首次同步返回全部数据
+	if len(first.Changes) != 1 || first.Changes[0].ID != "w1" {
+		t.Fatalf("first sync changes = %+v", first.Changes)
+	}
+
+	second, err := service.Sync(ctx, Request{SyncToken: first.SyncToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Changes) != 0 || second.SyncToken != first.SyncToken {
+		t.Errorf("second sync = %+v, want no changes and token %s", second, first.SyncToken)
+	}
+
+	for _, token := range []string{"abc", "-1"} {
+		if _, err := service.Sync(ctx, Request{SyncToken: token}); err == nil {
+			t.Errorf("token %q accepted", token)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package effort
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"workout-tracker/models"
+)
+
+// MaxRIR 允许记录的最大保留次数
+const MaxRIR = 10
+
+// ValidateRPE RPE 为 1-10 之间 0.5 的倍数，0 表示未填写
+func ValidateRPE(rpe float64) error {
+	if rpe == 0 {
+		return nil
+	}
+	if rpe < 1 || rpe > 10 || math.Mod(rpe*2, 1) != 0 {
+		return fmt.Errorf("rpe must be between 1 and 10 in steps of 0.5")
+	}
+	return nil
+}
+
+// NormalizeTempo 节奏为四段，每段是秒数或 X(爆发)，可以写成 3-1-X-0 或 31X0，统一为 3-1-X-0
+func NormalizeTempo(tempo string) (string, error) {
+	tempo = strings.ToUpper(strings.TrimSpace(tempo))
+	if tempo == "" {
+		return "", nil
+	}
+	parts := strings.Split(tempo, "-")
+	if len(parts) == 1 {
+		parts = strings.Split(tempo, "")
+	}
+	if len(parts) != 4 {
+		return "", fmt.Errorf("tempo %q must have four phases, e.g. 3-1-X-0", tempo)
+	}
+	for _, part := range parts {
+		if len(part) != 1 || (part != "X" && (part[0] < '0' || part[0] > '9')) {
+			return "", fmt.Errorf("tempo %q must have four phases, e.g. 3-1-X-0", tempo)
+		}
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// ValidateTarget 检查目标 RPE 和节奏
+func ValidateTarget(target *models.SetTarget) error {
+	if err := ValidateRPE(target.RPE); err != nil {
+		return err
+	}
+	tempo, err := NormalizeTempo(target.Tempo)
+	if err != nil {
+		return err
+	}
+	target.Tempo = tempo
+	return nil
+}
+
+// ValidateLog 检查一组的主观记录
+func ValidateLog(log *models.SetLog) error {
+	if err := ValidateRPE(log.RPE); err != nil {
+		return err
+	}
+	if log.RIR != nil && (*log.RIR < 0 || *log.RIR > MaxRIR) {
+		return fmt.Errorf("rir must be between 0 and %d", MaxRIR)
+	}
+	tempo, err := NormalizeTempo(log.Tempo)
+	if err != nil {
+		return err
+	}
+	log.Tempo = tempo
+	log.Note = strings.TrimSpace(log.Note)
+	return nil
+}
+
+// Empty 一组没有任何主观记录
+func Empty(log models.SetLog) bool {
+	return log.RPE == 0 && log.RIR == nil && log.Tempo == "" && !log.Pain && !log.FormIssue && log.Note == ""
+}
+
+// ValidateSession 检查训练记录中每组的主观记录，记录不能多于组数，
+// 全部为空时去掉
+func ValidateSession(session *models.WorkoutSession) error {
+	for i := range session.Exercises {
+		exercise := &session.Exercises[i]
+		sets := len(exercise.CompletedReps)
+		if exercise.CompletedSets > sets {
+			sets = exercise.CompletedSets
+		}
+		if len(exercise.SetLogs) > sets {
+			return fmt.Errorf("exercises[%d]: %d set logs but only %d sets", i, len(exercise.SetLogs), sets)
+		}
+		empty := true
+		for j := range exercise.SetLogs {
+			if err := ValidateLog(&exercise.SetLogs[j]); err != nil {
+				return fmt.Errorf("exercises[%d].setLogs[%d]: %v", i, j, err)
+			}
+			empty = empty && Empty(exercise.SetLogs[j])
+		}
+		if empty {
+			exercise.SetLogs = nil
+		}
+	}
+	return nil
+}
+
+// Effective 一组的 RPE：优先使用记录的 RPE，只记录了 RIR 时按 10-RIR 换算，都没有时为 0
+func Effective(log models.SetLog) float64 {
+	if log.RPE > 0 {
+		return log.RPE
+	}
+	if log.RIR != nil {
+		return math.Max(1, float64(10-*log.RIR))
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package effort
+
+import (
+	"strings"
+	"testing"
+	"workout-tracker/models"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestValidateRPE(t *testing.T) {
+	for rpe, ok := range map[float64]bool{0: true, 1: true, 7.5: true, 10: true, 0.5: false, 7.25: false, 10.5: false, -1: false} {
+		if err := ValidateRPE(rpe); (err == nil) != ok {
+			t.Errorf("ValidateRPE(%v) = %v", rpe, err)
+		}
+	}
+}
+
+func TestNormalizeTempo(t *testing.T) {
+	tests := []struct {
+		tempo string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{"3-1-x-0", "3-1-X-0", true},
+		{" 31X0 ", "3-1-X-0", true},
+		{"2-0-2-0", "2-0-2-0", true},
+		{"3-1-X", "", false},
+		{"3-10-X-0", "", false},
+		{"3-1-Y-0", "", false},
+		{"31X01", "", false},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeTempo(tt.tempo)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("NormalizeTempo(%q) = %q, %v", tt.tempo, got, err)
+		}
+	}
+}
+
+func TestValidateLog(t *testing.T) {
+	tests := []struct {
+		name string
+		log  models.SetLog
+		want models.SetLog
+		err  string
+	}{
+		{"normalizes tempo and note", models.SetLog{RPE: 8, Tempo: "30x0", Note: "  grindy "}, models.SetLog{RPE: 8, Tempo: "3-0-X-0", Note: "grindy"}, ""},
+		{"rir at failure", models.SetLog{RIR: intPtr(0)}, models.SetLog{RIR: intPtr(0)}, ""},
+		{"rir too high", models.SetLog{RIR: intPtr(MaxRIR + 1)}, models.SetLog{}, "rir must be between 0 and 10"},
+		{"negative rir", models.SetLog{RIR: intPtr(-1)}, models.SetLog{}, "rir must be between"},
+		{"bad rpe", models.SetLog{RPE: 11}, models.SetLog{}, "rpe must be between"},
+		{"bad tempo", models.SetLog{Tempo: "fast"}, models.SetLog{}, "four phases"},
+	}
+	for _, tt := range tests {
+		log := tt.log
+		err := ValidateLog(&log)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if log.RPE != tt.want.RPE || log.Tempo != tt.want.Tempo || log.Note != tt.want.Note || (log.RIR == nil) != (tt.want.RIR == nil) {
+			t.Errorf("%s: log = %+v, want %+v", tt.name, log, tt.want)
+		}
+	}
+}
+
+func TestValidateSession(t *testing.T) {
+	session := models.WorkoutSession{Exercises: []models.CompletedExercise{
+		{CompletedSets: 2, SetLogs: []models.SetLog{{RPE: 8}, {Tempo: "2020"}}},
+		// 全部为空的记录被去掉
+		{CompletedSets: 2, SetLogs: []models.SetLog{{}, {Note: " "}}},
+		// 组数按已完成组数和次数中较多的计算
+		{CompletedReps: []int{5, 5}, SetLogs: []models.SetLog{{}, {Pain: true}}},
+	}}
+	if err := ValidateSession(&session); err != nil {
+		t.Fatal(err)
+	}
+	if logs := session.Exercises[0].SetLogs; len(logs) != 2 || logs[1].Tempo != "2-0-2-0" {
+		t.Errorf("logs = %+v", logs)
+	}
+	if logs := session.Exercises[1].SetLogs; logs != nil {
+		t.Errorf("empty logs kept: %+v", logs)
+	}
+	if logs := session.Exercises[2].SetLogs; len(logs) != 2 {
+		t.Errorf("logs = %+v", logs)
+	}
+
+	tests := []struct {
+		exercise models.CompletedExercise
+		err      string
+	}{
+		{models.CompletedExercise{CompletedSets: 1, SetLogs: []models.SetLog{{}, {}}}, "exercises[0]: 2 set logs but only 1 sets"},
+		{models.CompletedExercise{CompletedSets: 2, SetLogs: []models.SetLog{{}, {RPE: 0.5}}}, "exercises[0].setLogs[1]: rpe must be between"},
+	}
+	for _, tt := range tests {
+		session := models.WorkoutSession{Exercises: []models.CompletedExercise{tt.exercise}}
+		if err := ValidateSession(&session); err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("err = %v, want %q", err, tt.err)
+		}
+	}
+}
+
+func TestEffective(t *testing.T) {
+	tests := []struct {
+		log  models.SetLog
+		want float64
+	}{
+		{models.SetLog{}, 0},
+		{models.SetLog{RPE: 8.5, RIR: intPtr(3)}, 8.5},
+		{models.SetLog{RIR: intPtr(2)}, 8},
+		{models.SetLog{RIR: intPtr(0)}, 10},
+		// 保留次数很多时至少为 1
+		{models.SetLog{RIR: intPtr(10)}, 1},
+	}
+	for _, tt := range tests {
+		if got := Effective(tt.log); got != tt.want {
+			t.Errorf("Effective(%+v) = %v, want %v", tt.log, got, tt.want)
+		}
+	}
+}
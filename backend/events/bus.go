@@ -0,0 +1,107 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 领域事件类型
+const (
+	SessionStarted   = "session.started"
+	SessionUpdated   = "session.updated"
+	SessionCompleted = "session.completed"
+	SetLogged        = "set.logged"
+	RestStarted      = "rest.started"
+	RestEnded        = "rest.ended"
+	RecordAchieved   = "record.achieved"
+
+	ExerciseCreated = "exercise.created"
+	ExerciseUpdated = "exercise.updated"
+	ExerciseDeleted = "exercise.deleted"
+	WorkoutCreated  = "workout.created"
+	WorkoutUpdated  = "workout.updated"
+	WorkoutDeleted  = "workout.deleted"
+)
+
+// Types 所有事件类型
+var Types = []string{
+	SessionStarted, SessionUpdated, SessionCompleted, SetLogged, RestStarted, RestEnded, RecordAchieved,
+	ExerciseCreated, ExerciseUpdated, ExerciseDeleted,
+	WorkoutCreated, WorkoutUpdated, WorkoutDeleted,
+}
+
+// IsType 检查事件类型是否存在
+func IsType(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event 领域事件
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	SessionID string      `json:"sessionId,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Time      time.Time   `json:"time"`
+}
+
+func New(eventType, sessionID string, data interface{}) Event {
+	return Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		SessionID: sessionID,
+		Data:      data,
+		Time:      time.Now(),
+	}
+}
+
+// Bus 进程内事件总线，订阅者处理过慢时丢弃事件，不阻塞发布方
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[int]chan Event
+	nextID      int
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]chan Event)}
+}
+
+func (b *Bus) Publish(events ...Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, event := range events {
+		for _, ch := range b.subscribers {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+// Subscribe 订阅所有事件，返回的函数用于取消订阅
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, buffer)
+	b.subscribers[id] = ch
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[id]; ok {
+			delete(b.subscribers, id)
+			close(ch)
+		}
+	}
+}
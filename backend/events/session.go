@@ -0,0 +1,63 @@
+package events
+
+import "workout-tracker/models"
+
+// SetLoggedData 完成一组的事件数据
+type SetLoggedData struct {
+	ExerciseID string  `json:"exerciseId"`
+	Block      string  `json:"block,omitempty"` // 训练块中的动作 SetNumber 即轮数
+	SetNumber  int     `json:"setNumber"`
+	Reps       int     `json:"reps"`
+	Weight     float64 `json:"weight,omitempty"`   // 公斤
+	Duration   int     `json:"duration,omitempty"` // 秒
+	Distance   float64 `json:"distance,omitempty"` // 米
+	RPE        float64 `json:"rpe,omitempty"`
+	RIR        *int    `json:"rir,omitempty"`
+}
+
+// SessionChanged 比较训练记录保存前后的状态，生成对应的事件
+// before 为 nil 表示新建的训练记录
+func SessionChanged(before *models.WorkoutSession, after models.WorkoutSession) []Event {
+	if before == nil {
+		result := []Event{New(SessionStarted, after.ID, after)}
+		if after.IsCompleted {
+			result = append(result, New(SessionCompleted, after.ID, after))
+		}
+		return result
+	}
+
+	var result []Event
+	// 同一个动作可以出现在不同的训练块中
+	previous := make(map[[2]string]models.CompletedExercise)
+	for _, exercise := range before.Exercises {
+		previous[[2]string{exercise.Block, exercise.ExerciseID}] = exercise
+	}
+	for _, exercise := range after.Exercises {
+		old := previous[[2]string{exercise.Block, exercise.ExerciseID}]
+		for set := old.CompletedSets; set < exercise.CompletedSets; set++ {
+			data := SetLoggedData{ExerciseID: exercise.ExerciseID, Block: exercise.Block, SetNumber: set + 1}
+			if set < len(exercise.CompletedReps) {
+				data.Reps = exercise.CompletedReps[set]
+			}
+			if set < len(exercise.CompletedWeights) {
+				data.Weight = exercise.CompletedWeights[set]
+			}
+			if set < len(exercise.CompletedDurations) {
+				data.Duration = exercise.CompletedDurations[set]
+			}
+			if set < len(exercise.CompletedDistances) {
+				data.Distance = exercise.CompletedDistances[set]
+			}
+			if set < len(exercise.SetLogs) {
+				data.RPE, data.RIR = exercise.SetLogs[set].RPE, exercise.SetLogs[set].RIR
+			}
+			result = append(result, New(SetLogged, after.ID, data))
+		}
+	}
+
+	result = append(result, New(SessionUpdated, after.ID, after))
+	if after.IsCompleted && !before.IsCompleted {
+		result = append(result, New(SessionCompleted, after.ID, after))
+	}
+	return result
+}
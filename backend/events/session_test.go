@@ -0,0 +1,43 @@
+package events
+
+import (
+	"testing"
+	"workout-tracker/models"
+)
+
+func TestSessionChangedTracksBlocksSeparately(t *testing.T) {
+	before := models.WorkoutSession{ID: "s1", Exercises: []models.CompletedExercise{
+		{ExerciseID: "pushup", Block: "a", CompletedSets: 1, CompletedReps: []int{20}},
+		{ExerciseID: "pushup", Block: "b"},
+	}}
+	after := models.WorkoutSession{ID: "s1", IsCompleted: true, Exercises: []models.CompletedExercise{
+		{ExerciseID: "pushup", Block: "a", CompletedSets: 2, CompletedReps: []int{20, 18}},
+		// 同一个动作在另一个训练块中从第一轮开始
+		{ExerciseID: "pushup", Block: "b", CompletedSets: 1, CompletedReps: []int{15}},
+	}}
+
+	events := SessionChanged(&before, after)
+	var logged []SetLoggedData
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+		if event.Type == SetLogged {
+			logged = append(logged, event.Data.(SetLoggedData))
+		}
+	}
+	want := []SetLoggedData{
+		{ExerciseID: "pushup", Block: "a", SetNumber: 2, Reps: 18},
+		{ExerciseID: "pushup", Block: "b", SetNumber: 1, Reps: 15},
+	}
+	if len(logged) != len(want) {
+		t.Fatalf("logged = %+v, want %+v", logged, want)
+	}
+	for i := range want {
+		if logged[i] != want[i] {
+			t.Errorf("logged[%d] = %+v, want %+v", i, logged[i], want[i])
+		}
+	}
+	if n := len(types); n != 4 || types[2] != SessionUpdated || types[3] != SessionCompleted {
+		t.Errorf("event types = %v", types)
+	}
+}
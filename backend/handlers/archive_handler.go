@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"workout-tracker/archive"
+	"workout-tracker/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ArchiveHandler struct {
+	service *archive.Service
+}
+
+func NewArchiveHandler(service *archive.Service) *ArchiveHandler {
+	return &ArchiveHandler{service: service}
+}
+
+// Export 以 zip 流的形式导出全部数据
+func (h *ArchiveHandler) Export(c *gin.Context) {
+	filename := "workout-export-" + time.Now().Format("20060102-150405") + ".zip"
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// 响应已经开始写入，出错时只能记录日志
+	if err := h.service.Export(c.Request.Context(), c.Writer); err != nil {
+		logging.FromContext(c.Request.Context()).Error("export archive failed", "error", err)
+	}
+}
+
+// Import 导入 zip 导出包，?mode=merge|replace&dryRun=true
+func (h *ArchiveHandler) Import(c *gin.Context) {
+	// 请求体额外留出 multipart 表单本身的开销
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, archive.MaxImportSize+1<<20)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Archive exceeds the %d byte limit", archive.MaxImportSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	zr, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid archive: " + err.Error()})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+	report, err := h.service.Import(c.Request.Context(), zr, c.Query("mode"), dryRun)
+	if errors.Is(err, archive.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
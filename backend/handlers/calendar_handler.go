@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"workout-tracker/calendar"
+	"workout-tracker/logging"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CalendarHandler struct {
+	repo *repository.FileRepository
+}
+
+func NewCalendarHandler(repo *repository.FileRepository) *CalendarHandler {
+	return &CalendarHandler{repo: repo}
+}
+
+func (h *CalendarHandler) GetFeeds(c *gin.Context) {
+	feeds, err := h.repo.WithContext(c.Request.Context()).GetAllCalendarFeeds()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]models.CalendarFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		feed.TokenHash = ""
+		result = append(result, feed)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *CalendarHandler) CreateFeed(c *gin.Context) {
+	var req calendar.FeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, tokenHash, err := calendar.NewToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	feed := models.CalendarFeed{
+		ID:        uuid.New().String(),
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: tokenHash,
+		CreatedAt: time.Now(),
+	}
+	if err := h.repo.WithContext(c.Request.Context()).SaveCalendarFeed(feed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	feed.TokenHash = ""
+	c.JSON(http.StatusCreated, calendar.FeedResponse{
+		CalendarFeed: feed,
+		Token:        token,
+		URL:          feedURL(c, token),
+	})
+}
+
+func (h *CalendarHandler) DeleteFeed(c *gin.Context) {
+	if err := h.repo.WithContext(c.Request.Context()).DeleteCalendarFeed(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed deleted successfully"})
+}
+
+// 订阅地址，日历应用通过它定期拉取
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/calendar/" + token + "/feed.ics"
+}
+
+// GetICS 输出 iCalendar 订阅，数据未变化时返回 304
+func (h *CalendarHandler) GetICS(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	feed, err := repo.GetCalendarFeedByTokenHash(calendar.HashToken(c.Param("token")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+
+	logging.AddAttrs(c.Request.Context(), "feed_id", feed.ID)
+
+	changeLog, err := repo.GetChangeLog()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := repo.TouchCalendarFeed(feed.ID, time.Now()); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("touch calendar feed failed", "feed_id", feed.ID, "error", err)
+	}
+
+	// 计划或训练记录变化时变更日志序号增加
+	etag := fmt.Sprintf(`"%s-%d"`, feed.ID, changeLog.LastSeq)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, max-age=0")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data := calendar.Feed{Name: feed.Name}
+	if data.Workouts, err = repo.GetAllWorkouts(); err == nil {
+		if data.Sessions, err = repo.GetAllSessions(); err == nil {
+			data.Exercises, err = repo.GetAllExercises()
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := data.Write(&buf, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="workouts.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
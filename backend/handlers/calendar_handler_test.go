@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"workout-tracker/calendar"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCalendarFeedLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewFileRepository(t.TempDir())
+	handler := NewCalendarHandler(repo)
+	router := gin.New()
+	router.GET("/api/calendar/feeds", handler.GetFeeds)
+	router.POST("/api/calendar/feeds", handler.CreateFeed)
+	router.DELETE("/api/calendar/feeds/:id", handler.DeleteFeed)
+	router.GET("/api/calendar/:token/feed.ics", handler.GetICS)
+
+	request := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	start := time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)
+	if err := repo.SaveSession(models.WorkoutSession{ID: "s1", Date: start, StartTime: start, EndTime: start.Add(time.Hour), IsCompleted: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveSession(models.WorkoutSession{ID: "s2", Date: start, StartTime: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(http.MethodPost, "/api/calendar/feeds", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("create without name: %d", w.Code)
+	}
+	w := request(http.MethodPost, "/api/calendar/feeds", `{"name":" phone "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	var created calendar.FeedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "phone" || created.Token == "" || created.TokenHash != "" || !strings.HasSuffix(created.URL, "/api/calendar/"+created.Token+"/feed.ics") {
+		t.Fatalf("created = %+v", created)
+	}
+
+	// 列表不返回令牌和哈希
+	w = request(http.MethodGet, "/api/calendar/feeds", "")
+	if strings.Contains(w.Body.String(), created.Token) || strings.Contains(w.Body.String(), calendar.HashToken(created.Token)) {
+		t.Errorf("feed list leaks the token: %s", w.Body)
+	}
+
+	ics := "/api/calendar/" + created.Token + "/feed.ics"
+	w = request(http.MethodGet, ics, "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("feed: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	// 只发布已完成的训练
+	body := w.Body.String()
+	if !strings.Contains(body, "BEGIN:VCALENDAR") || strings.Count(body, "BEGIN:VEVENT") != 1 || !strings.Contains(body, "s1") {
+		t.Errorf("feed body:\n%s", body)
+	}
+
+	etag := w.Header().Get("ETag")
+	if w := request(http.MethodGet, ics, "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("unchanged feed: %d, want 304", w.Code)
+	}
+	// 训练记录变化后 ETag 改变
+	if err := repo.SaveSession(models.WorkoutSession{ID: "s3", Date: start, IsCompleted: true}); err != nil {
+		t.Fatal(err)
+	}
+	if w := request(http.MethodGet, ics, "", "If-None-Match", etag); w.Code != http.StatusOK || strings.Count(w.Body.String(), "BEGIN:VEVENT") != 2 {
+		t.Errorf("changed feed: %d %s", w.Code, w.Body)
+	}
+
+	if w := request(http.MethodGet, "/api/calendar/wrong/feed.ics", ""); w.Code != http.StatusNotFound {
+		t.Errorf("wrong token: %d", w.Code)
+	}
+	if w := request(http.MethodDelete, "/api/calendar/feeds/"+created.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	if w := request(http.MethodDelete, "/api/calendar/feeds/"+created.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("delete again: %d", w.Code)
+	}
+	// 撤销后令牌失效
+	if w := request(http.MethodGet, ics, ""); w.Code != http.StatusNotFound {
+		t.Errorf("revoked token: %d", w.Code)
+	}
+}
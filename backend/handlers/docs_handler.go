@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+	"workout-tracker/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DocsHandler struct {
+	doc *openapi.Document
+}
+
+func NewDocsHandler(doc *openapi.Document) *DocsHandler {
+	return &DocsHandler{doc: doc}
+}
+
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.JSON(http.StatusOK, h.doc)
+}
+
+func (h *DocsHandler) GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsHTML)
+}
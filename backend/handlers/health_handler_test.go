@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"workout-tracker/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var storageErr error
+	checker := health.NewChecker(health.Check{Name: "storage", Run: func(ctx context.Context) error { return storageErr }})
+	handler := NewHealthHandler(checker)
+	router := gin.New()
+	router.GET("/healthz", handler.Healthz)
+	router.GET("/readyz", handler.Readyz)
+
+	tests := []struct {
+		name    string
+		setup   func()
+		path    string
+		status  int
+		overall string
+	}{
+		{"liveness", func() {}, "/healthz", http.StatusOK, health.StatusOK},
+		{"ready", func() {}, "/readyz", http.StatusOK, health.StatusOK},
+		{"storage unreachable", func() { storageErr = errors.New("connection refused") }, "/readyz", http.StatusServiceUnavailable, health.StatusUnavailable},
+		{"shutting down", func() { storageErr = nil; checker.SetShuttingDown() }, "/readyz", http.StatusServiceUnavailable, health.StatusShuttingDown},
+		// 关闭期间仍然存活，避免被提前强制重启
+		{"liveness while shutting down", func() {}, "/healthz", http.StatusOK, health.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			var report health.Report
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.overall {
+				t.Errorf("report = %+v, want %s", report, tt.overall)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"workout-tracker/history"
+	"workout-tracker/logging"
+	"workout-tracker/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导出格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+type HistoryHandler struct {
+	repo     *repository.FileRepository
+	importer *history.Importer
+}
+
+func NewHistoryHandler(repo *repository.FileRepository, importer *history.Importer) *HistoryHandler {
+	return &HistoryHandler{repo: repo, importer: importer}
+}
+
+// Export 按组导出训练历史，?format=csv|xlsx&from=2024-01-01&to=2024-12-31&columns=date,exercise,reps
+func (h *HistoryHandler) Export(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	format := strings.ToLower(c.DefaultQuery("format", FormatCSV))
+	if format != FormatCSV && format != FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+
+	columns, err := history.ParseColumns(c.Query("columns"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := parseDay(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date, expected YYYY-MM-DD"})
+		return
+	}
+	to, err := parseDay(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date, expected YYYY-MM-DD"})
+		return
+	}
+	// to 包含当天
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	workouts, err := repo.GetAllWorkouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := "workout-history-" + time.Now().Format("20060102") + "." + format
+	if format == FormatXLSX {
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// 响应已经开始写入，出错时只能记录日志
+	var w history.Writer
+	if format == FormatXLSX {
+		w, err = history.NewXLSXWriter(c.Writer)
+	} else {
+		w, err = history.NewCSVWriter(c.Writer)
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("export history failed", "format", format, "error", err)
+		return
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.Title
+	}
+	err = w.WriteRow(header)
+	if err == nil {
+		err = history.Rows(sessions, workouts, exercises, from, to, func(row history.Row) error {
+			return w.WriteRow(history.Values(columns, row))
+		})
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("export history failed", "format", format, "error", err)
+	}
+}
+
+// 解析 YYYY-MM-DD 格式的日期，为空时返回零值
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// CreateImport 上传 Strong/Hevy 导出的 CSV，返回预览和动作匹配结果
+func (h *HistoryHandler) CreateImport(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	preview, err := h.importer.Create(c.Request.Context(), file, header.Filename, c.Query("source"), c.Query("weightUnit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, preview)
+}
+
+func (h *HistoryHandler) GetImport(c *gin.Context) {
+	preview, err := h.importer.Preview(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// CommitImport 确认动作映射并写入训练记录
+func (h *HistoryHandler) CommitImport(c *gin.Context) {
+	var confirmation history.Confirmation
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&confirmation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.importer.Commit(c.Request.Context(), c.Param("id"), confirmation)
+	var unresolved *history.UnresolvedError
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, result)
+	case errors.Is(err, history.ErrImportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, history.ErrImportCommitted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &unresolved):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "unresolved": unresolved.Names})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *HistoryHandler) DeleteImport(c *gin.Context) {
+	if err := h.importer.Discard(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Import discarded successfully"})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"workout-tracker/patch"
+
+	"github.com/gin-gonic/gin"
+)
+
+const jsonPatchContentType = "application/json-patch+json"
+
+// applyPatch 将请求体作为补丁应用到 original，结果写入 target
+// Content-Type 为 application/json-patch+json 时按 RFC 6902 处理，否则按 RFC 7396 合并
+func applyPatch(c *gin.Context, original interface{}, target interface{}) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	doc, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == jsonPatchContentType {
+		patched, err = patch.ApplyJSONPatch(doc, body)
+	} else {
+		patched, err = patch.MergePatch(doc, body)
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(patched, target)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"workout-tracker/events"
+	"workout-tracker/repository"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+type StreamHandler struct {
+	repo *repository.FileRepository
+	bus  *events.Bus
+
+	// 服务关闭时结束所有推送连接，否则长连接会一直阻塞关闭
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewStreamHandler(repo *repository.FileRepository, bus *events.Bus) *StreamHandler {
+	return &StreamHandler{repo: repo, bus: bus, done: make(chan struct{})}
+}
+
+// Close 结束所有正在进行的推送
+func (h *StreamHandler) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// 客户端可以上报的瞬时事件，不会持久化
+var clientEventTypes = map[string]bool{
+	events.RestStarted: true,
+	events.RestEnded:   true,
+}
+
+type clientEvent struct {
+	Type string      `json:"type" binding:"required"`
+	Data interface{} `json:"data"`
+}
+
+// StreamSession 推送单个训练记录的实时事件
+func (h *StreamHandler) StreamSession(c *gin.Context) {
+	id := c.Param("id")
+	session, err := h.repo.WithContext(c.Request.Context()).GetSessionByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ch, unsubscribe := h.bus.Subscribe(64)
+	defer unsubscribe()
+
+	// 先推送当前状态，响应头要在第一次写入之前设置
+	streamHeaders(c)
+	c.Render(-1, sse.Event{Event: "session", Data: session})
+	c.Writer.Flush()
+	h.stream(c, ch, func(event events.Event) bool {
+		return event.SessionID == id
+	})
+}
+
+// StreamSessions 推送所有训练的开始和结束事件
+func (h *StreamHandler) StreamSessions(c *gin.Context) {
+	ch, unsubscribe := h.bus.Subscribe(64)
+	defer unsubscribe()
+
+	streamHeaders(c)
+	h.stream(c, ch, func(event events.Event) bool {
+		return event.Type == events.SessionStarted || event.Type == events.SessionCompleted
+	})
+}
+
+// 禁止客户端和反向代理缓存、缓冲推送内容
+func streamHeaders(c *gin.Context) {
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+}
+
+func (h *StreamHandler) stream(c *gin.Context, ch <-chan events.Event, filter func(events.Event) bool) {
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if filter(event) {
+				c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event})
+			}
+			return true
+		case <-heartbeat.C:
+			c.Render(-1, sse.Event{Event: "ping", Data: time.Now().Unix()})
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		case <-h.done:
+			return false
+		}
+	})
+}
+
+// PostSessionEvent 接收移动端上报的休息开始/结束等事件并转发给订阅者
+func (h *StreamHandler) PostSessionEvent(c *gin.Context) {
+	id := c.Param("id")
+	var req clientEvent
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !clientEventTypes[req.Type] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported event type"})
+		return
+	}
+	if _, err := h.repo.WithContext(c.Request.Context()).GetSessionByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := events.New(req.Type, id, req.Data)
+	h.bus.Publish(event)
+	c.JSON(http.StatusAccepted, event)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"workout-tracker/events"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamSessionSendsInitialStateImmediately(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewFileRepository(t.TempDir())
+	if err := repo.SaveSession(models.WorkoutSession{ID: "s1", Date: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewStreamHandler(repo, events.NewBus())
+	router := gin.New()
+	router.GET("/api/sessions/:id/stream", handler.StreamSession)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	defer handler.Close()
+
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(server.URL + "/api/sessions/s1/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	for header, want := range map[string]string{
+		"Cache-Control":     "no-cache",
+		"X-Accel-Buffering": "no",
+		"Content-Type":      "text/event-stream",
+	} {
+		if got := resp.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// 当前状态不等待下一个事件或心跳就能读到
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(line) != "event:session" {
+		t.Errorf("first line = %q", line)
+	}
+}
+
+func TestStreamHandlerCloseEndsStreams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewStreamHandler(repository.NewFileRepository(t.TempDir()), events.NewBus())
+	started := make(chan struct{})
+	router := gin.New()
+	router.GET("/api/sessions/stream", func(c *gin.Context) {
+		close(started)
+		handler.StreamSessions(c)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(server.URL + "/api/sessions/stream")
+		if err != nil {
+			done <- err
+			return
+		}
+		defer resp.Body.Close()
+		_, err = io.Copy(io.Discard, resp.Body)
+		done <- err
+	}()
+	<-started
+	handler.Close()
+	// 关闭后推送结束，服务才能在超时前退出
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("stream still open after Close")
+	}
+	handler.Close()
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"workout-tracker/delta"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SyncHandler struct {
+	service *delta.Service
+}
+
+func NewSyncHandler(service *delta.Service) *SyncHandler {
+	return &SyncHandler{service: service}
+}
+
+func (h *SyncHandler) Sync(c *gin.Context) {
+	var req delta.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.service.Sync(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
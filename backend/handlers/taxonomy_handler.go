@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+	"workout-tracker/taxonomy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TaxonomyHandler struct {
+	repo *repository.FileRepository
+}
+
+func NewTaxonomyHandler(repo *repository.FileRepository) *TaxonomyHandler {
+	return &TaxonomyHandler{repo: repo}
+}
+
+// GetTaxonomy 按类型分组返回全部分类
+func (h *TaxonomyHandler) GetTaxonomy(c *gin.Context) {
+	terms, err := h.repo.WithContext(c.Request.Context()).GetAllTaxonomyTerms()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make(map[string][]models.TaxonomyTerm, len(taxonomy.Kinds))
+	for _, kind := range taxonomy.Kinds {
+		result[kind] = []models.TaxonomyTerm{}
+	}
+	for _, term := range terms {
+		if _, ok := result[term.Kind]; ok {
+			result[term.Kind] = append(result[term.Kind], term)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// 路径中的分类类型，不支持时返回 404
+func taxonomyKind(c *gin.Context) (string, bool) {
+	kind := c.Param("kind")
+	if !taxonomy.IsKind(kind) {
+		c.JSON(http.StatusNotFound, gin.H{"error": taxonomy.ErrUnknownKind.Error()})
+		return "", false
+	}
+	return kind, true
+}
+
+func (h *TaxonomyHandler) GetTerms(c *gin.Context) {
+	kind, ok := taxonomyKind(c)
+	if !ok {
+		return
+	}
+	terms, err := h.repo.WithContext(c.Request.Context()).GetTaxonomyTermsByKind(kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, terms)
+}
+
+func (h *TaxonomyHandler) CreateTerm(c *gin.Context) {
+	kind, ok := taxonomyKind(c)
+	if !ok {
+		return
+	}
+	var req taxonomy.TermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Normalize()
+
+	repo := h.repo.WithContext(c.Request.Context())
+	if !h.checkName(c, repo, kind, "", req.Name) {
+		return
+	}
+	term := models.TaxonomyTerm{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Name:      req.Name,
+		Aliases:   req.Aliases,
+		CreatedAt: time.Now(),
+	}
+	if err := repo.SaveTaxonomyTerm(term); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, term)
+}
+
+func (h *TaxonomyHandler) UpdateTerm(c *gin.Context) {
+	kind, ok := taxonomyKind(c)
+	if !ok {
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	term, err := repo.GetTaxonomyTerm(kind, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	var req taxonomy.TermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Normalize()
+
+	if !h.checkName(c, repo, kind, term.ID, req.Name) {
+		return
+	}
+	term.Name = req.Name
+	term.Aliases = req.Aliases
+	if err := repo.SaveTaxonomyTerm(*term); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, term)
+}
+
+// 同一类型中名称不能重复
+func (h *TaxonomyHandler) checkName(c *gin.Context, repo *repository.FileRepository, kind, id, name string) bool {
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must not be empty"})
+		return false
+	}
+	terms, err := repo.GetTaxonomyTermsByKind(kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	for _, term := range terms {
+		if term.ID != id && strings.EqualFold(term.Name, name) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a term with the same name already exists", "id": term.ID})
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteTerm 仍被动作引用的分类不能删除，返回 409 和引用它的动作
+func (h *TaxonomyHandler) DeleteTerm(c *gin.Context) {
+	kind, ok := taxonomyKind(c)
+	if !ok {
+		return
+	}
+	repo := h.repo.WithContext(c.Request.Context())
+	id := c.Param("id")
+	if _, err := repo.GetTaxonomyTerm(kind, id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	inUse := &taxonomy.InUseError{}
+	for _, exercise := range exercises {
+		if taxonomy.Uses(exercise, id) {
+			inUse.Exercises = append(inUse.Exercises, exercise.ID)
+		}
+	}
+	if len(inUse.Exercises) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": inUse.Error(), "exercises": inUse.Exercises})
+		return
+	}
+
+	if err := repo.DeleteTaxonomyTerm(kind, id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Taxonomy term deleted successfully"})
+}
+
+// Migrate 按 bodyPart 为还没有分类的动作填写主要肌群，dryRun=true 时只返回结果
+func (h *TaxonomyHandler) Migrate(c *gin.Context) {
+	dryRun := c.Query("dryRun") == "true"
+	report, err := taxonomy.Migrate(h.repo.WithContext(c.Request.Context()), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+	"workout-tracker/taxonomy"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTaxonomyTerms(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewFileRepository(t.TempDir())
+	if _, err := taxonomy.Seed(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveExercise(models.Exercise{ID: "bench", PrimaryMuscles: []string{"chest"}}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewTaxonomyHandler(repo)
+	router := gin.New()
+	router.GET("/api/taxonomy", handler.GetTaxonomy)
+	router.GET("/api/taxonomy/:kind", handler.GetTerms)
+	router.POST("/api/taxonomy/:kind", handler.CreateTerm)
+	router.PUT("/api/taxonomy/:kind/:id", handler.UpdateTerm)
+	router.DELETE("/api/taxonomy/:kind/:id", handler.DeleteTerm)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/taxonomy/equipment", `{"name":" 雪橇 ","aliases":["sled"," sled ",""]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	var sled models.TaxonomyTerm
+	if err := json.Unmarshal(w.Body.Bytes(), &sled); err != nil {
+		t.Fatal(err)
+	}
+	if sled.ID == "" || sled.Kind != models.TermEquipment || sled.Name != "雪橇" || len(sled.Aliases) != 1 {
+		t.Fatalf("created = %+v", sled)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"unknown kind", http.MethodGet, "/api/taxonomy/colour", "", http.StatusNotFound},
+		{"create in unknown kind", http.MethodPost, "/api/taxonomy/colour", `{"name":"red"}`, http.StatusNotFound},
+		{"missing name", http.MethodPost, "/api/taxonomy/equipment", `{}`, http.StatusBadRequest},
+		{"blank name", http.MethodPost, "/api/taxonomy/equipment", `{"name":"  "}`, http.StatusBadRequest},
+		{"name matching an alias", http.MethodPost, "/api/taxonomy/equipment", `{"name":"Barbell"}`, http.StatusCreated},
+		{"duplicate name in another case", http.MethodPost, "/api/taxonomy/equipment", `{"name":"barbell"}`, http.StatusConflict},
+		// 不同类型中可以同名
+		{"same name in another kind", http.MethodPost, "/api/taxonomy/pattern", `{"name":"雪橇"}`, http.StatusCreated},
+		{"rename to existing name", http.MethodPut, "/api/taxonomy/equipment/" + sled.ID, `{"name":"杠铃"}`, http.StatusConflict},
+		{"rename keeping own name", http.MethodPut, "/api/taxonomy/equipment/" + sled.ID, `{"name":"雪橇","aliases":["prowler"]}`, http.StatusOK},
+		{"update with wrong kind", http.MethodPut, "/api/taxonomy/muscle/" + sled.ID, `{"name":"雪橇"}`, http.StatusNotFound},
+		{"delete term in use", http.MethodDelete, "/api/taxonomy/muscle/chest", "", http.StatusConflict},
+		{"delete unused term", http.MethodDelete, "/api/taxonomy/equipment/" + sled.ID, "", http.StatusOK},
+		{"delete missing term", http.MethodDelete, "/api/taxonomy/equipment/" + sled.ID, "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.method, tt.path, tt.body)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+
+	w = request(http.MethodGet, "/api/taxonomy", "")
+	var grouped map[string][]models.TaxonomyTerm
+	if err := json.Unmarshal(w.Body.Bytes(), &grouped); err != nil {
+		t.Fatal(err)
+	}
+	if len(grouped) != len(taxonomy.Kinds) || len(grouped[models.TermPattern]) != 6 {
+		t.Errorf("grouped = %v", grouped)
+	}
+	for _, term := range grouped[models.TermEquipment] {
+		if term.ID == sled.ID {
+			t.Error("deleted term listed")
+		}
+	}
+	// 仍被引用时返回引用它的动作
+	w = request(http.MethodDelete, "/api/taxonomy/muscle/chest", "")
+	var conflict struct {
+		Exercises []string `json:"exercises"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	if len(conflict.Exercises) != 1 || conflict.Exercises[0] != "bench" {
+		t.Errorf("conflict = %s", w.Body)
+	}
+}
@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"workout-tracker/logging"
+	"workout-tracker/media"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+	"workout-tracker/uploads"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UploadHandler struct {
+	repo     *repository.FileRepository
+	store    *uploads.Store
+	sweeper  *uploads.Sweeper
+	sessions *uploads.Sessions
+	limits   uploads.Limits
+}
+
+func NewUploadHandler(repo *repository.FileRepository, store *uploads.Store, sweeper *uploads.Sweeper, sessions *uploads.Sessions, limits uploads.Limits) *UploadHandler {
+	return &UploadHandler{repo: repo, store: store, sweeper: sweeper, sessions: sessions, limits: limits}
+}
+
+// UploadFile 以表单上传单个文件，大文件使用分片上传
+func (h *UploadHandler) UploadFile(c *gin.Context) {
+	// 请求体额外留出 multipart 表单本身的开销
+	largest := h.limits.Largest()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, largest+1<<20)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the %d byte limit", largest)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	item, status, err := h.save(c.Request.Context(), file, header.Size, header.Filename)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// 上传的文件内容：表单文件或分片上传拼接好的临时文件
+type uploadSource interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+// 按文件内容校验类型和大小后保存，图片生成缩放版本，视频和动图校验编码并生成封面。
+// 出错时返回对应的状态码
+func (h *UploadHandler) save(ctx context.Context, src uploadSource, size int64, filename string) (*models.Media, int, error) {
+	// 按文件内容判断类型，扩展名必须与内容一致
+	head := make([]byte, uploads.SniffLen)
+	n, err := src.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, http.StatusBadRequest, err
+	}
+	fileType, err := uploads.Detect(head[:n], filename)
+	if err != nil {
+		if errors.Is(err, uploads.ErrExtensionMismatch) {
+			return nil, http.StatusBadRequest, err
+		}
+		return nil, http.StatusUnsupportedMediaType, err
+	}
+	if limit := h.limits.Max(fileType.MIME); size > limit {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("File exceeds the %d byte limit", limit)
+	}
+
+	// 相同内容已经处理过时直接复用
+	hash, err := uploads.HashReader(io.NewSectionReader(src, 0, size))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	repo := h.repo.WithContext(ctx)
+	existing, err := repo.GetMediaByHash(hash)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if existing != nil && h.store.Reuse(ctx, mediaFiles(existing)...) {
+		return existing, http.StatusOK, nil
+	}
+
+	var item *models.Media
+	switch {
+	case media.Processable(fileType.MIME):
+		// 图片去除元数据并生成缩放版本，动图按视频片段处理
+		item, err = h.saveImage(ctx, src, size, fileType, hash)
+		if errors.Is(err, media.ErrNotProcessable) && media.IsClip(fileType.MIME) {
+			item, err = h.saveClip(ctx, src, size, fileType, hash)
+		}
+	case media.IsClip(fileType.MIME):
+		item, err = h.saveClip(ctx, src, size, fileType, hash)
+	default:
+		item, err = h.saveRaw(ctx, src, size, fileType, hash)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, media.ErrTooLarge):
+		return nil, http.StatusRequestEntityTooLarge, err
+	case errors.Is(err, media.ErrUnsupportedCodec):
+		return nil, http.StatusUnsupportedMediaType, err
+	case errors.Is(err, media.ErrInvalidVideo), errors.Is(err, media.ErrNotProcessable), errors.Is(err, errDecode):
+		return nil, http.StatusBadRequest, err
+	default:
+		logging.FromContext(ctx).Error("save upload failed", "filename", filename, "error", err)
+		return nil, http.StatusInternalServerError, errors.New("Failed to save file")
+	}
+
+	if err := repo.SaveMedia(*item); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return item, http.StatusOK, nil
+}
+
+// 图片解码失败
+var errDecode = errors.New("decode image")
+
+// 保存处理后的图片及其缩放版本，文件名以上传内容的哈希开头
+func (h *UploadHandler) saveImage(ctx context.Context, src uploadSource, size int64, fileType *uploads.Type, hash string) (*models.Media, error) {
+	data := make([]byte, size)
+	if _, err := src.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	result, err := media.Process(data, fileType.MIME)
+	if err != nil {
+		if errors.Is(err, media.ErrNotProcessable) || errors.Is(err, media.ErrTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errDecode, err)
+	}
+
+	original := result.Original
+	filename := hash + original.Ext
+	if err := h.store.Save(ctx, filename, bytes.NewReader(original.Data), int64(len(original.Data))); err != nil {
+		return nil, err
+	}
+
+	item := &models.Media{
+		URL:       uploads.URL(filename),
+		Hash:      hash,
+		Type:      original.MIME,
+		Width:     original.Width,
+		Height:    original.Height,
+		Size:      len(original.Data),
+		Variants:  []models.ImageVariant{},
+		CreatedAt: time.Now(),
+	}
+	for _, variant := range result.Variants {
+		name := hash + variant.Suffix + variant.Ext
+		if err := h.store.Save(ctx, name, bytes.NewReader(variant.Data), int64(len(variant.Data))); err != nil {
+			return nil, err
+		}
+		item.Variants = append(item.Variants, models.ImageVariant{
+			URL:    uploads.URL(name),
+			Type:   variant.MIME,
+			Width:  variant.Width,
+			Height: variant.Height,
+			Size:   len(variant.Data),
+		})
+	}
+	return item, nil
+}
+
+// 校验视频或动图的容器和编码后按原样保存，并生成封面图
+func (h *UploadHandler) saveClip(ctx context.Context, src uploadSource, size int64, fileType *uploads.Type, hash string) (*models.Media, error) {
+	info, err := media.ProbeVideo(src, size, fileType.MIME)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := hash + fileType.Extension()
+	if err := h.store.Save(ctx, filename, io.NewSectionReader(src, 0, size), size); err != nil {
+		return nil, err
+	}
+	item := &models.Media{
+		URL:       uploads.URL(filename),
+		Hash:      hash,
+		Type:      fileType.MIME,
+		Width:     info.Width,
+		Height:    info.Height,
+		Size:      int(size),
+		Variants:  []models.ImageVariant{},
+		Duration:  info.Duration,
+		Codecs:    info.Codecs(),
+		CreatedAt: time.Now(),
+	}
+
+	// 没有封面不影响上传，客户端可以在动作中自行指定
+	poster, err := h.poster(ctx, src, size, fileType, info)
+	switch {
+	case err == nil:
+		name := hash + poster.Suffix + poster.Ext
+		if err := h.store.Save(ctx, name, bytes.NewReader(poster.Data), int64(len(poster.Data))); err != nil {
+			return nil, err
+		}
+		item.Poster = uploads.URL(name)
+	case !errors.Is(err, media.ErrNoPoster):
+		logging.FromContext(ctx).Warn("extract poster frame failed", "url", item.URL, "error", err)
+	}
+	return item, nil
+}
+
+// 截取封面需要本地文件，表单上传的小文件可能只在内存中
+func (h *UploadHandler) poster(ctx context.Context, src uploadSource, size int64, fileType *uploads.Type, info *media.VideoInfo) (*media.File, error) {
+	if f, ok := src.(*os.File); ok {
+		return media.Poster(ctx, f.Name(), fileType.MIME, info)
+	}
+
+	tmp, err := os.CreateTemp("", "workout-clip-*"+fileType.Extension())
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, io.NewSectionReader(src, 0, size)); err != nil {
+		return nil, err
+	}
+	return media.Poster(ctx, tmp.Name(), fileType.MIME, info)
+}
+
+// 其他允许的类型按原样保存
+func (h *UploadHandler) saveRaw(ctx context.Context, src uploadSource, size int64, fileType *uploads.Type, hash string) (*models.Media, error) {
+	filename := hash + fileType.Extension()
+	if err := h.store.Save(ctx, filename, io.NewSectionReader(src, 0, size), size); err != nil {
+		return nil, err
+	}
+	return &models.Media{
+		URL:       uploads.URL(filename),
+		Hash:      hash,
+		Type:      fileType.MIME,
+		Size:      int(size),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// 上传记录对应的全部文件名
+func mediaFiles(item *models.Media) []string {
+	var names []string
+	add := func(url string) {
+		if name, ok := uploads.NameFromURL(url); ok {
+			names = append(names, name)
+		}
+	}
+	add(item.URL)
+	for _, variant := range item.Variants {
+		add(variant.URL)
+	}
+	if item.Poster != "" {
+		add(item.Poster)
+	}
+	return names
+}
+
+// CreateUploadSession 创建分片上传会话，按扩展名预先检查类型和大小，完成时再按内容校验
+func (h *UploadHandler) CreateUploadSession(c *gin.Context) {
+	var req uploads.SessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fileType, ok := uploads.ByExtension(req.Filename)
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": uploads.ErrUnsupportedType.Error()})
+		return
+	}
+	if limit := h.limits.Max(fileType.MIME); req.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the %d byte limit", limit)})
+		return
+	}
+
+	session, err := h.sessions.Create(c.Request.Context(), req.Filename, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetUploadSession 查询已接收的字节数，用于断点续传
+func (h *UploadHandler) GetUploadSession(c *gin.Context) {
+	session, err := h.sessions.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// UploadChunk 写入一个分片，请求头 Content-Range: bytes <start>-<end>/<size>。
+// 最后一个分片写入后校验并保存文件，响应中的 media 为上传结果
+func (h *UploadHandler) UploadChunk(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	start, end, total, err := parseContentRange(c.GetHeader("Content-Range"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	length := end - start + 1
+	if length > uploads.MaxChunkSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Chunk exceeds the %d byte limit", uploads.MaxChunkSize)})
+		return
+	}
+	if c.Request.ContentLength >= 0 && c.Request.ContentLength != length {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-Length does not match Content-Range"})
+		return
+	}
+
+	session, err := h.sessions.Get(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if total != session.Size {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-Range size does not match the upload session"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, length)
+	session, err = h.sessions.Append(ctx, id, start, length, body)
+	switch {
+	case err == nil:
+	case errors.Is(err, uploads.ErrSessionComplete):
+		// 客户端没有收到最后一个分片的响应而重试
+		c.JSON(http.StatusOK, session)
+		return
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "offset": session.Offset})
+		return
+	case errors.Is(err, uploads.ErrSessionBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, uploads.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if session.Offset < session.Size {
+		c.JSON(http.StatusOK, session)
+		return
+	}
+
+	// 全部接收后与表单上传一样校验并保存，失败的文件无法继续使用，直接删除会话
+	file, err := h.sessions.Open(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	item, status, err := h.save(ctx, file, session.Size, session.Filename)
+	file.Close()
+	if err != nil {
+		h.sessions.Delete(ctx, id)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	session, err = h.sessions.Complete(ctx, id, item)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// DeleteUploadSession 取消分片上传
+func (h *UploadHandler) DeleteUploadSession(c *gin.Context) {
+	if err := h.sessions.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Upload session deleted successfully"})
+}
+
+// 解析 bytes <start>-<end>/<size>
+func parseContentRange(value string) (int64, int64, int64, error) {
+	invalid := fmt.Errorf("invalid Content-Range %q, expected bytes <start>-<end>/<size>", value)
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	byteRange, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	first, last, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	start, err1 := strconv.ParseInt(first, 10, 64)
+	end, err2 := strconv.ParseInt(last, 10, 64)
+	total, err3 := strconv.ParseInt(size, 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || start < 0 || end < start || end >= total {
+		return 0, 0, 0, invalid
+	}
+	return start, end, total, nil
+}
+
+// Serve 读取上传的文件：对象存储配置了预签名时重定向到临时地址，否则由服务端代理并支持 Range 请求
+func (h *UploadHandler) Serve(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("filepath"), "/")
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") {
+		http.NotFound(c.Writer, c.Request)
+		return
+	}
+
+	ctx := c.Request.Context()
+	url, err := h.store.ReadURL(ctx, name)
+	if err != nil {
+		h.readFailed(c, name, err)
+		return
+	}
+	if url != "" {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	file, obj, err := h.store.Open(ctx, name)
+	if err != nil {
+		h.readFailed(c, name, err)
+		return
+	}
+	defer file.Close()
+	http.ServeContent(c.Writer, c.Request, name, obj.ModTime, file)
+}
+
+// 响应头已经按文件类型设置，错误以纯文本返回
+func (h *UploadHandler) readFailed(c *gin.Context, name string, err error) {
+	if errors.Is(err, uploads.ErrNotFound) {
+		http.NotFound(c.Writer, c.Request)
+		return
+	}
+	logging.FromContext(c.Request.Context()).Error("read upload failed", "name", name, "error", err)
+	http.Error(c.Writer, "failed to read file", http.StatusBadGateway)
+}
+
+// CollectGarbage 清理未被引用且超过保留期的上传文件，?dryRun=true 时只返回将被清理的文件
+func (h *UploadHandler) CollectGarbage(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+	report, err := h.sweeper.Sweep(c.Request.Context(), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Migrate 把原来本地目录中的文件全部复制到当前的对象存储
+func (h *UploadHandler) Migrate(c *gin.Context) {
+	report, err := h.store.Migrate(c.Request.Context())
+	if errors.Is(err, uploads.ErrNoMigration) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+	"workout-tracker/uploads"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newUploadRouter(t *testing.T, limits uploads.Limits) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewFileRepository(t.TempDir())
+	handler := NewUploadHandler(repo, uploads.NewStore(uploads.NewLocal(t.TempDir())), nil, nil, limits)
+	router := gin.New()
+	router.POST("/api/upload", handler.UploadFile)
+	return router
+}
+
+func uploadRequest(t *testing.T, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if filename != "" {
+		part, err := form.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestUploadFileValidatesContent(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	router := newUploadRouter(t, uploads.Limits{File: int64(img.Len()), Video: 1 << 20})
+
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		status   int
+	}{
+		{"image", "dot.png", img.Bytes(), http.StatusOK},
+		{"no file", "", nil, http.StatusBadRequest},
+		{"html disguised as image", "photo.png", []byte("<html><script>alert(1)</script></html>"), http.StatusUnsupportedMediaType},
+		{"extension does not match content", "dot.jpg", img.Bytes(), http.StatusBadRequest},
+		// 图片超过图片上限，即使小于视频上限
+		{"image over its limit", "big.png", append(img.Bytes(), make([]byte, 1024)...), http.StatusRequestEntityTooLarge},
+		{"request over the largest limit", "huge.png", append(img.Bytes(), make([]byte, 3<<20)...), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, uploadRequest(t, tt.filename, tt.content))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var item models.Media
+			if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+				t.Fatal(err)
+			}
+			if item.Type != "image/png" || item.URL == "" {
+				t.Errorf("media = %+v", item)
+			}
+		})
+	}
+}
+
+func TestUploadFileReusesSameContent(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	router := newUploadRouter(t, uploads.Limits{File: 1 << 20, Video: 1 << 20})
+
+	var urls []string
+	for _, filename := range []string{"a.png", "b.png"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, uploadRequest(t, filename, img.Bytes()))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var item models.Media
+		if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+			t.Fatal(err)
+		}
+		if item.Hash != uploads.Hash(img.Bytes()) {
+			t.Errorf("hash = %s", item.Hash)
+		}
+		urls = append(urls, item.URL)
+	}
+	// 文件名由内容决定，与上传时的文件名无关
+	if urls[0] != urls[1] || !strings.HasPrefix(urls[0], uploads.URL(uploads.Hash(img.Bytes()))) {
+		t.Errorf("urls = %v", urls)
+	}
+}
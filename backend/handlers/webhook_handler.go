@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+	"workout-tracker/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	repo       *repository.FileRepository
+	dispatcher *webhooks.Dispatcher
+}
+
+func NewWebhookHandler(repo *repository.FileRepository, dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{repo: repo, dispatcher: dispatcher}
+}
+
+// 列表和详情中不返回签名密钥
+func hideSecret(webhook models.WebhookSubscription) models.WebhookSubscription {
+	webhook.Secret = ""
+	return webhook
+}
+
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhookList, err := h.repo.WithContext(c.Request.Context()).GetAllWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]models.WebhookSubscription, 0, len(webhookList))
+	for _, webhook := range webhookList {
+		result = append(result, hideSecret(webhook))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req webhooks.SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook := models.WebhookSubscription{
+		ID:        uuid.New().String(),
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    req.Secret,
+		Active:    req.Active == nil || *req.Active,
+		CreatedAt: time.Now(),
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := h.repo.WithContext(c.Request.Context()).SaveWebhook(webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 仅在创建时返回密钥
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, err := h.repo.WithContext(c.Request.Context()).GetWebhookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, hideSecret(*webhook))
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	webhook, err := repo.GetWebhookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req webhooks.SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if err := repo.SaveWebhook(*webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, hideSecret(*webhook))
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.repo.WithContext(c.Request.Context()).DeleteWebhook(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries 单个 Webhook 的投递日志
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.repo.WithContext(c.Request.Context()).GetWebhookByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	h.listDeliveries(c, func(delivery models.WebhookDelivery) bool {
+		return delivery.SubscriptionID == id
+	})
+}
+
+// GetDeliveries 所有投递记录，?status=dead 查看死信列表
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	status := c.Query("status")
+	h.listDeliveries(c, func(delivery models.WebhookDelivery) bool {
+		return status == "" || delivery.Status == status
+	})
+}
+
+func (h *WebhookHandler) listDeliveries(c *gin.Context, filter func(models.WebhookDelivery) bool) {
+	deliveries, err := h.repo.WithContext(c.Request.Context()).GetAllWebhookDeliveries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := []models.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		if filter(delivery) {
+			result = append(result, delivery)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	delivery, err := h.dispatcher.Retry(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+	"workout-tracker/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newWebhookRouter(t *testing.T) (*gin.Engine, *repository.FileRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewFileRepository(t.TempDir())
+	handler := NewWebhookHandler(repo, webhooks.NewDispatcher(repo))
+
+	router := gin.New()
+	router.GET("/api/webhooks/deliveries", handler.GetDeliveries)
+	router.POST("/api/webhooks/deliveries/:id/retry", handler.RetryDelivery)
+	router.GET("/api/webhooks/:id/deliveries", handler.GetWebhookDeliveries)
+	return router, repo
+}
+
+func TestWebhookDeliveryLog(t *testing.T) {
+	router, repo := newWebhookRouter(t)
+	for _, id := range []string{"a", "b"} {
+		if err := repo.SaveWebhook(models.WebhookSubscription{ID: id, URL: "http://example.com", Events: []string{"*"}, Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	err := repo.SaveWebhookDeliveries(
+		models.WebhookDelivery{ID: "a1", SubscriptionID: "a", Status: models.DeliverySucceeded, CreatedAt: now.Add(-2 * time.Minute)},
+		models.WebhookDelivery{ID: "a2", SubscriptionID: "a", Status: models.DeliveryDead, Attempts: 6, CreatedAt: now.Add(-time.Minute)},
+		models.WebhookDelivery{ID: "b1", SubscriptionID: "b", Status: models.DeliveryPending, CreatedAt: now},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		ids    []string
+	}{
+		{"subscription log newest first", http.MethodGet, "/api/webhooks/a/deliveries", http.StatusOK, []string{"a2", "a1"}},
+		{"unknown subscription", http.MethodGet, "/api/webhooks/missing/deliveries", http.StatusNotFound, nil},
+		{"all deliveries", http.MethodGet, "/api/webhooks/deliveries", http.StatusOK, []string{"b1", "a2", "a1"}},
+		{"dead letters", http.MethodGet, "/api/webhooks/deliveries?status=dead", http.StatusOK, []string{"a2"}},
+		{"retry pending delivery", http.MethodPost, "/api/webhooks/deliveries/b1/retry", http.StatusBadRequest, nil},
+		{"retry dead letter", http.MethodPost, "/api/webhooks/deliveries/a2/retry", http.StatusAccepted, nil},
+		{"dead letters after retry", http.MethodGet, "/api/webhooks/deliveries?status=dead", http.StatusOK, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.ids == nil {
+				return
+			}
+			var deliveries []models.WebhookDelivery
+			if err := json.Unmarshal(w.Body.Bytes(), &deliveries); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, delivery := range deliveries {
+				ids = append(ids, delivery.ID)
+			}
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("ids = %v, want %v", ids, tt.ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("ids = %v, want %v", ids, tt.ids)
+				}
+			}
+		})
+	}
+}
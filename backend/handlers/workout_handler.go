@@ -1,14 +1,22 @@
 package handlers
 
 import (
-	"io"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
 	"time"
+	"workout-tracker/calendar"
+	"workout-tracker/effort"
+	"workout-tracker/events"
+	"workout-tracker/logging"
 	"workout-tracker/models"
+	"workout-tracker/prescription"
 	"workout-tracker/presenter"
+	"workout-tracker/progression"
+	"workout-tracker/records"
 	"workout-tracker/repository"
+	"workout-tracker/taxonomy"
+	"workout-tracker/tracking"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,20 +25,20 @@ import (
 type WorkoutHandler struct {
 	repo      *repository.FileRepository
 	presenter *presenter.WorkoutPresenter
-	uploadDir string
+	bus       *events.Bus
 }
 
-func NewWorkoutHandler(repo *repository.FileRepository, presenter *presenter.WorkoutPresenter, uploadDir string) *WorkoutHandler {
+func NewWorkoutHandler(repo *repository.FileRepository, presenter *presenter.WorkoutPresenter, bus *events.Bus) *WorkoutHandler {
 	return &WorkoutHandler{
 		repo:      repo,
 		presenter: presenter,
-		uploadDir: uploadDir,
+		bus:       bus,
 	}
 }
 
 // Exercise handlers
 func (h *WorkoutHandler) GetExercises(c *gin.Context) {
-	exercises, err := h.repo.GetAllExercises()
+	exercises, err := h.repo.WithContext(c.Request.Context()).GetAllExercises()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -45,18 +53,24 @@ func (h *WorkoutHandler) CreateExercise(c *gin.Context) {
 		return
 	}
 
+	repo := h.repo.WithContext(c.Request.Context())
 	exercise.ID = uuid.New().String()
 	exercise.CreatedAt = time.Now()
+	if !resolveExercise(c, repo, &exercise) {
+		return
+	}
 
-	if err := h.repo.SaveExercise(exercise); err != nil {
+	if err := repo.SaveExercise(exercise); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.New(events.ExerciseCreated, "", exercise))
 
 	c.JSON(http.StatusCreated, exercise)
 }
 
 func (h *WorkoutHandler) UpdateExercise(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
 	id := c.Param("id")
 	var exercise models.Exercise
 	if err := c.ShouldBindJSON(&exercise); err != nil {
@@ -65,26 +79,123 @@ func (h *WorkoutHandler) UpdateExercise(c *gin.Context) {
 	}
 
 	exercise.ID = id
-	if err := h.repo.SaveExercise(exercise); err != nil {
+	// 保留创建时间，避免整体更新时被清零
+	if existing, err := repo.GetExerciseByID(id); err == nil {
+		exercise.CreatedAt = existing.CreatedAt
+	}
+	if !resolveExercise(c, repo, &exercise) {
+		return
+	}
+	if err := repo.SaveExercise(exercise); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.bus.Publish(events.New(events.ExerciseUpdated, "", exercise))
+
+	c.JSON(http.StatusOK, exercise)
+}
+
+func (h *WorkoutHandler) PatchExercise(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	id := c.Param("id")
+	existing, err := repo.GetExerciseByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exercise models.Exercise
+	if err := applyPatch(c, existing, &exercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 服务端管理的字段不允许修改
+	exercise.ID = existing.ID
+	exercise.CreatedAt = existing.CreatedAt
+	if !resolveExercise(c, repo, &exercise) {
+		return
+	}
+	if err := repo.SaveExercise(exercise); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.New(events.ExerciseUpdated, "", exercise))
 
 	c.JSON(http.StatusOK, exercise)
 }
 
 func (h *WorkoutHandler) DeleteExercise(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.repo.DeleteExercise(id); err != nil {
+	if err := h.repo.WithContext(c.Request.Context()).DeleteExercise(id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.New(events.ExerciseDeleted, "", gin.H{"id": id}))
 	c.JSON(http.StatusOK, gin.H{"message": "Exercise deleted successfully"})
 }
 
+// GetExerciseRecords 动作的个人记录，?formula=epley|brzycki|lombardi 选择 1RM 估算公式
+func (h *WorkoutHandler) GetExerciseRecords(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	id := c.Param("id")
+	formula := c.DefaultQuery("formula", records.DefaultFormula)
+	if !records.IsFormula(formula) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "formula must be one of " + strings.Join(records.Formulas, ", ")})
+		return
+	}
+	if _, err := repo.GetExerciseByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	found, err := repo.GetRecordsByExercise(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, records.Summarize(id, found, formula))
+}
+
+// 保存动作前检查引用的分类和记录方式并填写上传文件的信息，失败时已写入响应
+func resolveExercise(c *gin.Context, repo *repository.FileRepository, exercise *models.Exercise) bool {
+	if err := taxonomy.Resolve(repo, exercise); err != nil {
+		status := http.StatusInternalServerError
+		var unknown *taxonomy.UnknownTermError
+		if errors.As(err, &unknown) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := tracking.Validate(exercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := repo.ResolveExerciseMedia(exercise); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// 按动作的记录方式整理每组的目标，失败时已写入响应
+func normalizeWorkout(c *gin.Context, repo *repository.FileRepository, workout *models.Workout) bool {
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := prescription.NormalizeWorkout(workout, tracking.ByExercise(exercises)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
 // Workout handlers
 func (h *WorkoutHandler) GetWorkouts(c *gin.Context) {
-	workouts, err := h.repo.GetAllWorkouts()
+	workouts, err := h.repo.WithContext(c.Request.Context()).GetAllWorkouts()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -98,21 +209,49 @@ func (h *WorkoutHandler) CreateWorkout(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := calendar.ValidateSchedule(workout.Schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !normalizeWorkout(c, h.repo.WithContext(c.Request.Context()), &workout) {
+		return
+	}
 
 	workout.ID = uuid.New().String()
 	workout.CreatedAt = time.Now()
 
-	if err := h.repo.SaveWorkout(workout); err != nil {
+	if err := h.repo.WithContext(c.Request.Context()).SaveWorkout(workout); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.New(events.WorkoutCreated, "", workout))
 
 	c.JSON(http.StatusCreated, workout)
 }
 
+// GetRecommendations 预览按计划开始下一次训练时的渐进超负荷建议
+func (h *WorkoutHandler) GetRecommendations(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	workout, err := repo.GetWorkoutByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result := progression.Recommend(*workout, sessions)
+	if result == nil {
+		result = []models.Recommendation{}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *WorkoutHandler) GetWorkout(c *gin.Context) {
 	id := c.Param("id")
-	workout, err := h.repo.GetWorkoutByID(id)
+	workout, err := h.repo.WithContext(c.Request.Context()).GetWorkoutByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -121,28 +260,76 @@ func (h *WorkoutHandler) GetWorkout(c *gin.Context) {
 }
 
 func (h *WorkoutHandler) UpdateWorkout(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
 	id := c.Param("id")
 	var workout models.Workout
 	if err := c.ShouldBindJSON(&workout); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := calendar.ValidateSchedule(workout.Schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !normalizeWorkout(c, repo, &workout) {
+		return
+	}
 
 	workout.ID = id
-	if err := h.repo.SaveWorkout(workout); err != nil {
+	// 保留创建时间，避免整体更新时被清零
+	if existing, err := repo.GetWorkoutByID(id); err == nil {
+		workout.CreatedAt = existing.CreatedAt
+	}
+	if err := repo.SaveWorkout(workout); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.bus.Publish(events.New(events.WorkoutUpdated, "", workout))
+
+	c.JSON(http.StatusOK, workout)
+}
+
+func (h *WorkoutHandler) PatchWorkout(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	id := c.Param("id")
+	existing, err := repo.GetWorkoutByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var workout models.Workout
+	if err := applyPatch(c, existing, &workout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := calendar.ValidateSchedule(workout.Schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !normalizeWorkout(c, repo, &workout) {
+		return
+	}
+
+	// 服务端管理的字段不允许修改
+	workout.ID = existing.ID
+	workout.CreatedAt = existing.CreatedAt
+	if err := repo.SaveWorkout(workout); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.New(events.WorkoutUpdated, "", workout))
 
 	c.JSON(http.StatusOK, workout)
 }
 
 func (h *WorkoutHandler) DeleteWorkout(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.repo.DeleteWorkout(id); err != nil {
+	if err := h.repo.WithContext(c.Request.Context()).DeleteWorkout(id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.New(events.WorkoutDeleted, "", gin.H{"id": id}))
 	c.JSON(http.StatusOK, gin.H{"message": "Workout deleted successfully"})
 }
 
@@ -154,29 +341,40 @@ func (h *WorkoutHandler) CreateSession(c *gin.Context) {
 		return
 	}
 
+	if err := effort.ValidateSession(&session); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	session.ID = uuid.New().String()
 	session.Date = time.Now()
 	session.StartTime = time.Now()
 
-	if err := h.repo.SaveSession(session); err != nil {
+	repo := h.repo.WithContext(c.Request.Context())
+	session.Recommendations = h.recommend(c, repo, session.WorkoutID)
+	if err := repo.SaveSession(session); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.SessionChanged(nil, session)...)
+	h.detectRecords(c, repo, nil, session)
 
-	c.JSON(http.StatusCreated, session)
+	c.JSON(http.StatusCreated, h.sessionResponse(c, repo, session))
 }
 
 func (h *WorkoutHandler) GetSession(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
 	id := c.Param("id")
-	session, err := h.repo.GetSessionByID(id)
+	session, err := repo.GetSessionByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, session)
+	c.JSON(http.StatusOK, h.sessionResponse(c, repo, *session))
 }
 
 func (h *WorkoutHandler) UpdateSession(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
 	id := c.Param("id")
 	var session models.WorkoutSession
 	if err := c.ShouldBindJSON(&session); err != nil {
@@ -184,26 +382,112 @@ func (h *WorkoutHandler) UpdateSession(c *gin.Context) {
 		return
 	}
 
+	if err := effort.ValidateSession(&session); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	session.ID = id
-	if session.IsCompleted && session.EndTime.IsZero() {
-		session.EndTime = time.Now()
-		session.TotalTime = int(session.EndTime.Sub(session.StartTime).Seconds())
+	completeSession(&session)
+
+	existing, _ := repo.GetSessionByID(id)
+	if err := repo.SaveSession(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	h.bus.Publish(events.SessionChanged(existing, session)...)
+	h.detectRecords(c, repo, existing, session)
 
-	if err := h.repo.SaveSession(session); err != nil {
+	c.JSON(http.StatusOK, h.sessionResponse(c, repo, session))
+}
+
+func (h *WorkoutHandler) PatchSession(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	id := c.Param("id")
+	existing, err := repo.GetSessionByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var session models.WorkoutSession
+	if err := applyPatch(c, existing, &session); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := effort.ValidateSession(&session); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 服务端管理的字段不允许修改
+	session.ID = existing.ID
+	session.Date = existing.Date
+	completeSession(&session)
+
+	if err := repo.SaveSession(session); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.bus.Publish(events.SessionChanged(existing, session)...)
+	h.detectRecords(c, repo, existing, session)
+
+	c.JSON(http.StatusOK, h.sessionResponse(c, repo, session))
+}
 
-	c.JSON(http.StatusOK, session)
+// 训练刚完成时检测个人记录并发布事件，训练记录已经保存，失败时只记录日志
+func (h *WorkoutHandler) detectRecords(c *gin.Context, repo *repository.FileRepository, before *models.WorkoutSession, session models.WorkoutSession) {
+	found, err := records.Detect(repo, before, session)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("detect personal records failed", "session_id", session.ID, "error", err)
+		return
+	}
+	for _, record := range records.Visible(found, records.DefaultFormula) {
+		h.bus.Publish(events.New(events.RecordAchieved, session.ID, record))
+	}
+}
+
+// 按计划开始训练时给出下次训练的目标，没有计划或计划不存在时为空
+func (h *WorkoutHandler) recommend(c *gin.Context, repo *repository.FileRepository, workoutID string) []models.Recommendation {
+	if workoutID == "" {
+		return nil
+	}
+	workout, err := repo.GetWorkoutByID(workoutID)
+	if err != nil {
+		return nil
+	}
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("load sessions for recommendations failed", "workout_id", workoutID, "error", err)
+		return nil
+	}
+	return progression.Recommend(*workout, sessions)
+}
+
+// 训练记录和这次训练产生的个人记录
+func (h *WorkoutHandler) sessionResponse(c *gin.Context, repo *repository.FileRepository, session models.WorkoutSession) presenter.SessionResponse {
+	found, err := repo.GetRecordsBySession(session.ID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("load personal records failed", "session_id", session.ID, "error", err)
+	}
+	return presenter.SessionResponse{WorkoutSession: session, Records: records.Visible(found, records.DefaultFormula)}
+}
+
+// 训练标记完成时补全结束时间和总时长
+func completeSession(session *models.WorkoutSession) {
+	if session.IsCompleted && session.EndTime.IsZero() {
+		session.EndTime = time.Now()
+		session.TotalTime = int(session.EndTime.Sub(session.StartTime).Seconds())
+	}
 }
 
 func (h *WorkoutHandler) GetSessions(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
 	// 获取查询参数
 	startDate := c.Query("start")
 	endDate := c.Query("end")
 	
-	sessions, err := h.repo.GetAllSessions()
+	sessions, err := repo.GetAllSessions()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -214,7 +498,7 @@ func (h *WorkoutHandler) GetSessions(c *gin.Context) {
 		start, err1 := time.Parse("2006-01-02", startDate)
 		end, err2 := time.Parse("2006-01-02", endDate)
 		if err1 == nil && err2 == nil {
-			filteredSessions, err := h.repo.GetSessionsByDateRange(start, end.AddDate(0, 0, 1))
+			filteredSessions, err := repo.GetSessionsByDateRange(start, end.AddDate(0, 0, 1))
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
@@ -228,7 +512,7 @@ func (h *WorkoutHandler) GetSessions(c *gin.Context) {
 
 // Statistics handler
 func (h *WorkoutHandler) GetStatistics(c *gin.Context) {
-	sessions, err := h.repo.GetAllSessions()
+	sessions, err := h.repo.WithContext(c.Request.Context()).GetAllSessions()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -238,36 +522,46 @@ func (h *WorkoutHandler) GetStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// Upload handler
-func (h *WorkoutHandler) UploadFile(c *gin.Context) {
-	file, header, err := c.Request.FormFile("file")
+// GetRPEStatistics 每个动作的平均 RPE 走势，?exerciseId=&period=week|month&from=&to=
+func (h *WorkoutHandler) GetRPEStatistics(c *gin.Context) {
+	repo := h.repo.WithContext(c.Request.Context())
+	period := c.DefaultQuery("period", presenter.PeriodWeek)
+	if period != presenter.PeriodWeek && period != presenter.PeriodMonth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be week or month"})
+		return
+	}
+	from, err := parseDay(c.Query("from"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date, expected YYYY-MM-DD"})
 		return
 	}
-	defer file.Close()
-
-	// 生成唯一文件名
-	filename := uuid.New().String() + filepath.Ext(header.Filename)
-	filePath := filepath.Join(h.uploadDir, filename)
-
-	// 确保上传目录存在
-	os.MkdirAll(h.uploadDir, 0755)
-
-	// 保存文件
-	dst, err := os.Create(filePath)
+	to, err := parseDay(c.Query("to"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date, expected YYYY-MM-DD"})
 		return
 	}
-	defer dst.Close()
+	// to 包含当天
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
 
-	if _, err := io.Copy(dst, file); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	exerciseID := c.Query("exerciseId")
+	if exerciseID != "" {
+		if _, err := repo.GetExerciseByID(exerciseID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 返回文件URL
-	fileURL := "/uploads/" + filename
-	c.JSON(http.StatusOK, gin.H{"url": fileURL})
+	c.JSON(http.StatusOK, h.presenter.FormatRPETrends(sessions, exercises, exerciseID, period, from, to))
 }
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"workout-tracker/events"
+	"workout-tracker/models"
+	"workout-tracker/presenter"
+	"workout-tracker/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newWorkoutRouter(t *testing.T) (*gin.Engine, *repository.FileRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewFileRepository(t.TempDir())
+	handler := NewWorkoutHandler(repo, presenter.NewWorkoutPresenter(), events.NewBus())
+	router := gin.New()
+	router.POST("/api/exercises", handler.CreateExercise)
+	router.POST("/api/workouts", handler.CreateWorkout)
+	router.POST("/api/sessions", handler.CreateSession)
+	router.GET("/api/statistics/rpe", handler.GetRPEStatistics)
+	return router, repo
+}
+
+func postJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExerciseTrackingModes(t *testing.T) {
+	router, _ := newWorkoutRouter(t)
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		tracking string
+	}{
+		{"defaults to reps and weight", `{"name":"深蹲"}`, http.StatusCreated, models.TrackRepsWeight},
+		{"time based", `{"name":"平板支撑","tracking":"time","caloriesPerMinute":4}`, http.StatusCreated, models.TrackTime},
+		{"unknown mode", `{"name":"游泳","tracking":"laps"}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := postJSON(router, "/api/exercises", tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		var exercise models.Exercise
+		json.Unmarshal(w.Body.Bytes(), &exercise)
+		if exercise.Tracking != tt.tracking {
+			t.Errorf("%s: tracking = %q, want %q", tt.name, exercise.Tracking, tt.tracking)
+		}
+	}
+}
+
+func TestWorkoutTargetsFollowTrackingMode(t *testing.T) {
+	router, repo := newWorkoutRouter(t)
+	for _, exercise := range []models.Exercise{
+		{ID: "plank", Tracking: models.TrackTime},
+		{ID: "row", Tracking: models.TrackTimeDistance},
+		{ID: "squat"},
+	} {
+		if err := repo.SaveExercise(exercise); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 按动作的记录方式去掉不使用的字段
+	w := postJSON(router, "/api/workouts", `{"name":"A","exercises":[
+		{"exerciseId":"plank","targets":[{"reps":10,"weight":20,"duration":60}]},
+		{"exerciseId":"row","targets":[{"reps":10,"duration":300,"distance":1000}]},
+		{"exerciseId":"squat","targets":[{"reps":5,"weight":100,"duration":30}]}
+	]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var workout models.Workout
+	if err := json.Unmarshal(w.Body.Bytes(), &workout); err != nil {
+		t.Fatal(err)
+	}
+	want := []models.SetTarget{
+		{Type: models.SetWorking, Duration: 60},
+		{Type: models.SetWorking, Duration: 300, Distance: 1000},
+		{Type: models.SetWorking, Reps: 5, Weight: 100, Unit: models.UnitKg},
+	}
+	for i, set := range workout.Exercises {
+		if len(set.Targets) != 1 || set.Targets[0] != want[i] {
+			t.Errorf("%s targets = %+v, want %+v", set.ExerciseID, set.Targets, want[i])
+		}
+	}
+
+	w = postJSON(router, "/api/workouts", `{"name":"B","exercises":[{"exerciseId":"row","targets":[{"distance":-5}]}]}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "distance must not be negative") {
+		t.Errorf("negative distance: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCreateSessionValidatesEffort(t *testing.T) {
+	router, _ := newWorkoutRouter(t)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"rpe and rir", `{"exercises":[{"exerciseId":"squat","completedSets":2,"completedReps":[5,5],"setLogs":[{"rpe":8},{"rir":2}]}]}`, http.StatusCreated},
+		{"rpe out of range", `{"exercises":[{"exerciseId":"squat","completedSets":2,"completedReps":[5,5],"setLogs":[{"rpe":11}]}]}`, http.StatusBadRequest},
+		{"invalid tempo", `{"exercises":[{"exerciseId":"squat","completedSets":2,"completedReps":[5,5],"setLogs":[{"tempo":"3-1"}]}]}`, http.StatusBadRequest},
+		{"more logs than sets", `{"exercises":[{"exerciseId":"squat","completedSets":1,"completedReps":[5],"setLogs":[{"rpe":8},{"rpe":9}]}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := postJSON(router, "/api/sessions", tt.body); w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+}
+
+func TestGetRPEStatistics(t *testing.T) {
+	router, repo := newWorkoutRouter(t)
+	repo.SaveExercise(models.Exercise{ID: "squat", Name: "深蹲"})
+	repo.SaveSession(models.WorkoutSession{
+		ID:          "s1",
+		Date:        time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC),
+		IsCompleted: true,
+		Exercises:   []models.CompletedExercise{{ExerciseID: "squat", SetLogs: []models.SetLog{{RPE: 7}, {RPE: 8}}}},
+	})
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		trends int
+	}{
+		{"all exercises", "", http.StatusOK, 1},
+		{"single exercise by month", "?exerciseId=squat&period=month", http.StatusOK, 1},
+		{"to is inclusive", "?from=2026-03-01&to=2026-03-02", http.StatusOK, 1},
+		{"outside range", "?from=2026-03-03", http.StatusOK, 0},
+		{"unknown period", "?period=year", http.StatusBadRequest, 0},
+		{"invalid from", "?from=03/01/2026", http.StatusBadRequest, 0},
+		{"invalid to", "?to=tomorrow", http.StatusBadRequest, 0},
+		{"unknown exercise", "?exerciseId=missing", http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/statistics/rpe"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var trends []presenter.RPETrend
+		json.Unmarshal(w.Body.Bytes(), &trends)
+		if len(trends) != tt.trends {
+			t.Errorf("%s: trends = %+v", tt.name, trends)
+		}
+	}
+}
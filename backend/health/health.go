@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 单项检查的超时时间
+const checkTimeout = 2 * time.Second
+
+// 就绪状态
+const (
+	StatusOK           = "ok"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check 一项就绪检查，返回 nil 表示通过
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Report 就绪检查结果
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"` // 检查名 -> ok 或错误信息
+}
+
+// Checker 执行就绪检查，服务关闭时直接返回未就绪
+type Checker struct {
+	mu           sync.Mutex
+	checks       []Check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// Add 注册一项检查，例如对象存储的连通性
+func (c *Checker) Add(check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check)
+}
+
+// SetShuttingDown 标记服务正在关闭，之后的就绪检查都会失败
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready 并发执行所有检查
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]Check(nil), c.checks...)
+	c.mu.Unlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(checks))}
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+		return report
+	}
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			results[i] = check.Run(checkCtx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for i, check := range checks {
+		if results[i] != nil {
+			report.Status = StatusUnavailable
+			report.Checks[check.Name] = results[i].Error()
+			continue
+		}
+		report.Checks[check.Name] = StatusOK
+	}
+	return report
+}
+
+// Writable 检查目录可以创建文件，目录不存在时与写入逻辑一样先创建
+func Writable(name, dir string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		file, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return err
+		}
+		file.Close()
+		return os.Remove(file.Name())
+	}}
+}
+
+// JSONFiles 检查目录下的 JSON 数据文件都能解析
+func JSONFiles(name, dir string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			// 空文件按空数据处理，与仓库的读取逻辑一致
+			if len(data) > 0 && !json.Valid(data) {
+				errs = append(errs, fmt.Errorf("%s: invalid JSON", filepath.Base(file)))
+			}
+		}
+		return errors.Join(errs...)
+	}}
+}
@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func check(name string, err error) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error { return err }}
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name         string
+		checks       []Check
+		shuttingDown bool
+		status       string
+		results      map[string]string
+	}{
+		{"no checks", nil, false, StatusOK, map[string]string{}},
+		{"all pass", []Check{check("data", nil), check("storage", nil)}, false, StatusOK,
+			map[string]string{"data": StatusOK, "storage": StatusOK}},
+		{"one fails", []Check{check("data", nil), check("storage", errors.New("bucket unreachable"))}, false, StatusUnavailable,
+			map[string]string{"data": StatusOK, "storage": "bucket unreachable"}},
+		{"shutting down skips checks", []Check{check("data", nil)}, true, StatusShuttingDown, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(tt.checks...)
+			if tt.shuttingDown {
+				checker.SetShuttingDown()
+			}
+			report := checker.Ready(context.Background())
+			if report.Status != tt.status {
+				t.Errorf("status = %s, want %s", report.Status, tt.status)
+			}
+			if len(report.Checks) != len(tt.results) {
+				t.Fatalf("checks = %v, want %v", report.Checks, tt.results)
+			}
+			for name, want := range tt.results {
+				if report.Checks[name] != want {
+					t.Errorf("%s = %q, want %q", name, report.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyTimesOutSlowChecks(t *testing.T) {
+	checker := NewChecker()
+	checker.Add(Check{Name: "hangs", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := checker.Ready(ctx)
+	if report.Status != StatusUnavailable || report.Checks["hangs"] != context.Canceled.Error() {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestWritable(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		ok   bool
+	}{
+		{"existing directory", dir, true},
+		{"missing directory is created", filepath.Join(dir, "data"), true},
+		{"path is a file", file, false},
+	}
+	for _, tt := range tests {
+		err := Writable("data", tt.dir).Run(context.Background())
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+		if tt.ok {
+			// 检查用的临时文件要删除
+			if leftover, _ := filepath.Glob(filepath.Join(tt.dir, ".readyz-*")); len(leftover) > 0 {
+				t.Errorf("%s: left %v", tt.name, leftover)
+			}
+		}
+	}
+}
+
+func TestJSONFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		bad   []string // 错误信息中应包含的文件
+	}{
+		{"valid and empty files", map[string]string{"a.json": `[{"id":"1"}]`, "b.json": "", "notes.txt": "{"}, nil},
+		{"invalid files are listed", map[string]string{"a.json": `[]`, "b.json": `[{"id":`, "c.json": `nope`}, []string{"b.json", "c.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := JSONFiles("data", dir).Run(context.Background())
+			if len(tt.bad) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, name := range tt.bad {
+				if !strings.Contains(err.Error(), name) {
+					t.Errorf("error %q does not mention %s", err, name)
+				}
+			}
+			if strings.Contains(err.Error(), "a.json") {
+				t.Errorf("error %q mentions a valid file", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,560 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"workout-tracker/effort"
+	"workout-tracker/models"
+	"workout-tracker/prescription"
+	"workout-tracker/records"
+	"workout-tracker/repository"
+	"workout-tracker/taxonomy"
+	"workout-tracker/tracking"
+
+	"github.com/google/uuid"
+)
+
+// 待确认的导入保留7天
+const importRetention = 7 * 24 * time.Hour
+
+// 预览中最多展示的训练记录数
+const previewSessions = 20
+
+// 根据来源、开始时间和名称生成固定的训练记录ID，重复导入同一文件时跳过已有记录
+var sessionNamespace = uuid.MustParse("6f1d7c2e-3b8a-4f5e-9c1d-2a7b8e4f6c3d")
+
+// Importer 导入其他应用的训练记录，先预览、确认动作映射后再写入
+type Importer struct {
+	repo *repository.FileRepository
+	mu   sync.Mutex
+}
+
+func NewImporter(repo *repository.FileRepository) *Importer {
+	return &Importer{repo: repo}
+}
+
+// ExerciseMapping 导出文件中的一个动作及其匹配结果
+type ExerciseMapping struct {
+	Name       string      `json:"name"`
+	Sets       int         `json:"sets"`
+	Match      *Candidate  `json:"match"` // 自动匹配的动作，为空时需要确认
+	Candidates []Candidate `json:"candidates"`
+}
+
+// SessionPreview 将要创建的训练记录
+type SessionPreview struct {
+	Date        time.Time `json:"date"`
+	WorkoutName string    `json:"workoutName"`
+	Exercises   int       `json:"exercises"`
+	Sets        int       `json:"sets"`
+	Duplicate   bool      `json:"duplicate"` // 已经导入过，确认时会跳过
+}
+
+// Preview 导入预览
+type Preview struct {
+	ID          string            `json:"id"`
+	Source      string            `json:"source"`
+	FileName    string            `json:"fileName"`
+	Status      string            `json:"status"`
+	SessionsNum int               `json:"sessions"`
+	Duplicates  int               `json:"duplicates"`
+	SetsNum     int               `json:"sets"`
+	SkippedRows int               `json:"skippedRows"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	Exercises   []ExerciseMapping `json:"exercises"`
+	Sessions    []SessionPreview  `json:"sessionsPreview"` // 最近的若干条
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+// MappingChoice 用户对一个动作的确认：映射到已有动作、新建或跳过
+type MappingChoice struct {
+	ExerciseID string `json:"exerciseId,omitempty"`
+	Create     bool   `json:"create,omitempty"`
+	BodyPart   string `json:"bodyPart,omitempty"` // 新建动作时使用
+	Skip       bool   `json:"skip,omitempty"`
+}
+
+// Confirmation 确认导入的请求，未列出的动作使用自动匹配结果
+type Confirmation struct {
+	Mappings map[string]MappingChoice `json:"mappings"`
+}
+
+// CommitResult 导入结果
+type CommitResult struct {
+	SessionsCreated  int `json:"sessionsCreated"`
+	SessionsSkipped  int `json:"sessionsSkipped"`
+	WorkoutsCreated  int `json:"workoutsCreated"`
+	ExercisesCreated int `json:"exercisesCreated"`
+	SetsImported     int `json:"setsImported"`
+	SetsSkipped      int `json:"setsSkipped"`
+}
+
+var (
+	ErrImportNotFound  = errors.New("import not found")
+	ErrImportCommitted = errors.New("import already committed")
+)
+
+// UnresolvedError 存在未确认映射的动作
+type UnresolvedError struct {
+	Names []string
+}
+
+func (e *UnresolvedError) Error() string {
+	return "exercises need a mapping: " + strings.Join(e.Names, ", ")
+}
+
+// Create 解析导出文件并保存为待确认的导入
+func (s *Importer) Create(ctx context.Context, r io.Reader, fileName, source, weightUnit string) (*Preview, error) {
+	parsed, err := Parse(r, source, weightUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := s.repo.WithContext(ctx)
+	if _, err := repo.DeleteHistoryImportsBefore(time.Now().Add(-importRetention)); err != nil {
+		return nil, err
+	}
+
+	item := models.HistoryImport{
+		ID:          uuid.New().String(),
+		Source:      parsed.Source,
+		FileName:    fileName,
+		Status:      models.HistoryImportPending,
+		Rows:        parsed.Rows,
+		SkippedRows: parsed.Skipped,
+		CreatedAt:   time.Now(),
+	}
+	if err := repo.SaveHistoryImport(item); err != nil {
+		return nil, err
+	}
+	return preview(repo, &item)
+}
+
+// Preview 重新生成导入预览
+func (s *Importer) Preview(ctx context.Context, id string) (*Preview, error) {
+	repo := s.repo.WithContext(ctx)
+	item, err := repo.GetHistoryImportByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return preview(repo, item)
+}
+
+// Discard 放弃导入
+func (s *Importer) Discard(ctx context.Context, id string) error {
+	return s.repo.WithContext(ctx).DeleteHistoryImport(id)
+}
+
+func preview(repo *repository.FileRepository, item *models.HistoryImport) (*Preview, error) {
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := repo.GetExerciseAliases()
+	if err != nil {
+		return nil, err
+	}
+	existingSessions, err := sessionIDs(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{
+		ID:          item.ID,
+		Source:      item.Source,
+		FileName:    item.FileName,
+		Status:      item.Status,
+		SetsNum:     len(item.Rows),
+		SkippedRows: item.SkippedRows,
+		Exercises:   []ExerciseMapping{},
+		Sessions:    []SessionPreview{},
+		CreatedAt:   item.CreatedAt,
+	}
+
+	setCounts := make(map[string]int)
+	var names []string
+	for _, row := range item.Rows {
+		if setCounts[row.Exercise] == 0 {
+			names = append(names, row.Exercise)
+		}
+		setCounts[row.Exercise]++
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mapping := ExerciseMapping{Name: name, Sets: setCounts[name]}
+		mapping.Candidates = Candidates(name, exercises, aliases, 5)
+		if len(mapping.Candidates) > 0 && mapping.Candidates[0].Score >= autoMatchScore {
+			mapping.Match = &mapping.Candidates[0]
+		}
+		preview.Exercises = append(preview.Exercises, mapping)
+	}
+
+	groups := groupSessions(item)
+	preview.SessionsNum = len(groups)
+	for i, group := range groups {
+		if i == 0 || group.start.Before(preview.From) {
+			preview.From = group.start
+		}
+		if group.start.After(preview.To) {
+			preview.To = group.start
+		}
+		duplicate := existingSessions[group.id]
+		if duplicate {
+			preview.Duplicates++
+		}
+		if len(preview.Sessions) < previewSessions {
+			exerciseNames := make(map[string]bool)
+			for _, row := range group.rows {
+				exerciseNames[row.Exercise] = true
+			}
+			preview.Sessions = append(preview.Sessions, SessionPreview{
+				Date:        group.start,
+				WorkoutName: group.name,
+				Exercises:   len(exerciseNames),
+				Sets:        len(group.rows),
+				Duplicate:   duplicate,
+			})
+		}
+	}
+	return preview, nil
+}
+
+// 同一次训练的所有组
+type sessionGroup struct {
+	id    string
+	name  string
+	start time.Time
+	end   time.Time
+	notes string
+	rows  []models.HistoryImportRow
+}
+
+// 按开始时间和训练名称分组，最近的在前
+func groupSessions(item *models.HistoryImport) []*sessionGroup {
+	byKey := make(map[string]*sessionGroup)
+	var groups []*sessionGroup
+	for _, row := range item.Rows {
+		key := item.Source + "|" + row.Start.Format(time.RFC3339) + "|" + row.WorkoutName
+		group, ok := byKey[key]
+		if !ok {
+			group = &sessionGroup{
+				id:    uuid.NewSHA1(sessionNamespace, []byte(key)).String(),
+				name:  row.WorkoutName,
+				start: row.Start,
+				end:   row.End,
+				notes: row.WorkoutNotes,
+			}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.rows = append(group.rows, row)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].start.After(groups[j].start)
+	})
+	return groups
+}
+
+func sessionIDs(repo *repository.FileRepository) (map[string]bool, error) {
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool)
+	for _, session := range sessions {
+		ids[session.ID] = true
+	}
+	return ids, nil
+}
+
+// Commit 按确认的动作映射写入训练记录
+func (s *Importer) Commit(ctx context.Context, id string, confirmation Confirmation) (*CommitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repo.WithContext(ctx)
+	item, err := repo.GetHistoryImportByID(id)
+	if err != nil {
+		return nil, ErrImportNotFound
+	}
+	if item.Status != models.HistoryImportPending {
+		return nil, ErrImportCommitted
+	}
+
+	preview, err := preview(repo, item)
+	if err != nil {
+		return nil, err
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return nil, err
+	}
+	exerciseByID := make(map[string]models.Exercise)
+	for _, exercise := range exercises {
+		exerciseByID[exercise.ID] = exercise
+	}
+
+	// 解析每个外部动作对应的动作ID，空字符串表示跳过
+	result := &CommitResult{}
+	resolved := make(map[string]string)
+	aliases := make(map[string]string)
+	var newExercises []models.Exercise
+	var unresolved []string
+	for _, mapping := range preview.Exercises {
+		choice, chosen := confirmation.Mappings[mapping.Name]
+		switch {
+		case chosen && choice.Skip:
+			resolved[mapping.Name] = ""
+		case chosen && choice.ExerciseID != "":
+			if _, ok := exerciseByID[choice.ExerciseID]; !ok {
+				return nil, fmt.Errorf("exercise %s not found", choice.ExerciseID)
+			}
+			resolved[mapping.Name] = choice.ExerciseID
+			aliases[aliasKey(mapping.Name)] = choice.ExerciseID
+		case chosen && choice.Create:
+			exercise := models.Exercise{
+				ID:        uuid.New().String(),
+				Name:      mapping.Name,
+				BodyPart:  choice.BodyPart,
+				Tracking:  inferTracking(item.Rows, mapping.Name),
+				CreatedAt: time.Now(),
+			}
+			if err := taxonomy.Resolve(repo, &exercise); err != nil {
+				return nil, err
+			}
+			newExercises = append(newExercises, exercise)
+			exerciseByID[exercise.ID] = exercise
+			resolved[mapping.Name] = exercise.ID
+			aliases[aliasKey(mapping.Name)] = exercise.ID
+		case mapping.Match != nil:
+			resolved[mapping.Name] = mapping.Match.ExerciseID
+		default:
+			unresolved = append(unresolved, mapping.Name)
+		}
+	}
+	if len(unresolved) > 0 {
+		return nil, &UnresolvedError{Names: unresolved}
+	}
+	result.ExercisesCreated = len(newExercises)
+
+	workouts, err := repo.GetAllWorkouts()
+	if err != nil {
+		return nil, err
+	}
+	workoutByName := make(map[string]string)
+	for _, workout := range workouts {
+		workoutByName[workout.Name] = workout.ID
+	}
+
+	existingSessions, err := sessionIDs(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var newWorkouts []models.Workout
+	var sessions []models.WorkoutSession
+	groups := groupSessions(item)
+	for _, group := range groups {
+		if existingSessions[group.id] {
+			result.SessionsSkipped++
+			continue
+		}
+
+		session := buildSession(group, resolved, exerciseByID, result)
+		if len(session.Exercises) == 0 {
+			result.SessionsSkipped++
+			continue
+		}
+
+		// 同名训练计划复用，否则以最近一次训练为模板新建
+		workoutID, ok := workoutByName[group.name]
+		if !ok {
+			workout := buildWorkout(group, session, resolved, exerciseByID)
+			newWorkouts = append(newWorkouts, workout)
+			workoutByName[group.name] = workout.ID
+			workoutID = workout.ID
+		}
+		session.WorkoutID = workoutID
+		sessions = append(sessions, session)
+	}
+	result.SessionsCreated = len(sessions)
+	result.WorkoutsCreated = len(newWorkouts)
+
+	if err := repo.ImportData(newExercises, newWorkouts, sessions, false); err != nil {
+		return nil, err
+	}
+	// 导入的训练可能早于已有的训练，重新计算个人记录
+	if _, err := records.Rebuild(repo); err != nil {
+		return nil, err
+	}
+	if len(aliases) > 0 {
+		if err := repo.SaveExerciseAliases(aliases); err != nil {
+			return nil, err
+		}
+	}
+
+	// 已导入的原始数据不再需要
+	now := time.Now()
+	item.Status = models.HistoryImportCommitted
+	item.CommittedAt = &now
+	item.Rows = nil
+	if err := repo.SaveHistoryImport(*item); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func buildSession(group *sessionGroup, resolved map[string]string, exerciseByID map[string]models.Exercise, result *CommitResult) models.WorkoutSession {
+	session := models.WorkoutSession{
+		ID:          group.id,
+		Date:        group.start,
+		StartTime:   group.start,
+		EndTime:     group.end,
+		TotalTime:   int(group.end.Sub(group.start).Seconds()),
+		Notes:       group.notes,
+		IsCompleted: true,
+		Exercises:   []models.CompletedExercise{},
+	}
+
+	index := make(map[string]int)
+	for _, row := range group.rows {
+		exerciseID := resolved[row.Exercise]
+		if exerciseID == "" {
+			result.SetsSkipped++
+			continue
+		}
+
+		i, ok := index[exerciseID]
+		if !ok {
+			i = len(session.Exercises)
+			index[exerciseID] = i
+			session.Exercises = append(session.Exercises, models.CompletedExercise{
+				ExerciseID:      exerciseID,
+				CompletedReps:   []int{},
+				ActualRestTimes: []int{},
+				IsCompleted:     true,
+			})
+		}
+
+		exercise := exerciseByID[exerciseID]
+		fields := tracking.FieldsOf(tracking.Mode(exercise))
+		completed := &session.Exercises[i]
+		completed.CompletedSets++
+		completed.CompletedReps = append(completed.CompletedReps, row.Reps)
+		completed.ActualRestTimes = append(completed.ActualRestTimes, 0)
+		if fields.Weight {
+			completed.CompletedWeights = append(completed.CompletedWeights, math.Round(row.Weight*100)/100)
+		}
+		if fields.Duration {
+			completed.CompletedDurations = append(completed.CompletedDurations, row.Seconds)
+		}
+		if fields.Distance {
+			completed.CompletedDistances = append(completed.CompletedDistances, row.Distance)
+		}
+		completed.SetLogs = append(completed.SetLogs, setLog(row))
+		completed.CaloriesBurned += tracking.Calories(exercise, row.Reps, row.Seconds)
+		result.SetsImported++
+	}
+
+	for i := range session.Exercises {
+		session.TotalCalories += session.Exercises[i].CaloriesBurned
+		if !logged(session.Exercises[i].SetLogs) {
+			session.Exercises[i].SetLogs = nil
+		}
+	}
+	return session
+}
+
+// 导入一组的 RPE 和备注，超出范围的 RPE 丢弃
+func setLog(row models.HistoryImportRow) models.SetLog {
+	log := models.SetLog{Note: strings.TrimSpace(row.Notes)}
+	if effort.ValidateRPE(row.RPE) == nil {
+		log.RPE = row.RPE
+	}
+	return log
+}
+
+// 是否有任何一组带有主观记录
+func logged(logs []models.SetLog) bool {
+	for _, log := range logs {
+		if !effort.Empty(log) {
+			return true
+		}
+	}
+	return false
+}
+
+// 根据一次训练生成训练计划：每组的次数、重量（公斤）、时长和距离作为目标
+func buildWorkout(group *sessionGroup, session models.WorkoutSession, resolved map[string]string, exerciseByID map[string]models.Exercise) models.Workout {
+	workout := models.Workout{
+		ID:        uuid.New().String(),
+		Name:      group.name,
+		Exercises: []models.ExerciseSet{},
+		CreatedAt: time.Now(),
+	}
+
+	targets := make(map[string][]models.SetTarget)
+	for _, row := range group.rows {
+		exerciseID := resolved[row.Exercise]
+		if exerciseID == "" {
+			continue
+		}
+		setType := row.SetType
+		if setType == "" {
+			// 之前保存的待确认导入没有组类型
+			setType = models.SetWorking
+		}
+		targets[exerciseID] = append(targets[exerciseID], models.SetTarget{
+			Type:     setType,
+			Reps:     row.Reps,
+			Weight:   math.Round(row.Weight*100) / 100,
+			Unit:     models.UnitKg,
+			Duration: row.Seconds,
+			Distance: row.Distance,
+		})
+	}
+
+	for _, completed := range session.Exercises {
+		set := models.ExerciseSet{ExerciseID: completed.ExerciseID, Targets: targets[completed.ExerciseID]}
+		// 导入的数据已经校验过，这里只生成摘要
+		prescription.Normalize(&set, tracking.Mode(exerciseByID[completed.ExerciseID]))
+		workout.Exercises = append(workout.Exercises, set)
+	}
+	return workout
+}
+
+// 根据导入数据中的字段推断新建动作的记录方式
+func inferTracking(rows []models.HistoryImportRow, name string) string {
+	var reps, weight, seconds, distance bool
+	for _, row := range rows {
+		if row.Exercise != name {
+			continue
+		}
+		reps = reps || row.Reps > 0
+		weight = weight || row.Weight > 0
+		seconds = seconds || row.Seconds > 0
+		distance = distance || row.Distance > 0
+	}
+	switch {
+	case reps && !weight:
+		return models.TrackReps
+	case reps:
+		return models.TrackRepsWeight
+	case seconds && distance:
+		return models.TrackTimeDistance
+	case seconds:
+		return models.TrackTime
+	case distance:
+		return models.TrackDistance
+	}
+	return models.TrackRepsWeight
+}
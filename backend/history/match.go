@@ -0,0 +1,112 @@
+package history
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"workout-tracker/models"
+)
+
+// 相似度达到该值时自动匹配，否则需要用户确认
+const autoMatchScore = 0.8
+
+// Candidate 候选的动作
+type Candidate struct {
+	ExerciseID string  `json:"exerciseId"`
+	Name       string  `json:"name"`
+	Score      float64 `json:"score"` // 0-1，1 表示完全一致或已确认过的映射
+}
+
+// 统一大小写，去掉标点，括号内的器械说明作为普通词
+func normalizeName(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// 取词集合相似度与编辑距离相似度中较高的一个
+func similarity(a, b string) float64 {
+	left, right := normalizeName(a), normalizeName(b)
+	if len(left) == 0 || len(right) == 0 {
+		return 0
+	}
+
+	joinedLeft, joinedRight := strings.Join(left, " "), strings.Join(right, " ")
+	if joinedLeft == joinedRight {
+		return 1
+	}
+
+	words := make(map[string]bool)
+	for _, word := range left {
+		words[word] = true
+	}
+	common := 0
+	for _, word := range right {
+		if words[word] {
+			common++
+			words[word] = false
+		}
+	}
+	dice := 2 * float64(common) / float64(len(left)+len(right))
+
+	l, r := []rune(joinedLeft), []rune(joinedRight)
+	longest := len(l)
+	if len(r) > longest {
+		longest = len(r)
+	}
+	edit := 1 - float64(levenshtein(l, r))/float64(longest)
+
+	if dice > edit {
+		return dice
+	}
+	return edit
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// Candidates 按相似度返回最多 limit 个候选动作，已确认过的映射排在最前
+func Candidates(name string, exercises []models.Exercise, aliases map[string]string, limit int) []Candidate {
+	aliasID := aliases[aliasKey(name)]
+
+	result := []Candidate{}
+	for _, exercise := range exercises {
+		score := similarity(name, exercise.Name)
+		if exercise.ID == aliasID {
+			score = 1
+		}
+		if score > 0.3 {
+			result = append(result, Candidate{ExerciseID: exercise.ID, Name: exercise.Name, Score: score})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Score > result[j].Score
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// 保存映射时使用的外部动作名称
+func aliasKey(name string) string {
+	return strings.Join(normalizeName(name), " ")
+}
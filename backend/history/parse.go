@@ -0,0 +1,299 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"workout-tracker/models"
+)
+
+// 支持导入的应用
+const (
+	SourceAuto   = "auto"
+	SourceStrong = "strong"
+	SourceHevy   = "hevy"
+)
+
+// 重量单位
+const (
+	UnitKg = "kg"
+	UnitLb = "lb"
+)
+
+const kgPerLb = 0.45359237
+
+// ParseResult 解析后的导出文件
+type ParseResult struct {
+	Source  string
+	Rows    []models.HistoryImportRow
+	Skipped int
+}
+
+// Parse 解析 Strong 或 Hevy 导出的 CSV
+// source 为 auto 时根据表头识别；weightUnit 用于没有单位信息的 Strong 导出
+func Parse(r io.Reader, source, weightUnit string) (*ParseResult, error) {
+	if weightUnit == "" {
+		weightUnit = UnitKg
+	}
+	if weightUnit != UnitKg && weightUnit != UnitLb {
+		return nil, fmt.Errorf("weightUnit must be kg or lb")
+	}
+
+	br := bufio.NewReader(r)
+	firstLine, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = detectDelimiter(firstLine)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\uFEFF")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if source == "" || source == SourceAuto {
+		source = detectSource(columns)
+		if source == "" {
+			return nil, fmt.Errorf("unrecognized export format")
+		}
+	}
+
+	var parseRow func(get func(string) string) (*models.HistoryImportRow, error)
+	switch source {
+	case SourceStrong:
+		if err := requireColumns(columns, "date", "workout name", "exercise name", "set order"); err != nil {
+			return nil, err
+		}
+		parseRow = func(get func(string) string) (*models.HistoryImportRow, error) {
+			return parseStrongRow(get, weightUnit)
+		}
+	case SourceHevy:
+		if err := requireColumns(columns, "title", "start_time", "exercise_title"); err != nil {
+			return nil, err
+		}
+		parseRow = parseHevyRow
+	default:
+		return nil, fmt.Errorf("unknown source %q", source)
+	}
+
+	result := &ParseResult{Source: source}
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		get := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		row, err := parseRow(get)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if row == nil {
+			result.Skipped++
+			continue
+		}
+		result.Rows = append(result.Rows, *row)
+	}
+
+	if len(result.Rows) == 0 {
+		return nil, fmt.Errorf("no sets found in file")
+	}
+	return result, nil
+}
+
+// 旧版 Strong 导出使用分号分隔
+func detectDelimiter(sample []byte) rune {
+	if i := bytes.IndexByte(sample, '\n'); i >= 0 {
+		sample = sample[:i]
+	}
+	if bytes.Count(sample, []byte(";")) > bytes.Count(sample, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func detectSource(columns map[string]int) string {
+	if _, ok := columns["exercise_title"]; ok {
+		return SourceHevy
+	}
+	if _, ok := columns["exercise name"]; ok {
+		return SourceStrong
+	}
+	return ""
+}
+
+func requireColumns(columns map[string]int, names ...string) error {
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("missing column %q", name)
+		}
+	}
+	return nil
+}
+
+// Strong 的 Set Order 中正式组按数字编号，热身组、递减组和力竭组分别记为 W、D、F，不编号
+var strongSetTypes = map[string]string{
+	"W": models.SetWarmup,
+	"D": models.SetDrop,
+	"F": models.SetAMRAP,
+}
+
+// Strong: Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE
+func parseStrongRow(get func(string) string, weightUnit string) (*models.HistoryImportRow, error) {
+	setType := models.SetWorking
+	setIndex, err := strconv.Atoi(get("set order"))
+	if err != nil {
+		var ok bool
+		if setType, ok = strongSetTypes[strings.ToUpper(get("set order"))]; !ok {
+			// 计时器等非训练组的行
+			return nil, nil
+		}
+	}
+
+	start, err := time.ParseInLocation("2006-01-02 15:04:05", get("date"), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", get("date"))
+	}
+
+	weight := parseFloat(get("weight"))
+	if weightUnit == UnitLb {
+		weight *= kgPerLb
+	}
+
+	row := &models.HistoryImportRow{
+		WorkoutName:  get("workout name"),
+		Start:        start,
+		End:          start.Add(parseStrongDuration(get("duration"))),
+		Exercise:     get("exercise name"),
+		SetIndex:     setIndex,
+		SetType:      setType,
+		Weight:       weight,
+		Reps:         int(parseFloat(get("reps"))),
+		Seconds:      int(parseFloat(get("seconds"))),
+		Distance:     parseFloat(get("distance")) * 1000, // Strong 以公里导出
+		RPE:          parseFloat(get("rpe")),
+		Notes:        get("notes"),
+		WorkoutNotes: get("workout notes"),
+	}
+	if row.Exercise == "" {
+		return nil, fmt.Errorf("missing exercise name")
+	}
+	return row, nil
+}
+
+var strongDurationPattern = regexp.MustCompile(`(\d+)\s*([hms])`)
+
+// 形如 "1h 5m"、"45m"
+func parseStrongDuration(value string) time.Duration {
+	var d time.Duration
+	for _, match := range strongDurationPattern.FindAllStringSubmatch(value, -1) {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			d += time.Duration(n) * time.Hour
+		case "m":
+			d += time.Duration(n) * time.Minute
+		case "s":
+			d += time.Duration(n) * time.Second
+		}
+	}
+	return d
+}
+
+var hevyTimeLayouts = []string{
+	"2 Jan 2006, 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+func parseHevyTime(value string) (time.Time, error) {
+	for _, layout := range hevyTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// Hevy 的 set_type 为 normal、warmup、dropset 或 failure，其他值按正式组导入
+var hevySetTypes = map[string]string{
+	"warmup":  models.SetWarmup,
+	"dropset": models.SetDrop,
+	"failure": models.SetAMRAP,
+}
+
+// Hevy: title,start_time,end_time,description,exercise_title,superset_id,exercise_notes,set_index,set_type,weight_kg,reps,distance_km,duration_seconds,rpe
+func parseHevyRow(get func(string) string) (*models.HistoryImportRow, error) {
+	setType := hevySetTypes[strings.ToLower(get("set_type"))]
+	if setType == "" {
+		setType = models.SetWorking
+	}
+
+	start, err := parseHevyTime(get("start_time"))
+	if err != nil {
+		return nil, err
+	}
+	end := start
+	if value := get("end_time"); value != "" {
+		if end, err = parseHevyTime(value); err != nil {
+			return nil, err
+		}
+	}
+
+	weight := parseFloat(get("weight_kg"))
+	if value := get("weight_lbs"); value != "" {
+		weight = parseFloat(value) * kgPerLb
+	}
+
+	row := &models.HistoryImportRow{
+		WorkoutName:  get("title"),
+		Start:        start,
+		End:          end,
+		Exercise:     get("exercise_title"),
+		SetIndex:     int(parseFloat(get("set_index"))) + 1, // Hevy 从0开始编号
+		SetType:      setType,
+		Weight:       weight,
+		Reps:         int(parseFloat(get("reps"))),
+		Seconds:      int(parseFloat(get("duration_seconds"))),
+		Distance:     parseFloat(get("distance_km")) * 1000,
+		RPE:          parseFloat(get("rpe")),
+		WorkoutNotes: get("description"),
+	}
+	if row.Exercise == "" {
+		return nil, fmt.Errorf("missing exercise title")
+	}
+	return row, nil
+}
+
+// 空值或无法解析时返回0，兼容逗号作为小数点的导出
+func parseFloat(value string) float64 {
+	value = strings.Replace(value, ",", ".", 1)
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
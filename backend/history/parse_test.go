@@ -0,0 +1,92 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"workout-tracker/models"
+)
+
+func TestParseSetTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		types   []string
+		indexes []int
+		skipped int
+	}{
+		{
+			name: "strong",
+			csv: "Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE\n" +
+				"2024-01-02 18:00:00,Push,1h,Bench Press,W,40,10,0,0,,,\n" +
+				"2024-01-02 18:00:00,Push,1h,Bench Press,1,80,5,0,0,,,\n" +
+				"2024-01-02 18:00:00,Push,1h,Bench Press,Rest Timer,0,0,0,90,,,\n" +
+				"2024-01-02 18:00:00,Push,1h,Bench Press,D,60,8,0,0,,,\n" +
+				"2024-01-02 18:00:00,Push,1h,Bench Press,F,70,6,0,0,,,\n",
+			types:   []string{models.SetWarmup, models.SetWorking, models.SetDrop, models.SetAMRAP},
+			indexes: []int{0, 1, 0, 0},
+			skipped: 1,
+		},
+		{
+			name: "hevy",
+			csv: "title,start_time,end_time,description,exercise_title,superset_id,exercise_notes,set_index,set_type,weight_kg,reps,distance_km,duration_seconds,rpe\n" +
+				"Push,\"2 Jan 2024, 18:00\",\"2 Jan 2024, 19:00\",,Bench Press,,,0,warmup,40,10,,,\n" +
+				"Push,\"2 Jan 2024, 18:00\",\"2 Jan 2024, 19:00\",,Bench Press,,,1,normal,80,5,,,\n" +
+				"Push,\"2 Jan 2024, 18:00\",\"2 Jan 2024, 19:00\",,Bench Press,,,2,dropset,60,8,,,\n" +
+				"Push,\"2 Jan 2024, 18:00\",\"2 Jan 2024, 19:00\",,Bench Press,,,3,failure,70,6,,,\n" +
+				"Push,\"2 Jan 2024, 18:00\",\"2 Jan 2024, 19:00\",,Bench Press,,,4,unknown,80,5,,,\n",
+			types:   []string{models.SetWarmup, models.SetWorking, models.SetDrop, models.SetAMRAP, models.SetWorking},
+			indexes: []int{1, 2, 3, 4, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(strings.NewReader(tt.csv), SourceAuto, UnitKg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Skipped != tt.skipped {
+				t.Errorf("skipped = %d, want %d", result.Skipped, tt.skipped)
+			}
+			if len(result.Rows) != len(tt.types) {
+				t.Fatalf("got %d rows, want %d", len(result.Rows), len(tt.types))
+			}
+			for i, row := range result.Rows {
+				if row.SetType != tt.types[i] || row.SetIndex != tt.indexes[i] {
+					t.Errorf("row %d: type %q index %d, want %q %d", i, row.SetType, row.SetIndex, tt.types[i], tt.indexes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildWorkoutKeepsSetTypes(t *testing.T) {
+	group := &sessionGroup{name: "Push", rows: []models.HistoryImportRow{
+		{Exercise: "Bench Press", SetType: models.SetWarmup, Reps: 10, Weight: 40},
+		{Exercise: "Bench Press", SetType: models.SetWorking, Reps: 5, Weight: 80},
+		{Exercise: "Bench Press", SetType: models.SetDrop, Reps: 8, Weight: 60},
+		{Exercise: "Bench Press", SetType: models.SetAMRAP, Reps: 6, Weight: 70},
+		// 之前保存的待确认导入没有组类型
+		{Exercise: "Bench Press", Reps: 5, Weight: 80},
+	}}
+	resolved := map[string]string{"Bench Press": "bench"}
+	session := models.WorkoutSession{Exercises: []models.CompletedExercise{{ExerciseID: "bench"}}}
+
+	workout := buildWorkout(group, session, resolved, map[string]models.Exercise{"bench": {ID: "bench"}})
+	if len(workout.Exercises) != 1 {
+		t.Fatalf("got %d exercises", len(workout.Exercises))
+	}
+	set := workout.Exercises[0]
+	want := []string{models.SetWarmup, models.SetWorking, models.SetDrop, models.SetAMRAP, models.SetWorking}
+	if len(set.Targets) != len(want) {
+		t.Fatalf("got %d targets, want %d", len(set.Targets), len(want))
+	}
+	for i, target := range set.Targets {
+		if target.Type != want[i] {
+			t.Errorf("target %d type = %q, want %q", i, target.Type, want[i])
+		}
+	}
+	// 摘要取第一个非热身组
+	if set.Reps != 5 || set.Weight != 80 {
+		t.Errorf("summary reps %d weight %v, want 5 80", set.Reps, set.Weight)
+	}
+}
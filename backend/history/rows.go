@@ -0,0 +1,225 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/prescription"
+	"workout-tracker/tracking"
+)
+
+// Column 导出列
+type Column struct {
+	Key   string
+	Title string
+	value func(r Row) interface{}
+}
+
+// Row 一条已记录的训练组
+type Row struct {
+	Date      time.Time
+	Workout   string
+	Exercise  string
+	BodyPart  string
+	Block     string // 训练块名称和类型，例如 "A 超级组"
+	SetNumber int
+	Reps      int
+	Weight    float64 // 公斤
+	Duration  int     // 秒
+	Distance  float64 // 米
+	RestTime  int
+	Calories  float64
+	RPE       float64 // 0 表示未记录
+	RIR       *int
+	Tempo     string
+	Flags     string // 疼痛、动作变形
+	Note      string
+}
+
+// Columns 所有可导出的列，默认按此顺序输出
+var Columns = []Column{
+	{Key: "date", Title: "日期", value: func(r Row) interface{} { return r.Date.Format("2006-01-02 15:04") }},
+	{Key: "workout", Title: "训练计划", value: func(r Row) interface{} { return r.Workout }},
+	{Key: "exercise", Title: "动作", value: func(r Row) interface{} { return r.Exercise }},
+	{Key: "bodyPart", Title: "身体部位", value: func(r Row) interface{} { return r.BodyPart }},
+	{Key: "block", Title: "训练块", value: func(r Row) interface{} { return r.Block }},
+	{Key: "set", Title: "组", value: func(r Row) interface{} { return r.SetNumber }},
+	{Key: "reps", Title: "次数", value: func(r Row) interface{} { return r.Reps }},
+	{Key: "weight", Title: "重量(kg)", value: func(r Row) interface{} { return r.Weight }},
+	{Key: "duration", Title: "时长(秒)", value: func(r Row) interface{} { return r.Duration }},
+	{Key: "distance", Title: "距离(米)", value: func(r Row) interface{} { return r.Distance }},
+	{Key: "rest", Title: "休息(秒)", value: func(r Row) interface{} { return r.RestTime }},
+	{Key: "calories", Title: "卡路里", value: func(r Row) interface{} { return r.Calories }},
+	{Key: "rpe", Title: "RPE", value: func(r Row) interface{} { return optional(r.RPE) }},
+	{Key: "rir", Title: "RIR", value: func(r Row) interface{} {
+		if r.RIR == nil {
+			return ""
+		}
+		return *r.RIR
+	}},
+	{Key: "tempo", Title: "节奏", value: func(r Row) interface{} { return r.Tempo }},
+	{Key: "flags", Title: "标记", value: func(r Row) interface{} { return r.Flags }},
+	{Key: "note", Title: "备注", value: func(r Row) interface{} { return r.Note }},
+}
+
+// 未记录的数值输出为空
+func optional(value float64) interface{} {
+	if value == 0 {
+		return ""
+	}
+	return value
+}
+
+// ParseColumns 解析逗号分隔的列名，为空时返回全部列
+func ParseColumns(spec string) ([]Column, error) {
+	if strings.TrimSpace(spec) == "" {
+		return Columns, nil
+	}
+
+	byKey := make(map[string]Column)
+	for _, column := range Columns {
+		byKey[column.Key] = column
+	}
+
+	var result []Column
+	for _, key := range strings.Split(spec, ",") {
+		column, ok := byKey[strings.TrimSpace(key)]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", strings.TrimSpace(key))
+		}
+		result = append(result, column)
+	}
+	return result, nil
+}
+
+// Values 按列顺序取出一行的值
+func Values(columns []Column, row Row) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column.value(row)
+	}
+	return values
+}
+
+// Rows 将训练记录展开为每组一行，按日期排序并通过 fn 逐行输出
+// from/to 为零值时不限制
+func Rows(sessions []models.WorkoutSession, workouts []models.Workout, exercises []models.Exercise, from, to time.Time, fn func(Row) error) error {
+	workoutByID := make(map[string]models.Workout)
+	for _, workout := range workouts {
+		workoutByID[workout.ID] = workout
+	}
+	exerciseByID := make(map[string]models.Exercise)
+	for _, exercise := range exercises {
+		exerciseByID[exercise.ID] = exercise
+	}
+
+	sorted := append([]models.WorkoutSession{}, sessions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	for _, session := range sorted {
+		if !from.IsZero() && session.Date.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !session.Date.Before(to) {
+			continue
+		}
+
+		workout := workoutByID[session.WorkoutID]
+		for _, completed := range session.Exercises {
+			exercise := exerciseByID[completed.ExerciseID]
+			fields := tracking.FieldsOf(tracking.Mode(exercise))
+			var planned *models.ExerciseSet
+			for i, set := range workout.Exercises {
+				if set.ExerciseID == completed.ExerciseID && set.Block == completed.Block {
+					planned = &workout.Exercises[i]
+					break
+				}
+			}
+
+			for i := 0; i < completed.CompletedSets; i++ {
+				row := Row{
+					Date:      session.Date,
+					Workout:   workout.Name,
+					Exercise:  exercise.Name,
+					BodyPart:  exercise.BodyPart,
+					Block:     blockLabel(workout, completed.Block),
+					SetNumber: i + 1,
+				}
+				if row.Exercise == "" {
+					row.Exercise = completed.ExerciseID
+				}
+				if i < len(completed.CompletedReps) {
+					row.Reps = completed.CompletedReps[i]
+				}
+				if fields.Weight {
+					// 没有记录实际重量时使用训练计划中的目标
+					if i < len(completed.CompletedWeights) {
+						row.Weight = completed.CompletedWeights[i]
+					} else {
+						row.Weight = plannedWeight(planned, i)
+					}
+				}
+				if i < len(completed.CompletedDurations) {
+					row.Duration = completed.CompletedDurations[i]
+				}
+				if i < len(completed.CompletedDistances) {
+					row.Distance = completed.CompletedDistances[i]
+				}
+				if i < len(completed.ActualRestTimes) {
+					row.RestTime = completed.ActualRestTimes[i]
+				}
+				row.Calories = tracking.Calories(exercise, row.Reps, row.Duration)
+				if i < len(completed.SetLogs) {
+					log := completed.SetLogs[i]
+					row.RPE, row.RIR, row.Tempo, row.Note = log.RPE, log.RIR, log.Tempo, log.Note
+					row.Flags = flagLabel(log)
+				}
+
+				if err := fn(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// 训练块的名称和类型，不属于训练块时为空
+func blockLabel(workout models.Workout, id string) string {
+	if id == "" {
+		return ""
+	}
+	for _, block := range workout.Blocks {
+		if block.ID == id {
+			return strings.TrimSpace(block.Name + " " + prescription.BlockTypeNames[block.Type])
+		}
+	}
+	return id
+}
+
+// 疼痛和动作变形标记
+func flagLabel(log models.SetLog) string {
+	var flags []string
+	if log.Pain {
+		flags = append(flags, "疼痛")
+	}
+	if log.FormIssue {
+		flags = append(flags, "动作变形")
+	}
+	return strings.Join(flags, "、")
+}
+
+// 训练计划中第 i 组的目标重量，换算为公斤
+func plannedWeight(set *models.ExerciseSet, i int) float64 {
+	if set == nil {
+		return 0
+	}
+	if i < len(set.Targets) {
+		return prescription.Kilograms(set.Targets[i].Weight, set.Targets[i].Unit)
+	}
+	return prescription.Kilograms(set.Weight, set.Unit)
+}
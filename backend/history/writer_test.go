@@ -0,0 +1,96 @@
+package history
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+var formulaTests = []struct {
+	value interface{}
+	csv   string
+}{
+	{"=HYPERLINK(\"http://evil\",\"x\")", "'=HYPERLINK(\"http://evil\",\"x\")"},
+	{"+1+1", "'+1+1"},
+	{"-2+3", "'-2+3"},
+	{"@SUM(A1)", "'@SUM(A1)"},
+	{"\t=1", "'\t=1"},
+	{"\r=1", "'\r=1"},
+	{"深蹲", "深蹲"},
+	{"3-1-1-0", "3-1-1-0"},
+	{"", ""},
+	// 数值不是用户输入的文本，负数保持原样
+	{-5, "-5"},
+	{-2.5, "-2.5"},
+}
+
+func TestCSVWriterEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]interface{}, len(formulaTests))
+	for i, tt := range formulaTests {
+		values[i] = tt.value
+	}
+	if err := w.WriteRow(values); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF"))).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range formulaTests {
+		if record[i] != tt.csv {
+			t.Errorf("%q: got %q, want %q", tt.value, record[i], tt.csv)
+		}
+	}
+}
+
+func TestXLSXWriterWritesTextAsInlineStrings(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSXWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]interface{}{"=1+1", -5, "备注"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t>=1+1</t></is></c>`,
+		`<c r="B1"><v>-5</v></c>`,
+		`<c r="C1" t="inlineStr"><is><t>备注</t></is></c>`,
+	} {
+		if !strings.Contains(string(sheet), want) {
+			t.Errorf("sheet missing %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(string(sheet), "<f>") {
+		t.Errorf("sheet contains a formula cell:\n%s", sheet)
+	}
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// ErrorClass 将错误归类，便于按类型聚合和告警
+func ErrorClass(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var pathErr *fs.PathError
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_found"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "decode"
+	case errors.As(err, &pathErr):
+		return "io"
+	case errors.As(err, &netErr):
+		return "network"
+	case strings.HasSuffix(err.Error(), "not found"):
+		return "not_found"
+	}
+	return "internal"
+}
+
+// classHandler 遇到 error 字段时自动补充 error_class
+type classHandler struct {
+	slog.Handler
+}
+
+func (h *classHandler) Handle(ctx context.Context, record slog.Record) error {
+	var class string
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "error" {
+			if err, ok := attr.Value.Any().(error); ok {
+				class = ErrorClass(err)
+				return false
+			}
+		}
+		return true
+	})
+	if class != "" {
+		record.AddAttrs(slog.String("error_class", class))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *classHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &classHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *classHandler) WithGroup(name string) slog.Handler {
+	return &classHandler{Handler: h.Handler.WithGroup(name)}
+}
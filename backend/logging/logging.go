@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Setup 将默认日志设置为结构化输出，标准库 log 的输出也会经过这里
+// level 为 debug|info|warn|error，format 为 json|text
+func Setup(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("invalid log level %q", level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(&classHandler{Handler: handler}))
+	return nil
+}
+
+type ctxKey struct{}
+
+// 一次请求的日志状态，处理过程中补充的字段也会出现在访问日志中
+type requestState struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+// NewContext 将请求的 logger 放入 context
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &requestState{logger: logger})
+}
+
+// FromContext 取出请求的 logger，没有时返回默认 logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if state, ok := ctx.Value(ctxKey{}).(*requestState); ok {
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			return state.logger
+		}
+	}
+	return slog.Default()
+}
+
+// AddAttrs 为请求补充日志字段，例如处理中才能确定的用户或实体ID
+func AddAttrs(ctx context.Context, args ...any) {
+	if ctx == nil {
+		return
+	}
+	if state, ok := ctx.Value(ctxKey{}).(*requestState); ok {
+		state.mu.Lock()
+		state.logger = state.logger.With(args...)
+		state.mu.Unlock()
+	}
+}
+
+// Component 后台任务使用的 logger
+func Component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
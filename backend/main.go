@@ -1,87 +1,367 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"workout-tracker/archive"
+	"workout-tracker/delta"
+	"workout-tracker/events"
 	"workout-tracker/handlers"
+	"workout-tracker/health"
+	"workout-tracker/history"
+	"workout-tracker/logging"
+	"workout-tracker/media"
+	"workout-tracker/metrics"
+	"workout-tracker/middleware"
+	"workout-tracker/openapi"
+	"workout-tracker/prescription"
+	"workout-tracker/records"
 	"workout-tracker/presenter"
 	"workout-tracker/repository"
+	"workout-tracker/taxonomy"
+	"workout-tracker/uploads"
+	"workout-tracker/webhooks"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 结构化日志，LOG_LEVEL=debug|info|warn|error，LOG_FORMAT=json|text
+	if err := logging.Setup(os.Stdout, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	// 设置数据目录和上传目录
 	dataDir := "../data"
 	uploadDir := "../uploads"
 
 	// 初始化仓库和呈现器
 	repo := repository.NewFileRepository(dataDir)
+	metrics.RegisterDomain(repo, dataDir)
 	presenter := presenter.NewWorkoutPresenter()
-	handler := handlers.NewWorkoutHandler(repo, presenter, uploadDir)
+	bus := events.NewBus()
+	backend, err := uploadBackend(uploadDir)
+	if err != nil {
+		slog.Error("configure upload storage failed", "error", err)
+		os.Exit(1)
+	}
+	store := uploads.NewStore(backend)
+
+	// 首次启动写入默认分类，并把旧的 bodyPart 迁移为主要肌群
+	if seeded, err := taxonomy.Seed(repo); err != nil {
+		slog.Error("seed taxonomy failed", "error", err)
+		os.Exit(1)
+	} else if seeded {
+		slog.Info("default taxonomy created")
+	}
+	if report, err := taxonomy.Migrate(repo, false); err != nil {
+		slog.Error("migrate body parts failed", "error", err)
+		os.Exit(1)
+	} else if report.Scanned > 0 {
+		slog.Info("body parts migrated", "migrated", len(report.Migrated), "unmapped", len(report.Unmapped))
+	}
+	// 旧格式的训练计划展开为每组的目标
+	if migrated, err := prescription.Migrate(repo); err != nil {
+		slog.Error("migrate workout sets failed", "error", err)
+		os.Exit(1)
+	} else if migrated > 0 {
+		slog.Info("workout sets migrated", "workouts", migrated)
+	}
+	// 根据已有的训练记录计算个人记录
+	records.DefaultFormula = e1rmFormula()
+	if count, err := records.Migrate(repo); err != nil {
+		slog.Error("compute personal records failed", "error", err)
+		os.Exit(1)
+	} else if count > 0 {
+		slog.Info("personal records computed", "records", count)
+	}
+	handler := handlers.NewWorkoutHandler(repo, presenter, bus)
+	spec := openapi.Build(openapi.Routes)
+	docsHandler := handlers.NewDocsHandler(spec)
+	syncHandler := handlers.NewSyncHandler(delta.NewService(repo, bus))
+	streamHandler := handlers.NewStreamHandler(repo, bus)
+
+	// 后台任务在服务关闭时停止
+	stop := make(chan struct{})
+	var background sync.WaitGroup
+
+	// Webhook 投递，WEBHOOK_ALLOW_PRIVATE=true 时允许投递到内网地址
+	webhooks.AllowPrivateTargets = os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+	dispatcher := webhooks.NewDispatcher(repo)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		dispatcher.Run(bus, stop)
+	}()
+	webhookHandler := handlers.NewWebhookHandler(repo, dispatcher)
+	archiveHandler := handlers.NewArchiveHandler(archive.NewService(repo, store))
+	calendarHandler := handlers.NewCalendarHandler(repo)
+	historyHandler := handlers.NewHistoryHandler(repo, history.NewImporter(repo))
+	taxonomyHandler := handlers.NewTaxonomyHandler(repo)
+
+	// 幂等请求记录保留24小时
+	idempotency := middleware.NewIdempotency(repo, 24*time.Hour)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		idempotency.Cleanup(time.Hour, stop)
+	}()
+	idempotent := idempotency.Handler()
+
+	// 定期清理未被引用的上传文件
+	sweeper := uploads.NewSweeper(repo, store, uploadGCGrace())
+	if interval := uploadGCInterval(); interval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			sweeper.Run(interval, stop)
+		}()
+	}
+
+	// 分片上传的临时文件保存在本地，过期的会话每小时清理
+	sessions := uploads.NewSessions(repo, filepath.Join(uploadDir, ".partial"), uploads.DefaultSessionTTL)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		sessions.Cleanup(time.Hour, stop)
+	}()
+	media.FFmpeg = ffmpegPath()
+	uploadHandler := handlers.NewUploadHandler(repo, store, sweeper, sessions, uploadLimits())
+
+	// 就绪检查
+	checker := health.NewChecker(
+		health.Writable("dataDir", dataDir),
+		health.JSONFiles("dataFiles", dataDir),
+		health.Check{Name: "uploads", Run: store.Check},
+	)
+	healthHandler := handlers.NewHealthHandler(checker)
 
 	// 设置路由
-	r := gin.Default()
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		slog.Debug("route registered", "method", method, "path", path, "handler", handler)
+	}
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), middleware.Recovery(), metrics.Middleware())
 
 	// 跨域设置
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Content-Range", "Authorization", middleware.IdempotencyKeyHeader, middleware.RequestIDHeader, middleware.UserHeader}
+	config.ExposeHeaders = []string{middleware.IdempotentReplayedHeader, middleware.RequestIDHeader}
 	r.Use(cors.New(config))
 
-	// 静态文件服务
-	r.Static("/uploads", uploadDir)
-	r.Static("/static", "../frontend")
-
-	// API 路由
-	api := r.Group("/api")
-	{
-		// 动作相关
-		api.GET("/exercises", handler.GetExercises)
-		api.POST("/exercises", handler.CreateExercise)
-		api.PUT("/exercises/:id", handler.UpdateExercise)
-		api.DELETE("/exercises/:id", handler.DeleteExercise)
-
-		// 训练计划相关
-		api.GET("/workouts", handler.GetWorkouts)
-		api.POST("/workouts", handler.CreateWorkout)
-		api.GET("/workouts/:id", handler.GetWorkout)
-		api.PUT("/workouts/:id", handler.UpdateWorkout)
-		api.DELETE("/workouts/:id", handler.DeleteWorkout)
-
-		// 训练记录相关
-		api.GET("/sessions", handler.GetSessions)
-		api.POST("/sessions", handler.CreateSession)
-		api.GET("/sessions/:id", handler.GetSession)
-		api.PUT("/sessions/:id", handler.UpdateSession)
-
-		// 统计相关
-		api.GET("/statistics", handler.GetStatistics)
-
-		// 文件上传
-		api.POST("/upload", handler.UploadFile)
-	}
-
-	// 根路径重定向到后台管理页面
-	r.GET("/", func(c *gin.Context) {
-		c.Redirect(302, "/static/admin.html")
+	registerRoutes(r, routeHandlers{
+		workout:    handler,
+		taxonomy:   taxonomyHandler,
+		stream:     streamHandler,
+		history:    historyHandler,
+		sync:       syncHandler,
+		webhook:    webhookHandler,
+		archive:    archiveHandler,
+		calendar:   calendarHandler,
+		upload:     uploadHandler,
+		docs:       docsHandler,
+		health:     healthHandler,
+		idempotent: idempotent,
 	})
 
-	// 移动端页面
-	r.GET("/mobile", func(c *gin.Context) {
-		// 保留查询参数
-		query := c.Request.URL.RawQuery
-		if query != "" {
-			c.Redirect(302, "/static/mobile.html?"+query)
-		} else {
-			c.Redirect(302, "/static/mobile.html")
+	server := &http.Server{Addr: ":8769", Handler: r}
+	server.RegisterOnShutdown(streamHandler.Close)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	slog.Info("server started",
+		"addr", "http://localhost:8769",
+		"admin", "http://localhost:8769",
+		"mobile", "http://localhost:8769/mobile",
+		"docs", "http://localhost:8769/api/docs")
+
+	// 收到 SIGINT/SIGTERM 后停止接收新连接，等待进行中的请求和写入完成
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serveErr:
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	case <-signals.Done():
+	}
+
+	timeout := shutdownTimeout()
+	slog.Info("shutting down", "timeout", timeout.String())
+	checker.SetShuttingDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	exitCode := 0
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("drain requests failed", "error", err)
+		exitCode = 1
+	}
+
+	close(stop)
+	stopped := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Error("stop background tasks failed", "error", ctx.Err())
+		exitCode = 1
+	}
+
+	if err := repo.Flush(ctx); err != nil {
+		slog.Error("flush pending writes failed", "error", err)
+		exitCode = 1
+	}
+
+	slog.Info("server stopped")
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// 单个上传文件的大小上限，UPLOAD_MAX_SIZE 例如 50MB，默认 20MB；
+// 视频单独由 UPLOAD_MAX_VIDEO_SIZE 设置，默认 200MB
+func uploadLimits() uploads.Limits {
+	return uploads.Limits{
+		File:  uploadSize("UPLOAD_MAX_SIZE", uploads.DefaultMaxSize),
+		Video: uploadSize("UPLOAD_MAX_VIDEO_SIZE", uploads.DefaultMaxVideoSize),
+	}
+}
+
+func uploadSize(name string, fallback int64) int64 {
+	if value := os.Getenv(name); value != "" {
+		if size, err := uploads.ParseSize(value); err == nil {
+			return size
 		}
-	})
+		slog.Warn("invalid "+name+", using default", "value", value)
+	}
+	return fallback
+}
+
+// 截取视频封面使用的 ffmpeg，FFMPEG_PATH 未设置时从 PATH 中查找，找不到时不生成视频封面
+func ffmpegPath() string {
+	if value := os.Getenv("FFMPEG_PATH"); value != "" {
+		return value
+	}
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		slog.Info("ffmpeg not found, video posters are disabled")
+		return ""
+	}
+	return path
+}
+
+// 上传文件的存储，UPLOAD_STORAGE=s3 时使用 S3 兼容的对象存储，
+// 本地目录中原有的文件在读取时或调用迁移接口时复制过去
+func uploadBackend(uploadDir string) (uploads.Backend, error) {
+	local := uploads.NewLocal(uploadDir)
+	switch storage := os.Getenv("UPLOAD_STORAGE"); storage {
+	case "", "local":
+		return local, nil
+	case "s3":
+	default:
+		return nil, fmt.Errorf("unknown UPLOAD_STORAGE %q", storage)
+	}
+
+	config := uploads.S3Config{
+		Endpoint:       os.Getenv("UPLOAD_S3_ENDPOINT"),
+		Bucket:         os.Getenv("UPLOAD_S3_BUCKET"),
+		Region:         os.Getenv("UPLOAD_S3_REGION"),
+		AccessKey:      os.Getenv("UPLOAD_S3_ACCESS_KEY"),
+		SecretKey:      os.Getenv("UPLOAD_S3_SECRET_KEY"),
+		Prefix:         os.Getenv("UPLOAD_S3_PREFIX"),
+		UseSSL:         os.Getenv("UPLOAD_S3_INSECURE") != "true",
+		PublicEndpoint: os.Getenv("UPLOAD_S3_PUBLIC_ENDPOINT"),
+	}
+	config.PublicUseSSL = config.UseSSL
+	if value := os.Getenv("UPLOAD_S3_PUBLIC_INSECURE"); value != "" {
+		config.PublicUseSSL = value != "true"
+	}
+	// 指定区域后生成预签名地址时不需要访问对象存储
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if value := os.Getenv("UPLOAD_S3_PRESIGN"); value != "" {
+		expiry, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UPLOAD_S3_PRESIGN: %w", err)
+		}
+		config.PresignExpiry = expiry
+	}
+
+	s3, err := uploads.NewS3(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s3.EnsureBucket(ctx); err != nil {
+		// 启动时对象存储不可用不阻止启动，由就绪检查报告
+		slog.Warn("ensure upload bucket failed", "bucket", config.Bucket, "error", err)
+	}
+	return uploads.NewMigrating(s3, local), nil
+}
 
-	log.Println("服务器启动在 http://localhost:8769")
-	log.Println("后台管理: http://localhost:8769")
-	log.Println("移动端训练: http://localhost:8769/mobile")
-	
-	r.Run(":8769")
+// 估算 1RM 的默认公式，E1RM_FORMULA=epley|brzycki|lombardi，默认 epley
+func e1rmFormula() string {
+	if value := os.Getenv("E1RM_FORMULA"); value != "" {
+		if records.IsFormula(value) {
+			return value
+		}
+		slog.Warn("invalid E1RM_FORMULA, using default", "value", value)
+	}
+	return records.DefaultFormula
+}
+
+// 未被引用的上传文件保留多久后才清理，UPLOAD_GC_GRACE 例如 72h，默认 24h
+func uploadGCGrace() time.Duration {
+	if value := os.Getenv("UPLOAD_GC_GRACE"); value != "" {
+		if grace, err := time.ParseDuration(value); err == nil && grace >= 0 {
+			return grace
+		}
+		slog.Warn("invalid UPLOAD_GC_GRACE, using default", "value", value)
+	}
+	return 24 * time.Hour
+}
+
+// 自动清理的间隔，UPLOAD_GC_INTERVAL 例如 6h，设为 0 关闭自动清理，默认 24h
+func uploadGCInterval() time.Duration {
+	if value := os.Getenv("UPLOAD_GC_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval >= 0 {
+			return interval
+		}
+		slog.Warn("invalid UPLOAD_GC_INTERVAL, using default", "value", value)
+	}
+	return 24 * time.Hour
+}
+
+// 关闭时等待的最长时间，SHUTDOWN_TIMEOUT 例如 30s，默认 20s
+func shutdownTimeout() time.Duration {
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			return timeout
+		}
+		slog.Warn("invalid SHUTDOWN_TIMEOUT, using default", "value", value)
+	}
+	return 20 * time.Second
 }
@@ -0,0 +1,137 @@
+package media
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// 动图在客户端逐帧解码，帧数 × 画布像素数超过该值时不接受
+const maxAnimationPixels = 1_000_000_000
+
+// gif 块类型
+const (
+	gifExtension  = 0x21
+	gifImage      = 0x2C
+	gifTrailer    = 0x3B
+	gifGraphicExt = 0xF9
+)
+
+var errGIFFormat = errors.New("malformed gif")
+
+// 遍历块结构得到的动图信息
+type gifInfo struct {
+	width, height int
+	frames        int
+	delay         int // 各帧延迟之和，单位 1/100 秒
+}
+
+// scanGIF 遍历 GIF 的块结构，统计帧数和延迟，不解压图像数据，内存占用与帧数无关。
+// 画布超过 maxPixels 时在读取帧之前返回 ErrTooLarge；maxFrames 大于 0 时读到该帧数后停止
+func scanGIF(r io.Reader, maxFrames int) (*gifInfo, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, errGIFFormat
+	}
+	if string(header[:6]) != "GIF87a" && string(header[:6]) != "GIF89a" {
+		return nil, errGIFFormat
+	}
+	info := &gifInfo{
+		width:  int(binary.LittleEndian.Uint16(header[6:8])),
+		height: int(binary.LittleEndian.Uint16(header[8:10])),
+	}
+	if info.width*info.height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	if err := skipColorTable(br, header[10]); err != nil {
+		return nil, err
+	}
+
+	for {
+		block, err := br.ReadByte()
+		if err != nil {
+			return nil, errGIFFormat
+		}
+		switch block {
+		case gifExtension:
+			label, err := br.ReadByte()
+			if err != nil {
+				return nil, errGIFFormat
+			}
+			if label == gifGraphicExt {
+				// 图形控制扩展：长度 4，第 2-3 字节为延迟，以 0 结束
+				ext := make([]byte, 6)
+				if _, err := io.ReadFull(br, ext); err != nil || ext[0] != 4 || ext[5] != 0 {
+					return nil, errGIFFormat
+				}
+				info.delay += int(binary.LittleEndian.Uint16(ext[2:4]))
+				continue
+			}
+			if err := skipSubBlocks(br); err != nil {
+				return nil, err
+			}
+		case gifImage:
+			desc := make([]byte, 9)
+			if _, err := io.ReadFull(br, desc); err != nil {
+				return nil, errGIFFormat
+			}
+			left := int(binary.LittleEndian.Uint16(desc[0:2]))
+			top := int(binary.LittleEndian.Uint16(desc[2:4]))
+			width := int(binary.LittleEndian.Uint16(desc[4:6]))
+			height := int(binary.LittleEndian.Uint16(desc[6:8]))
+			if left+width > info.width || top+height > info.height {
+				return nil, errGIFFormat
+			}
+			if err := skipColorTable(br, desc[8]); err != nil {
+				return nil, err
+			}
+			// LZW 最小码长，之后是压缩数据
+			if _, err := br.ReadByte(); err != nil {
+				return nil, errGIFFormat
+			}
+			if err := skipSubBlocks(br); err != nil {
+				return nil, err
+			}
+			info.frames++
+			if maxFrames > 0 && info.frames >= maxFrames {
+				return info, nil
+			}
+		case gifTrailer:
+			if info.frames == 0 {
+				return nil, errGIFFormat
+			}
+			return info, nil
+		default:
+			return nil, errGIFFormat
+		}
+	}
+}
+
+// 标记中带有颜色表时跳过
+func skipColorTable(br *bufio.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	if _, err := br.Discard(3 * (1 << (flags&0x07 + 1))); err != nil {
+		return errGIFFormat
+	}
+	return nil
+}
+
+// 跳过以长度为 0 的子块结束的数据
+func skipSubBlocks(br *bufio.Reader) error {
+	for {
+		n, err := br.ReadByte()
+		if err != nil {
+			return errGIFFormat
+		}
+		if n == 0 {
+			return nil
+		}
+		if _, err := br.Discard(int(n)); err != nil {
+			return errGIFFormat
+		}
+	}
+}
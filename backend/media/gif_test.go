@@ -0,0 +1,103 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// 生成 width×height 画布、frames 帧的动图，每帧延迟 delay
+func testGIF(t *testing.T, width, height, frames, delay int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{Config: image.Config{ColorModel: palette, Width: width, Height: height}}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, min(width, 4), min(height, 4)), palette)
+		frame.SetColorIndex(0, 0, uint8(i%2))
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, delay)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// 只有文件头的动图，用来构造超大画布
+func gifHeader(width, height int) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, uint16(width))
+	data = binary.LittleEndian.AppendUint16(data, uint16(height))
+	return append(data, 0, 0, 0, gifTrailer)
+}
+
+func TestProbeGIF(t *testing.T) {
+	valid := testGIF(t, 64, 48, 3, 10)
+	tests := []struct {
+		name     string
+		data     []byte
+		width    int
+		height   int
+		duration float64
+		err      error
+	}{
+		{"single frame", testGIF(t, 10, 10, 1, 0), 10, 10, 0, nil},
+		{"animation", valid, 64, 48, 0.3, nil},
+		{"oversize canvas", gifHeader(10000, 10000), 0, 0, 0, ErrTooLarge},
+		{"too many frames for canvas", testGIF(t, 1000, 1000, 1001, 1), 0, 0, 0, ErrTooLarge},
+		{"truncated", valid[:len(valid)-5], 0, 0, 0, ErrInvalidVideo},
+		{"no frames", gifHeader(10, 10), 0, 0, 0, ErrInvalidVideo},
+		{"not a gif", []byte("\x89PNG\r\n\x1a\n"), 0, 0, 0, ErrInvalidVideo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ProbeVideo(bytes.NewReader(tt.data), int64(len(tt.data)), "image/gif")
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Width != tt.width || info.Height != tt.height || info.Duration != tt.duration || info.Container != "gif" {
+				t.Errorf("info = %+v", info)
+			}
+		})
+	}
+}
+
+func TestScanGIFMatchesDecoder(t *testing.T) {
+	for _, frames := range []int{1, 2, 7} {
+		data := testGIF(t, 20, 30, frames, 5)
+		info, err := scanGIF(bytes.NewReader(data), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		delay := 0
+		for _, d := range decoded.Delay {
+			delay += d
+		}
+		if info.frames != len(decoded.Image) || info.delay != delay || info.width != 20 || info.height != 30 {
+			t.Errorf("%d frames: scan = %+v, decoder = %d frames, delay %d", frames, info, len(decoded.Image), delay)
+		}
+
+		limited, err := scanGIF(bytes.NewReader(data), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := min(frames, 2); limited.frames != want {
+			t.Errorf("%d frames with limit 2: got %d", frames, limited.frames)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package media
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// moov 通常只有几百 KB，超过该大小视为异常文件
+const maxMoovSize = 64 << 20
+
+// 浏览器普遍支持的 MP4 编码
+var (
+	mp4VideoCodecs = map[string]bool{"avc1": true, "avc3": true, "hvc1": true, "hev1": true, "av01": true, "vp09": true}
+	mp4AudioCodecs = map[string]bool{"mp4a": true, "Opus": true}
+)
+
+type mp4Box struct {
+	typ  string
+	data []byte // 不含头部
+}
+
+// 依次解析 data 中的子 box
+func mp4Children(data []byte) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, ErrInvalidVideo
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		typ := string(data[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, ErrInvalidVideo
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			header = 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return nil, ErrInvalidVideo
+		}
+		boxes = append(boxes, mp4Box{typ: typ, data: data[header:size]})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+func mp4Child(data []byte, path ...string) []byte {
+	for _, typ := range path {
+		boxes, err := mp4Children(data)
+		if err != nil {
+			return nil
+		}
+		data = nil
+		for _, box := range boxes {
+			if box.typ == typ {
+				data = box.data
+				break
+			}
+		}
+		if data == nil {
+			return nil
+		}
+	}
+	return data
+}
+
+// 读取顶层 box，只把 moov 读入内存，mdat 等直接跳过
+func readMoov(r io.ReaderAt, size int64) ([]byte, error) {
+	var offset int64
+	first := true
+	for offset+8 <= size {
+		header := make([]byte, 16)
+		n, err := r.ReadAt(header, offset)
+		if n < 8 {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header))
+		typ := string(header[4:8])
+		headerLen := int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - offset
+		case 1:
+			if n < 16 {
+				return nil, ErrInvalidVideo
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:]))
+			headerLen = 16
+		}
+		if boxSize < headerLen || offset+boxSize > size {
+			return nil, ErrInvalidVideo
+		}
+		if first && typ != "ftyp" {
+			return nil, ErrInvalidVideo
+		}
+		first = false
+
+		if typ == "moov" {
+			if boxSize > maxMoovSize {
+				return nil, ErrInvalidVideo
+			}
+			moov := make([]byte, boxSize-headerLen)
+			if _, err := r.ReadAt(moov, offset+headerLen); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+			}
+			return moov, nil
+		}
+		offset += boxSize
+	}
+	return nil, fmt.Errorf("%w: no moov box", ErrInvalidVideo)
+}
+
+// 解析 MP4 的轨道，只接受浏览器能播放的视频和音频编码
+func probeMP4(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	moov, err := readMoov(r, size)
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := mp4Children(moov)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &VideoInfo{Container: "mp4"}
+	for _, box := range boxes {
+		switch box.typ {
+		case "mvhd":
+			info.Duration = mp4Duration(box.data)
+		case "trak":
+			if err := probeMP4Track(box.data, info); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if info.VideoCodec == "" {
+		return nil, fmt.Errorf("%w: no video track", ErrInvalidVideo)
+	}
+	return info, nil
+}
+
+func probeMP4Track(trak []byte, info *VideoInfo) error {
+	hdlr := mp4Child(trak, "mdia", "hdlr")
+	stsd := mp4Child(trak, "mdia", "minf", "stbl", "stsd")
+	if len(hdlr) < 12 || len(stsd) < 16 {
+		return nil
+	}
+	handler := string(hdlr[8:12])
+	entry := stsd[8:]
+	codec := string(entry[4:8])
+
+	switch handler {
+	case "vide":
+		if !mp4VideoCodecs[codec] {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCodec, codec)
+		}
+		if info.VideoCodec != "" {
+			return nil
+		}
+		info.VideoCodec = codec
+		// tkhd 末尾是 16.16 定点数的显示宽高，缺失时使用样本描述中的编码宽高
+		if tkhd := mp4Child(trak, "tkhd"); len(tkhd) >= 8 {
+			info.Width = int(binary.BigEndian.Uint32(tkhd[len(tkhd)-8:]) >> 16)
+			info.Height = int(binary.BigEndian.Uint32(tkhd[len(tkhd)-4:]) >> 16)
+		}
+		if (info.Width == 0 || info.Height == 0) && len(entry) >= 36 {
+			info.Width = int(binary.BigEndian.Uint16(entry[32:]))
+			info.Height = int(binary.BigEndian.Uint16(entry[34:]))
+		}
+	case "soun":
+		if !mp4AudioCodecs[codec] {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCodec, codec)
+		}
+		if info.AudioCodec == "" {
+			info.AudioCodec = codec
+		}
+	}
+	return nil
+}
+
+// mvhd 中的总时长，单位秒
+func mp4Duration(mvhd []byte) float64 {
+	if len(mvhd) < 20 {
+		return 0
+	}
+	var timescale, duration uint64
+	if mvhd[0] == 1 {
+		if len(mvhd) < 32 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:]))
+		duration = binary.BigEndian.Uint64(mvhd[24:])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:]))
+	}
+	// 全 1 表示时长未知
+	if timescale == 0 || duration == 0xffffffff || duration == 0xffffffffffffffff {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
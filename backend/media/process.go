@@ -0,0 +1,141 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Widths 生成的缩放宽度，移动端通过 srcset 按屏幕选择
+var Widths = []int{320, 640, 1280}
+
+const (
+	// 超过该像素数的图片不处理，避免解码时耗尽内存
+	maxPixels       = 40_000_000
+	originalQuality = 90
+	variantQuality  = 80
+)
+
+// ErrNotProcessable 动图等无法处理的图片，保留原文件
+var ErrNotProcessable = errors.New("image cannot be processed")
+
+// ErrTooLarge 图片尺寸超过处理上限
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// File 处理后生成的一个文件
+type File struct {
+	Suffix string // 附加在文件名后，原尺寸为空，缩放版本例如 -640w
+	Ext    string
+	MIME   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Result 处理结果
+type Result struct {
+	Original File   // 去除元数据并按 EXIF 方向旋转后的原尺寸图片
+	Variants []File // 按宽度从小到大排列，WebP 版本紧跟在同尺寸的 JPEG/PNG 之后
+}
+
+// Processable 是否为可以处理的图片类型
+func Processable(mime string) bool {
+	switch mime {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Process 去除 EXIF 等元数据（包括 GPS 位置），按方向标记摆正图片，
+// 并生成多个宽度的缩放版本，每个宽度都有 JPEG/PNG 和无损 WebP 两个版本。
+// JPEG 输出 JPEG，其他格式输出 PNG 以保留透明度。
+func Process(data []byte, mime string) (*Result, error) {
+	if !Processable(mime) {
+		return nil, ErrNotProcessable
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	// 动图缩放后只剩第一帧，直接保留原文件；读到第二帧即可判断，不解码各帧
+	if mime == "image/gif" {
+		info, err := scanGIF(bytes.NewReader(data), 2)
+		if err != nil {
+			return nil, fmt.Errorf("decode image: %w", err)
+		}
+		if info.frames > 1 {
+			return nil, ErrNotProcessable
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if mime == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	encode, ext, outMIME := encodePNG, ".png", "image/png"
+	if mime == "image/jpeg" {
+		encode, ext, outMIME = encodeJPEG, ".jpg", "image/jpeg"
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	original, err := encode(img, originalQuality)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Original: File{Ext: ext, MIME: outMIME, Width: width, Height: height, Data: original},
+	}
+
+	for _, w := range Widths {
+		if w >= width {
+			break
+		}
+		h := max(1, (height*w+width/2)/width)
+		scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+
+		suffix := fmt.Sprintf("-%dw", w)
+		fallback, err := encode(scaled, variantQuality)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, File{Suffix: suffix, Ext: ext, MIME: outMIME, Width: w, Height: h, Data: fallback})
+
+		var webp bytes.Buffer
+		if err := EncodeWebP(&webp, scaled); err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, File{Suffix: suffix, Ext: ".webp", MIME: "image/webp", Width: w, Height: h, Data: webp.Bytes()})
+	}
+	return result, nil
+}
+
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	return buf.Bytes(), err
+}
+
+func encodePNG(img image.Image, _ int) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	err := encoder.Encode(&buf, img)
+	return buf.Bytes(), err
+}
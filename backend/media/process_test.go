@@ -0,0 +1,129 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		mime     string
+		err      error
+		ext      string
+		variants []int // 缩放版本的宽度
+	}{
+		{"animated gif is kept as is", testGIF(t, 40, 40, 3, 10), "image/gif", ErrNotProcessable, "", nil},
+		{"single frame gif", testGIF(t, 40, 40, 1, 0), "image/gif", nil, ".png", nil},
+		{"oversize gif canvas", gifHeader(10000, 10000), "image/gif", ErrTooLarge, "", nil},
+		{"jpeg with variants", testJPEG(t, 700, 350), "image/jpeg", nil, ".jpg", []int{320, 640}},
+		{"unsupported type", []byte("<svg/>"), "image/svg+xml", ErrNotProcessable, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Process(tt.data, tt.mime)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Original.Ext != tt.ext {
+				t.Errorf("ext = %s, want %s", result.Original.Ext, tt.ext)
+			}
+			var widths []int
+			for i, variant := range result.Variants {
+				// 每个 JPEG/PNG 版本之后紧跟同尺寸的 WebP 版本
+				if i%2 == 1 {
+					if prev := result.Variants[i-1]; variant.MIME != "image/webp" || variant.Ext != ".webp" || variant.Width != prev.Width || variant.Height != prev.Height {
+						t.Errorf("variant %d = %s %dx%d, want WebP %dx%d", i, variant.MIME, variant.Width, variant.Height, prev.Width, prev.Height)
+					}
+					continue
+				}
+				widths = append(widths, variant.Width)
+				if want := (350*variant.Width + 350) / 700; variant.Height != want {
+					t.Errorf("variant %dw height = %d, want %d", variant.Width, variant.Height, want)
+				}
+			}
+			if len(widths) != len(tt.variants) {
+				t.Fatalf("variant widths = %v, want %v", widths, tt.variants)
+			}
+			for i := range widths {
+				if widths[i] != tt.variants[i] {
+					t.Fatalf("variant widths = %v, want %v", widths, tt.variants)
+				}
+			}
+		})
+	}
+}
+
+// 生成类似照片的图片：平滑的明暗变化叠加传感器噪点，无损编码后通常比 JPEG 大
+func testPhoto(t *testing.T, width, height int) []byte {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			light := 128 + 100*math.Sin(float64(x)/90)*math.Cos(float64(y)/70)
+			noise := func() uint8 { return uint8(max(0, min(255, light+rng.NormFloat64()*12))) }
+			img.Set(x, y, color.RGBA{noise(), noise(), noise(), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessPhotoHasWebPVariants(t *testing.T) {
+	result, err := Process(testPhoto(t, 800, 600), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	webps := map[int]bool{}
+	for _, variant := range result.Variants {
+		if variant.Ext != ".webp" {
+			continue
+		}
+		img, err := webp.Decode(bytes.NewReader(variant.Data))
+		if err != nil {
+			t.Fatalf("%dw: %v", variant.Width, err)
+		}
+		if b := img.Bounds(); b.Dx() != variant.Width || b.Dy() != variant.Height {
+			t.Errorf("%dw: decoded %v, want %dx%d", variant.Width, b, variant.Width, variant.Height)
+		}
+		webps[variant.Width] = true
+	}
+	if !webps[320] || !webps[640] || len(webps) != 2 {
+		t.Errorf("webp variants = %v, want 320w and 640w", webps)
+	}
+}
@@ -0,0 +1,171 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// 封面图的最大宽度
+const posterWidth = 1280
+
+// FFmpeg 用于从 MP4/WebM 中截取封面的 ffmpeg 路径，为空时不生成视频封面
+var FFmpeg string
+
+var (
+	// ErrInvalidVideo 文件结构损坏或不是支持的容器格式
+	ErrInvalidVideo = errors.New("invalid video file")
+	// ErrUnsupportedCodec 浏览器无法播放的编码
+	ErrUnsupportedCodec = errors.New("unsupported video codec")
+	// ErrNoPoster 无法生成封面
+	ErrNoPoster = errors.New("poster frame not available")
+)
+
+// VideoInfo 视频或动图的容器、编码和尺寸
+type VideoInfo struct {
+	Container  string // mp4、webm、gif
+	VideoCodec string // 例如 avc1、vp09、V_VP9
+	AudioCodec string // 没有音轨时为空
+	Width      int
+	Height     int
+	Duration   float64 // 秒，未知时为 0
+}
+
+// Codecs 视频和音频编码
+func (v *VideoInfo) Codecs() []string {
+	codecs := []string{v.VideoCodec}
+	if v.AudioCodec != "" {
+		codecs = append(codecs, v.AudioCodec)
+	}
+	return codecs
+}
+
+// IsClip 是否按视频片段处理：视频以及无法处理成静态图片的动图
+func IsClip(mime string) bool {
+	switch mime {
+	case "video/mp4", "video/webm", "image/gif":
+		return true
+	}
+	return false
+}
+
+// ProbeVideo 校验容器结构和编码，读取尺寸和时长
+func ProbeVideo(r io.ReaderAt, size int64, mime string) (*VideoInfo, error) {
+	switch mime {
+	case "video/mp4":
+		return probeMP4(r, size)
+	case "video/webm":
+		return probeWebM(r, size)
+	case "image/gif":
+		return probeGIF(io.NewSectionReader(r, 0, size))
+	}
+	return nil, ErrInvalidVideo
+}
+
+// 动图的时长为各帧延迟之和。只遍历块结构，不解码各帧，
+// 画布过大或帧数 × 像素数超过上限时返回 ErrTooLarge
+func probeGIF(r io.Reader) (*VideoInfo, error) {
+	info, err := scanGIF(r, 0)
+	if errors.Is(err, ErrTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+	}
+	if info.frames*info.width*info.height > maxAnimationPixels {
+		return nil, ErrTooLarge
+	}
+	return &VideoInfo{
+		Container:  "gif",
+		VideoCodec: "gif",
+		Width:      info.width,
+		Height:     info.height,
+		Duration:   float64(info.delay) / 100,
+	}, nil
+}
+
+// Poster 生成 JPEG 封面：动图取第一帧，视频通过 ffmpeg 截取第一秒附近的画面
+func Poster(ctx context.Context, path string, mime string, info *VideoInfo) (*File, error) {
+	var img image.Image
+	var err error
+	switch mime {
+	case "image/gif":
+		img, err = gifFirstFrame(path)
+	case "video/mp4", "video/webm":
+		img, err = ffmpegFrame(ctx, path, info.Duration)
+	default:
+		return nil, ErrNoPoster
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > posterWidth {
+		h := max(1, (height*posterWidth+width/2)/width)
+		scaled := image.NewRGBA(image.Rect(0, 0, posterWidth, h))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+		img, width, height = scaled, posterWidth, h
+	}
+	data, err := encodeJPEG(img, variantQuality)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Suffix: "-poster", Ext: ".jpg", MIME: "image/jpeg", Width: width, Height: height, Data: data}, nil
+}
+
+func gifFirstFrame(path string) (image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := gif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+	}
+	return img, nil
+}
+
+// 用 ffmpeg 截取一帧并输出为 PNG
+func ffmpegFrame(ctx context.Context, path string, duration float64) (image.Image, error) {
+	if FFmpeg == "" {
+		return nil, ErrNoPoster
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// 第一帧常常是黑屏，取第一秒或短视频的中间；时长未知且不足一秒时退回第一帧
+	at := 1.0
+	if duration > 0 && duration < 2 {
+		at = duration / 2
+	}
+	for _, seek := range []float64{at, 0} {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, FFmpeg,
+			"-v", "error",
+			"-ss", strconv.FormatFloat(seek, 'f', 3, 64),
+			"-i", path,
+			"-frames:v", "1",
+			"-f", "image2pipe", "-c:v", "png", "-")
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("ffmpeg: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		if stdout.Len() > 0 {
+			return png.Decode(&stdout)
+		}
+	}
+	return nil, ErrNoPoster
+}
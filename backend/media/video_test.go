@@ -0,0 +1,211 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// mp4 box：4 字节大小 + 类型 + 内容
+func box(typ string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(out, typ...), data...)
+}
+
+func mp4Movie(timescale, duration uint32) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:], duration)
+	return box("mvhd", mvhd)
+}
+
+// 单个轨道，width/height 写入 tkhd，codedWidth/codedHeight 写入样本描述
+func mp4Track(handler, codec string, width, height, codedWidth, codedHeight int) []byte {
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:], uint32(width)<<16)
+	binary.BigEndian.PutUint32(tkhd[80:], uint32(height)<<16)
+
+	hdlr := make([]byte, 25)
+	copy(hdlr[8:], handler)
+
+	entry := make([]byte, 86)
+	binary.BigEndian.PutUint32(entry, uint32(len(entry)))
+	copy(entry[4:], codec)
+	binary.BigEndian.PutUint16(entry[32:], uint16(codedWidth))
+	binary.BigEndian.PutUint16(entry[34:], uint16(codedHeight))
+	stsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, entry...)
+
+	return box("trak", box("tkhd", tkhd), box("mdia", box("hdlr", hdlr), box("minf", box("stbl", box("stsd", stsd)))))
+}
+
+func mp4File(moov ...[]byte) []byte {
+	ftyp := box("ftyp", []byte("isom\x00\x00\x02\x00isomavc1"))
+	return bytes.Join([][]byte{ftyp, box("mdat", make([]byte, 32)), box("moov", moov...)}, nil)
+}
+
+func TestProbeMP4(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want VideoInfo
+		err  error
+	}{
+		{
+			name: "h264 with aac",
+			data: mp4File(mp4Movie(1000, 2500), mp4Track("vide", "avc1", 1280, 720, 1280, 720), mp4Track("soun", "mp4a", 0, 0, 0, 0)),
+			want: VideoInfo{Container: "mp4", VideoCodec: "avc1", AudioCodec: "mp4a", Width: 1280, Height: 720, Duration: 2.5},
+		},
+		{
+			name: "coded size when tkhd has none",
+			data: mp4File(mp4Movie(600, 0xffffffff), mp4Track("vide", "hvc1", 0, 0, 640, 480)),
+			want: VideoInfo{Container: "mp4", VideoCodec: "hvc1", Width: 640, Height: 480},
+		},
+		{
+			name: "unsupported video codec",
+			data: mp4File(mp4Track("vide", "mp4v", 320, 240, 320, 240)),
+			err:  ErrUnsupportedCodec,
+		},
+		{
+			name: "unsupported audio codec",
+			data: mp4File(mp4Track("vide", "avc1", 320, 240, 320, 240), mp4Track("soun", "ac-3", 0, 0, 0, 0)),
+			err:  ErrUnsupportedCodec,
+		},
+		{
+			name: "audio only",
+			data: mp4File(mp4Track("soun", "mp4a", 0, 0, 0, 0)),
+			err:  ErrInvalidVideo,
+		},
+		{
+			name: "no ftyp",
+			data: box("moov", mp4Track("vide", "avc1", 320, 240, 320, 240)),
+			err:  ErrInvalidVideo,
+		},
+		{
+			name: "box larger than file",
+			data: append(box("ftyp", []byte("isom")), 0, 0, 1, 0, 'm', 'o', 'o', 'v'),
+			err:  ErrInvalidVideo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ProbeVideo(bytes.NewReader(tt.data), int64(len(tt.data)), "video/mp4")
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *info != tt.want {
+				t.Errorf("info = %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
+
+// EBML 元素，大小固定用 8 字节写出
+func ebml(id uint32, unknownSize bool, payload ...[]byte) []byte {
+	var out []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(out) > 0 {
+			out = append(out, b)
+		}
+	}
+	data := bytes.Join(payload, nil)
+	if unknownSize {
+		out = append(out, 0xFF)
+	} else {
+		out = append(out, 0x01)
+		out = append(out, binary.BigEndian.AppendUint64(nil, uint64(len(data)))[1:]...)
+	}
+	return append(out, data...)
+}
+
+func el(id uint32, payload ...[]byte) []byte {
+	return ebml(id, false, payload...)
+}
+
+func ebmlUintBytes(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+func webmTrack(trackType uint64, codec string, width, height uint64) []byte {
+	parts := [][]byte{el(ebmlTrackType, ebmlUintBytes(trackType)), el(ebmlCodecID, []byte(codec))}
+	if trackType == 1 {
+		parts = append(parts, el(ebmlVideo, el(ebmlWidth, ebmlUintBytes(width)), el(ebmlHeight, ebmlUintBytes(height))))
+	}
+	return el(ebmlTrackEntry, parts...)
+}
+
+func webmFile(docType string, unknownSize bool, tracks ...[]byte) []byte {
+	header := el(ebmlHeader, el(ebmlDocType, []byte(docType)))
+	info := el(ebmlInfo,
+		el(ebmlTimescale, ebmlUintBytes(1000000)),
+		el(ebmlDuration, binary.BigEndian.AppendUint64(nil, math.Float64bits(2500))),
+	)
+	// Cluster 之后的内容不会被读取
+	cluster := ebml(ebmlCluster, true, []byte{0xde, 0xad})
+	return append(header, ebml(ebmlSegment, unknownSize, info, el(ebmlTracks, tracks...), cluster)...)
+}
+
+func TestProbeWebM(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want VideoInfo
+		err  error
+	}{
+		{
+			name: "vp9 with opus",
+			data: webmFile("webm", false, webmTrack(1, "V_VP9", 640, 360), webmTrack(2, "A_OPUS", 0, 0)),
+			want: VideoInfo{Container: "webm", VideoCodec: "V_VP9", AudioCodec: "A_OPUS", Width: 640, Height: 360, Duration: 2.5},
+		},
+		{
+			name: "segment of unknown size",
+			data: webmFile("webm", true, webmTrack(1, "V_VP8", 320, 240)),
+			want: VideoInfo{Container: "webm", VideoCodec: "V_VP8", Width: 320, Height: 240, Duration: 2.5},
+		},
+		{
+			name: "matroska doctype",
+			data: webmFile("matroska", false, webmTrack(1, "V_VP9", 640, 360)),
+			err:  ErrInvalidVideo,
+		},
+		{
+			name: "unsupported codec",
+			data: webmFile("webm", false, webmTrack(1, "V_MPEG4/ISO/AVC", 640, 360)),
+			err:  ErrUnsupportedCodec,
+		},
+		{
+			name: "no video track",
+			data: webmFile("webm", false, webmTrack(2, "A_VORBIS", 0, 0)),
+			err:  ErrInvalidVideo,
+		},
+		{
+			name: "truncated",
+			data: webmFile("webm", false, webmTrack(1, "V_VP9", 640, 360))[:40],
+			err:  ErrInvalidVideo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ProbeVideo(bytes.NewReader(tt.data), int64(len(tt.data)), "video/webm")
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *info != tt.want {
+				t.Errorf("info = %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
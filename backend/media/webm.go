@@ -0,0 +1,267 @@
+package media
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WebM 使用的 EBML 元素
+const (
+	ebmlHeader     = 0x1A45DFA3
+	ebmlDocType    = 0x4282
+	ebmlSegment    = 0x18538067
+	ebmlInfo       = 0x1549A966
+	ebmlTimescale  = 0x2AD7B1
+	ebmlDuration   = 0x4489
+	ebmlTracks     = 0x1654AE6B
+	ebmlTrackEntry = 0xAE
+	ebmlTrackType  = 0x83
+	ebmlCodecID    = 0x86
+	ebmlVideo      = 0xE0
+	ebmlWidth      = 0xB0
+	ebmlHeight     = 0xBA
+	ebmlCluster    = 0x1F43B675
+
+	// Info 和 Tracks 中单个元素的大小上限
+	maxEBMLElement = 1 << 20
+)
+
+// WebM 规定的编码
+var (
+	webmVideoCodecs = map[string]bool{"V_VP8": true, "V_VP9": true, "V_AV1": true}
+	webmAudioCodecs = map[string]bool{"A_VORBIS": true, "A_OPUS": true}
+)
+
+// 未知大小，直播或录制中的文件 Segment 和 Cluster 常用
+const ebmlUnknownSize = -1
+
+type ebmlElement struct {
+	id     uint32
+	offset int64 // 内容的起始位置
+	size   int64
+}
+
+// 读取一个变长整数，keepMarker 为 true 时保留长度标记位（元素 ID 的写法）
+func readVint(r io.ReaderAt, offset int64, keepMarker bool) (uint64, int, error) {
+	var first [1]byte
+	if _, err := r.ReadAt(first[:], offset); err != nil {
+		return 0, 0, err
+	}
+	length := 1
+	for mask := byte(0x80); length <= 8 && first[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 || (keepMarker && length > 4) {
+		return 0, 0, ErrInvalidVideo
+	}
+
+	buf := make([]byte, length)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return 0, 0, err
+	}
+	if !keepMarker {
+		buf[0] &= 0xff >> length
+	}
+	var value uint64
+	for _, b := range buf {
+		value = value<<8 | uint64(b)
+	}
+	return value, length, nil
+}
+
+func readEBMLElement(r io.ReaderAt, offset int64) (ebmlElement, error) {
+	id, idLen, err := readVint(r, offset, true)
+	if err != nil {
+		return ebmlElement{}, err
+	}
+	size, sizeLen, err := readVint(r, offset+int64(idLen), false)
+	if err != nil {
+		return ebmlElement{}, err
+	}
+	element := ebmlElement{id: uint32(id), offset: offset + int64(idLen+sizeLen), size: int64(size)}
+	// 所有数据位都为 1 表示未知大小
+	if size == 1<<(7*sizeLen)-1 {
+		element.size = ebmlUnknownSize
+	}
+	return element, nil
+}
+
+// 遍历 [start, end) 范围内的子元素，fn 返回 false 时停止
+func walkEBML(r io.ReaderAt, start, end int64, fn func(ebmlElement) (bool, error)) error {
+	for offset := start; offset < end; {
+		element, err := readEBMLElement(r, offset)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+		}
+		if element.size != ebmlUnknownSize && element.offset+element.size > end {
+			return ErrInvalidVideo
+		}
+		more, err := fn(element)
+		if err != nil || !more {
+			return err
+		}
+		// 未知大小的元素只允许出现在最后
+		if element.size == ebmlUnknownSize {
+			return nil
+		}
+		offset = element.offset + element.size
+	}
+	return nil
+}
+
+func readEBMLData(r io.ReaderAt, element ebmlElement) ([]byte, error) {
+	if element.size < 0 || element.size > maxEBMLElement {
+		return nil, ErrInvalidVideo
+	}
+	data := make([]byte, element.size)
+	if _, err := r.ReadAt(data, element.offset); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+	}
+	return data, nil
+}
+
+func ebmlUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func ebmlFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+// 解析 WebM 的 Info 和 Tracks，读到第一个 Cluster 时停止
+func probeWebM(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	header, err := readEBMLElement(r, 0)
+	if err != nil || header.id != ebmlHeader {
+		return nil, ErrInvalidVideo
+	}
+	docType := ""
+	err = walkEBML(r, header.offset, header.offset+header.size, func(e ebmlElement) (bool, error) {
+		if e.id == ebmlDocType {
+			data, err := readEBMLData(r, e)
+			docType = string(data)
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if docType != "webm" {
+		return nil, fmt.Errorf("%w: doctype %q", ErrInvalidVideo, docType)
+	}
+
+	segment, err := readEBMLElement(r, header.offset+header.size)
+	if err != nil || segment.id != ebmlSegment {
+		return nil, ErrInvalidVideo
+	}
+	end := size
+	if segment.size != ebmlUnknownSize && segment.offset+segment.size < end {
+		end = segment.offset + segment.size
+	}
+
+	info := &VideoInfo{Container: "webm"}
+	timescale, duration := uint64(1000000), 0.0
+	err = walkEBML(r, segment.offset, end, func(e ebmlElement) (bool, error) {
+		switch e.id {
+		case ebmlInfo:
+			return true, walkEBML(r, e.offset, e.offset+e.size, func(c ebmlElement) (bool, error) {
+				switch c.id {
+				case ebmlTimescale:
+					data, err := readEBMLData(r, c)
+					timescale = ebmlUint(data)
+					return true, err
+				case ebmlDuration:
+					data, err := readEBMLData(r, c)
+					duration = ebmlFloat(data)
+					return true, err
+				}
+				return true, nil
+			})
+		case ebmlTracks:
+			return true, walkEBML(r, e.offset, e.offset+e.size, func(c ebmlElement) (bool, error) {
+				if c.id != ebmlTrackEntry {
+					return true, nil
+				}
+				return true, probeWebMTrack(r, c, info)
+			})
+		case ebmlCluster:
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info.VideoCodec == "" {
+		return nil, fmt.Errorf("%w: no video track", ErrInvalidVideo)
+	}
+	// 时长以 TimestampScale 纳秒为单位，MediaRecorder 录制的文件通常没有时长
+	info.Duration = duration * float64(timescale) / 1e9
+	return info, nil
+}
+
+func probeWebMTrack(r io.ReaderAt, entry ebmlElement, info *VideoInfo) error {
+	var trackType uint64
+	var codec string
+	var width, height int
+	err := walkEBML(r, entry.offset, entry.offset+entry.size, func(e ebmlElement) (bool, error) {
+		switch e.id {
+		case ebmlTrackType:
+			data, err := readEBMLData(r, e)
+			trackType = ebmlUint(data)
+			return true, err
+		case ebmlCodecID:
+			data, err := readEBMLData(r, e)
+			codec = string(data)
+			return true, err
+		case ebmlVideo:
+			return true, walkEBML(r, e.offset, e.offset+e.size, func(c ebmlElement) (bool, error) {
+				switch c.id {
+				case ebmlWidth:
+					data, err := readEBMLData(r, c)
+					width = int(ebmlUint(data))
+					return true, err
+				case ebmlHeight:
+					data, err := readEBMLData(r, c)
+					height = int(ebmlUint(data))
+					return true, err
+				}
+				return true, nil
+			})
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch trackType {
+	case 1: // 视频
+		if !webmVideoCodecs[codec] {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCodec, codec)
+		}
+		if info.VideoCodec == "" {
+			info.VideoCodec, info.Width, info.Height = codec, width, height
+		}
+	case 2: // 音频
+		if !webmAudioCodecs[codec] {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCodec, codec)
+		}
+		if info.AudioCodec == "" {
+			info.AudioCodec = codec
+		}
+	}
+	return nil
+}
@@ -0,0 +1,614 @@
+package media
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+)
+
+// 无损 WebP (VP8L) 编码，只使用减绿和预测变换，向后引用只用于连续相同的像素，不使用颜色缓存。
+// 标准库和 x/image 只提供解码，这里实现一个够用的编码器。
+
+const (
+	webpMaxSize       = 1 << 14
+	predictorBits     = 4 // 每 16x16 像素选择一种预测模式
+	numPredictorModes = 14
+	maxCodeLength     = 15
+	maxCodeLengthCode = 7
+
+	minCopyLength = 3
+	maxCopyLength = 4096
+	// 距离码 2 表示左侧相邻像素，其前缀码为 1
+	leftDistancePrefix = 1
+)
+
+// 码长码的写入顺序
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP 以无损 WebP 格式编码图片
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || width > webpMaxSize || height > webpMaxSize {
+		return errors.New("webp: image size out of range")
+	}
+
+	argb, hasAlpha := toARGB(img)
+
+	bw := &bitWriter{}
+	bw.writeBits(0x2f, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3)
+
+	// 解码时按相反顺序还原：先还原预测，再加回绿色
+	subtractGreen(argb)
+	bw.writeBits(1, 1)
+	bw.writeBits(2, 2)
+
+	modes, residuals := predict(argb, width, height)
+	bw.writeBits(1, 1)
+	bw.writeBits(0, 2)
+	bw.writeBits(predictorBits-2, 3)
+	writeImageData(bw, modes, false)
+
+	bw.writeBits(0, 1)
+	writeImageData(bw, residuals, true)
+
+	data := bw.bytes()
+	chunkSize := len(data)
+	padded := chunkSize + chunkSize&1
+
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+padded))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padded != chunkSize {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// 转换为非预乘的 ARGB 像素
+func toARGB(img image.Image) ([]uint32, bool) {
+	bounds := img.Bounds()
+	argb := make([]uint32, 0, bounds.Dx()*bounds.Dy())
+	hasAlpha := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff {
+				hasAlpha = true
+			}
+			argb = append(argb, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+		}
+	}
+	return argb, hasAlpha
+}
+
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		g := (p >> 8) & 0xff
+		r := ((p >> 16) - g) & 0xff
+		b := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | b
+	}
+}
+
+// 每个块选择残差最小的预测模式，返回模式子图和残差
+func predict(argb []uint32, width, height int) ([]uint32, []uint32) {
+	tiles := func(size int) int { return (size + 1<<predictorBits - 1) >> predictorBits }
+	tilesX, tilesY := tiles(width), tiles(height)
+	modes := make([]uint32, tilesX*tilesY)
+	residuals := make([]uint32, len(argb))
+
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			x0, y0 := tx<<predictorBits, ty<<predictorBits
+			x1, y1 := min(x0+1<<predictorBits, width), min(y0+1<<predictorBits, height)
+
+			best, bestCost := 0, -1
+			for mode := 0; mode < numPredictorModes; mode++ {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						cost += residualCost(sub(argb[y*width+x], predictPixel(argb, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			modes[ty*tilesX+tx] = 0xff000000 | uint32(best)<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					i := y*width + x
+					residuals[i] = sub(argb[i], predictPixel(argb, width, x, y, best))
+				}
+			}
+		}
+	}
+	return modes, residuals
+}
+
+func predictPixel(argb []uint32, width, x, y, mode int) uint32 {
+	i := y*width + x
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[i-1]
+	case x == 0:
+		return argb[i-width]
+	}
+
+	// 最右一列的右上像素取当前行的第一个像素，与按下标计算的结果一致
+	l, t, tr, tl := argb[i-1], argb[i-width], argb[i-width+1], argb[i-width-1]
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return tr
+	case 4:
+		return tl
+	case 5:
+		return average2(average2(l, tr), t)
+	case 6:
+		return average2(l, tl)
+	case 7:
+		return average2(l, t)
+	case 8:
+		return average2(tl, t)
+	case 9:
+		return average2(t, tr)
+	case 10:
+		return average2(average2(l, tl), average2(t, tr))
+	case 11:
+		return selectPixel(l, t, tl)
+	case 12:
+		return clampAddSubtractFull(l, t, tl)
+	default:
+		return clampAddSubtractHalf(average2(l, t), tl)
+	}
+}
+
+func channel(p uint32, shift uint) int {
+	return int((p >> shift) & 0xff)
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+func selectPixel(l, t, tl uint32) uint32 {
+	pl, pt := 0, 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		estimate := channel(l, shift) + channel(t, shift) - channel(tl, shift)
+		pl += abs(estimate - channel(l, shift))
+		pt += abs(estimate - channel(t, shift))
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= uint32(clamp255(channel(a, shift)+channel(b, shift)-channel(c, shift))) << shift
+	}
+	return out
+}
+
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		ca := channel(a, shift)
+		out |= uint32(clamp255(ca+(ca-channel(b, shift))/2)) << shift
+	}
+	return out
+}
+
+// 按通道相减，模 256
+func sub(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= uint32((channel(a, shift)-channel(b, shift))&0xff) << shift
+	}
+	return out
+}
+
+func residualCost(p uint32) int {
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		cost += abs(int(int8(channel(p, shift))))
+	}
+	return cost
+}
+
+func clamp255(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// 写入一幅熵编码图像：5 个前缀码和逐像素的符号，连续相同的像素用向后引用表示
+func writeImageData(bw *bitWriter, argb []uint32, main bool) {
+	bw.writeBits(0, 1) // 不使用颜色缓存
+	if main {
+		bw.writeBits(0, 1) // 不使用多组前缀码
+	}
+
+	type token struct {
+		pixel  uint32
+		length int // 大于 0 时表示复制左侧像素 length 次
+	}
+	var tokens []token
+	for i := 0; i < len(argb); {
+		run := 0
+		if i > 0 {
+			for i+run < len(argb) && run < maxCopyLength && argb[i+run] == argb[i-1] {
+				run++
+			}
+		}
+		if run >= minCopyLength {
+			tokens = append(tokens, token{length: run})
+			i += run
+			continue
+		}
+		tokens = append(tokens, token{pixel: argb[i]})
+		i++
+	}
+
+	green := make([]int, 256+24)
+	red := make([]int, 256)
+	blue := make([]int, 256)
+	alpha := make([]int, 256)
+	distance := make([]int, 40)
+	for _, t := range tokens {
+		if t.length > 0 {
+			prefix, _, _ := prefixEncode(t.length)
+			green[256+prefix]++
+			distance[leftDistancePrefix]++
+			continue
+		}
+		green[(t.pixel>>8)&0xff]++
+		red[(t.pixel>>16)&0xff]++
+		blue[t.pixel&0xff]++
+		alpha[t.pixel>>24]++
+	}
+
+	greenCode := writePrefixCode(bw, green)
+	redCode := writePrefixCode(bw, red)
+	blueCode := writePrefixCode(bw, blue)
+	alphaCode := writePrefixCode(bw, alpha)
+	distanceCode := writePrefixCode(bw, distance)
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			prefix, extra, extraBits := prefixEncode(t.length)
+			greenCode.write(bw, 256+prefix)
+			bw.writeBits(uint32(extra), extraBits)
+			distanceCode.write(bw, leftDistancePrefix)
+			continue
+		}
+		greenCode.write(bw, int((t.pixel>>8)&0xff))
+		redCode.write(bw, int((t.pixel>>16)&0xff))
+		blueCode.write(bw, int(t.pixel&0xff))
+		alphaCode.write(bw, int(t.pixel>>24))
+	}
+}
+
+// 长度和距离的前缀编码：返回前缀码、额外位的值和位数
+func prefixEncode(value int) (int, int, uint) {
+	v := value - 1
+	if v < 4 {
+		return v, 0, 0
+	}
+	highest := 0
+	for v>>(highest+1) != 0 {
+		highest++
+	}
+	second := (v >> (highest - 1)) & 1
+	extraBits := uint(highest - 1)
+	return 2*highest + second, v & (1<<extraBits - 1), extraBits
+}
+
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16 // 已按写入顺序反转
+}
+
+func (c *prefixCode) write(bw *bitWriter, symbol int) {
+	if n := c.lengths[symbol]; n > 0 {
+		bw.writeBits(uint32(c.codes[symbol]), uint(n))
+	}
+}
+
+// 写入前缀码并返回编码表
+func writePrefixCode(bw *bitWriter, counts []int) *prefixCode {
+	var used []int
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	// 不超过两个符号且都小于 256 时使用简单码
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(used[0]), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(used[0]), 8)
+		}
+		lengths := make([]uint8, len(counts))
+		if len(used) == 2 {
+			bw.writeBits(uint32(used[1]), 8)
+			lengths[used[0]], lengths[used[1]] = 1, 1
+		}
+		return &prefixCode{lengths: lengths, codes: canonicalCodes(lengths)}
+	}
+
+	lengths := codeLengths(counts, maxCodeLength)
+	tokens := encodeLengths(lengths)
+
+	clCounts := make([]int, 19)
+	for _, token := range tokens {
+		clCounts[token.symbol]++
+	}
+	// 码长码至少需要两个符号才能构成完整的前缀码
+	if nonZero(clCounts) < 2 {
+		for i := range clCounts {
+			if clCounts[i] == 0 {
+				clCounts[i] = 1
+				break
+			}
+		}
+	}
+	clLengths := codeLengths(clCounts, maxCodeLengthCode)
+	clCodes := canonicalCodes(clLengths)
+
+	numCodes := 19
+	for numCodes > 4 && clLengths[codeLengthOrder[numCodes-1]] == 0 {
+		numCodes--
+	}
+
+	bw.writeBits(0, 1)
+	bw.writeBits(uint32(numCodes-4), 4)
+	for i := 0; i < numCodes; i++ {
+		bw.writeBits(uint32(clLengths[codeLengthOrder[i]]), 3)
+	}
+	bw.writeBits(0, 1) // 码长覆盖全部符号
+	for _, token := range tokens {
+		bw.writeBits(uint32(clCodes[token.symbol]), uint(clLengths[token.symbol]))
+		if token.extraBits > 0 {
+			bw.writeBits(uint32(token.extra), token.extraBits)
+		}
+	}
+	return &prefixCode{lengths: lengths, codes: canonicalCodes(lengths)}
+}
+
+func nonZero(counts []int) int {
+	n := 0
+	for _, count := range counts {
+		if count > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+type lengthToken struct {
+	symbol    int
+	extra     int
+	extraBits uint
+}
+
+// 用 16/17/18 对码长做游程编码
+func encodeLengths(lengths []uint8) []lengthToken {
+	var tokens []lengthToken
+	for i := 0; i < len(lengths); {
+		value := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == value {
+			run++
+		}
+		i += run
+
+		if value == 0 {
+			for run >= 11 {
+				n := min(run, 138)
+				tokens = append(tokens, lengthToken{symbol: 18, extra: n - 11, extraBits: 7})
+				run -= n
+			}
+			if run >= 3 {
+				tokens = append(tokens, lengthToken{symbol: 17, extra: run - 3, extraBits: 3})
+				run = 0
+			}
+			for ; run > 0; run-- {
+				tokens = append(tokens, lengthToken{symbol: 0})
+			}
+			continue
+		}
+
+		tokens = append(tokens, lengthToken{symbol: int(value)})
+		run--
+		for run >= 3 {
+			n := min(run, 6)
+			tokens = append(tokens, lengthToken{symbol: 16, extra: n - 3, extraBits: 2})
+			run -= n
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, lengthToken{symbol: int(value)})
+		}
+	}
+	return tokens
+}
+
+type huffmanNode struct {
+	count       int
+	symbol      int // 叶子节点的符号，内部节点为 -1
+	left, right *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() interface{} {
+	old := *h
+	node := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return node
+}
+
+// 计算不超过 maxLength 的哈夫曼码长，超长时抬高小频次后重新计算
+func codeLengths(counts []int, maxLength int) []uint8 {
+	lengths := make([]uint8, len(counts))
+	for floor := 1; ; floor *= 2 {
+		h := &huffmanHeap{}
+		for symbol, count := range counts {
+			if count > 0 {
+				heap.Push(h, &huffmanNode{count: max(count, floor), symbol: symbol})
+			}
+		}
+		if h.Len() == 1 {
+			lengths[(*h)[0].symbol] = 1
+			return lengths
+		}
+		for h.Len() > 1 {
+			a := heap.Pop(h).(*huffmanNode)
+			b := heap.Pop(h).(*huffmanNode)
+			heap.Push(h, &huffmanNode{count: a.count + b.count, symbol: -1, left: a, right: b})
+		}
+
+		longest := 0
+		var walk func(node *huffmanNode, depth int)
+		walk = func(node *huffmanNode, depth int) {
+			if node.left == nil {
+				lengths[node.symbol] = uint8(depth)
+				longest = max(longest, depth)
+				return
+			}
+			walk(node.left, depth+1)
+			walk(node.right, depth+1)
+		}
+		walk(heap.Pop(h).(*huffmanNode), 0)
+		if longest <= maxLength {
+			return lengths
+		}
+	}
+}
+
+// 由码长生成规范哈夫曼码，并反转为低位先写的顺序
+func canonicalCodes(lengths []uint8) []uint16 {
+	type entry struct {
+		symbol int
+		length uint8
+	}
+	var entries []entry
+	for symbol, length := range lengths {
+		if length > 0 {
+			entries = append(entries, entry{symbol, length})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].length != entries[j].length {
+			return entries[i].length < entries[j].length
+		}
+		return entries[i].symbol < entries[j].symbol
+	})
+
+	codes := make([]uint16, len(lengths))
+	code, prevLength := 0, uint8(0)
+	for i, e := range entries {
+		if i > 0 {
+			code = (code + 1) << (e.length - prevLength)
+		}
+		prevLength = e.length
+		codes[e.symbol] = reverseBits(uint16(code), e.length)
+	}
+	return codes
+}
+
+func reverseBits(code uint16, length uint8) uint16 {
+	var out uint16
+	for i := uint8(0); i < length; i++ {
+		out = out<<1 | code&1
+		code >>= 1
+	}
+	return out
+}
+
+// 低位先写的位流
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (w *bitWriter) writeBits(value uint32, n uint) {
+	w.acc |= uint64(value) << w.nacc
+	w.nacc += n
+	for w.nacc >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nacc -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nacc = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,89 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	fill := func(w, h int, pixel func(x, y int) color.NRGBA) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.SetNRGBA(x, y, pixel(x, y))
+			}
+		}
+		return img
+	}
+
+	tests := []struct {
+		name string
+		img  *image.NRGBA
+	}{
+		{"single pixel", fill(1, 1, func(x, y int) color.NRGBA { return color.NRGBA{200, 10, 30, 255} })},
+		{"solid color runs", fill(300, 7, func(x, y int) color.NRGBA { return color.NRGBA{40, 80, 120, 255} })},
+		{"gradient", fill(64, 48, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 4), uint8(y * 5), uint8(x + y), 255} })},
+		{"odd size with alpha", fill(17, 33, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 15), 90, uint8(y * 7), uint8(x * y)} })},
+		{"noise", fill(40, 40, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+		})},
+		{"stripes", fill(50, 20, func(x, y int) color.NRGBA {
+			if (x/3+y)%2 == 0 {
+				return color.NRGBA{255, 255, 255, 255}
+			}
+			return color.NRGBA{0, 0, 0, 255}
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, tt.img); err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded.Bounds() != tt.img.Bounds() {
+				t.Fatalf("bounds = %v, want %v", decoded.Bounds(), tt.img.Bounds())
+			}
+			// 无损编码，每个像素都要一致；完全透明的像素只比较透明度
+			b := tt.img.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					want := tt.img.NRGBAAt(x, y)
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if want.A == 0 {
+						want, got = color.NRGBA{}, color.NRGBA{A: got.A}
+					}
+					if got != want {
+						t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPSizeLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		rect image.Rectangle
+	}{
+		{"empty", image.Rect(0, 0, 0, 0)},
+		{"too wide", image.Rect(0, 0, webpMaxSize+1, 1)},
+		{"too tall", image.Rect(0, 0, 1, webpMaxSize+1)},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := EncodeWebP(&buf, image.NewNRGBA(tt.rect)); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
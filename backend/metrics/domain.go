@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+	"workout-tracker/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Source 领域指标的数据来源
+type Source interface {
+	GetAllExercises() ([]models.Exercise, error)
+	GetAllWorkouts() ([]models.Workout, error)
+	GetAllSessions() ([]models.WorkoutSession, error)
+}
+
+var (
+	activeSessionsDesc = prometheus.NewDesc(namespace+"_active_sessions",
+		"Sessions that have been started but not completed.", nil, nil)
+	completedTodayDesc = prometheus.NewDesc(namespace+"_sessions_completed_today",
+		"Sessions completed since local midnight.", nil, nil)
+	entitiesDesc = prometheus.NewDesc(namespace+"_entities",
+		"Stored entities by type.", []string{"entity"}, nil)
+	dataFileSizeDesc = prometheus.NewDesc(namespace+"_data_file_size_bytes",
+		"Size of each JSON data file.", []string{"file"}, nil)
+)
+
+// domainCollector 在每次抓取时读取数据，保证指标与文件内容一致
+type domainCollector struct {
+	source  Source
+	dataDir string
+}
+
+// RegisterDomain 注册训练数据和数据文件大小的指标
+func RegisterDomain(source Source, dataDir string) {
+	Registry.MustRegister(&domainCollector{source: source, dataDir: dataDir})
+}
+
+func (d *domainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSessionsDesc
+	ch <- completedTodayDesc
+	ch <- entitiesDesc
+	ch <- dataFileSizeDesc
+}
+
+func (d *domainCollector) Collect(ch chan<- prometheus.Metric) {
+	if sessions, err := d.source.GetAllSessions(); err == nil {
+		now := time.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		active, completedToday := 0, 0
+		for _, session := range sessions {
+			if !session.IsCompleted {
+				active++
+			} else if !session.EndTime.Before(midnight) {
+				completedToday++
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(activeSessionsDesc, prometheus.GaugeValue, float64(active))
+		ch <- prometheus.MustNewConstMetric(completedTodayDesc, prometheus.GaugeValue, float64(completedToday))
+		ch <- prometheus.MustNewConstMetric(entitiesDesc, prometheus.GaugeValue, float64(len(sessions)), models.EntitySession)
+	} else {
+		slog.Error("collect session metrics failed", "error", err)
+	}
+
+	if exercises, err := d.source.GetAllExercises(); err == nil {
+		ch <- prometheus.MustNewConstMetric(entitiesDesc, prometheus.GaugeValue, float64(len(exercises)), models.EntityExercise)
+	}
+	if workouts, err := d.source.GetAllWorkouts(); err == nil {
+		ch <- prometheus.MustNewConstMetric(entitiesDesc, prometheus.GaugeValue, float64(len(workouts)), models.EntityWorkout)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(d.dataDir, "*.json"))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(dataFileSizeDesc, prometheus.GaugeValue, float64(info.Size()), filepath.Base(file))
+	}
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "workout"
+
+// Registry 所有指标注册在这里，由 /metrics 输出
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, Gin route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and Gin route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	repositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_operation_duration_seconds",
+		Help:      "JSON file read/write latency by operation and file.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "file"})
+
+	repositoryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "repository_errors_total",
+		Help:      "Failed JSON file reads/writes by operation and file.",
+	}, []string{"operation", "file"})
+
+	uploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes written to the upload directory.",
+	})
+
+	uploads = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_total",
+		Help:      "Files written to the upload directory.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		repositoryDuration, repositoryErrors,
+		uploadBytes, uploads,
+	)
+}
+
+// Middleware 按 Gin 路由统计请求数和耗时
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// 未匹配的路径统一计数，避免标签数量无限增长
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+
+		// SSE 长连接的耗时没有意义
+		if strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveRepository 记录一次数据文件读写
+func ObserveRepository(operation, file string, start time.Time, err error) {
+	repositoryDuration.WithLabelValues(operation, file).Observe(time.Since(start).Seconds())
+	if err != nil {
+		repositoryErrors.WithLabelValues(operation, file).Inc()
+	}
+}
+
+// AddUpload 记录写入上传目录的文件
+func AddUpload(bytes int64) {
+	uploads.Inc()
+	uploadBytes.Add(float64(bytes))
+}
+
+// Handler 输出 Prometheus 文本格式的指标
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"workout-tracker/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// 某个路由的耗时样本数
+func durationSamples(t *testing.T, route string) uint64 {
+	t.Helper()
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != namespace+"_http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "route" && label.GetValue() == route {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/test/items/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/test/missing/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/test/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/test/items/1", "/test/items/2", "/test/missing/3", "/test/stream", "/not/a/route"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		route, status string
+		want          float64
+	}{
+		// 按路由模板而不是实际路径计数
+		{"/test/items/:id", "200", 2},
+		{"/test/missing/:id", "404", 1},
+		{"/test/stream", "200", 1},
+		{"unmatched", "404", 1},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, tt.route, tt.status)); got != tt.want {
+			t.Errorf("requests %s %s = %v, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+	if n := durationSamples(t, "/test/items/:id"); n != 2 {
+		t.Errorf("duration samples = %d, want 2", n)
+	}
+	if n := durationSamples(t, "/test/stream"); n != 0 {
+		t.Errorf("event stream observed %d durations", n)
+	}
+}
+
+func TestObserveRepository(t *testing.T) {
+	ObserveRepository("read", "test_ok.json", time.Now(), nil)
+	ObserveRepository("write", "test_failed.json", time.Now(), errors.New("disk full"))
+	ObserveRepository("write", "test_failed.json", time.Now(), errors.New("disk full"))
+
+	if got := testutil.ToFloat64(repositoryErrors.WithLabelValues("read", "test_ok.json")); got != 0 {
+		t.Errorf("errors for successful read = %v", got)
+	}
+	if got := testutil.ToFloat64(repositoryErrors.WithLabelValues("write", "test_failed.json")); got != 2 {
+		t.Errorf("errors for failed write = %v, want 2", got)
+	}
+}
+
+func TestAddUpload(t *testing.T) {
+	files, bytes := testutil.ToFloat64(uploads), testutil.ToFloat64(uploadBytes)
+	AddUpload(1024)
+	AddUpload(10)
+	if got := testutil.ToFloat64(uploads) - files; got != 2 {
+		t.Errorf("uploads += %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(uploadBytes) - bytes; got != 1034 {
+		t.Errorf("upload bytes += %v, want 1034", got)
+	}
+}
+
+type fakeSource struct {
+	exercises []models.Exercise
+	workouts  []models.Workout
+	sessions  []models.WorkoutSession
+	err       error
+}
+
+func (f fakeSource) GetAllExercises() ([]models.Exercise, error) { return f.exercises, nil }
+func (f fakeSource) GetAllWorkouts() ([]models.Workout, error)   { return f.workouts, nil }
+func (f fakeSource) GetAllSessions() ([]models.WorkoutSession, error) {
+	return f.sessions, f.err
+}
+
+func TestDomainCollector(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "sessions.json"), []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	source := fakeSource{
+		exercises: make([]models.Exercise, 3),
+		workouts:  make([]models.Workout, 1),
+		sessions: []models.WorkoutSession{
+			{ID: "active"},
+			{ID: "today", IsCompleted: true, EndTime: now},
+			{ID: "yesterday", IsCompleted: true, EndTime: now.AddDate(0, 0, -1)},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		source fakeSource
+		want   string
+	}{
+		{"all metrics", source, `
+# HELP workout_active_sessions Sessions that have been started but not completed.
+# TYPE workout_active_sessions gauge
+workout_active_sessions 1
+# HELP workout_sessions_completed_today Sessions completed since local midnight.
+# TYPE workout_sessions_completed_today gauge
+workout_sessions_completed_today 1
+# HELP workout_entities Stored entities by type.
+# TYPE workout_entities gauge
+workout_entities{entity="exercise"} 3
+workout_entities{entity="session"} 3
+workout_entities{entity="workout"} 1
+# HELP workout_data_file_size_bytes Size of each JSON data file.
+# TYPE workout_data_file_size_bytes gauge
+workout_data_file_size_bytes{file="sessions.json"} 2
+`},
+		// 读取训练记录失败时其他指标照常输出
+		{"sessions unreadable", fakeSource{exercises: source.exercises, workouts: source.workouts, err: errors.New("corrupt")}, `
+# HELP workout_entities Stored entities by type.
+# TYPE workout_entities gauge
+workout_entities{entity="exercise"} 3
+workout_entities{entity="workout"} 1
+# HELP workout_data_file_size_bytes Size of each JSON data file.
+# TYPE workout_data_file_size_bytes gauge
+workout_data_file_size_bytes{file="sessions.json"} 2
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &domainCollector{source: tt.source, dataDir: dataDir}
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(tt.want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/metrics", Handler())
+	AddUpload(1)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	for _, name := range []string{"workout_uploads_total", "go_goroutines", "process_"} {
+		if !strings.Contains(w.Body.String(), name) {
+			t.Errorf("output has no %s", name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"workout-tracker/logging"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Idempotency 根据 Idempotency-Key 请求头去重，重试时重放首次请求的响应
+type Idempotency struct {
+	repo      *repository.FileRepository
+	retention time.Duration
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+func NewIdempotency(repo *repository.FileRepository, retention time.Duration) *Idempotency {
+	return &Idempotency{
+		repo:      repo,
+		retention: retention,
+		inFlight:  make(map[string]bool),
+	}
+}
+
+// 记录响应内容的 ResponseWriter
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (m *Idempotency) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+
+		method := c.Request.Method
+		path := c.Request.URL.Path
+
+		lockKey := method + " " + path + " " + key
+		m.mu.Lock()
+		if m.inFlight[lockKey] {
+			m.mu.Unlock()
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with the same Idempotency-Key is in progress"})
+			return
+		}
+		m.inFlight[lockKey] = true
+		m.mu.Unlock()
+		defer func() {
+			m.mu.Lock()
+			delete(m.inFlight, lockKey)
+			m.mu.Unlock()
+		}()
+
+		repo := m.repo.WithContext(c.Request.Context())
+		record, err := repo.GetIdempotencyRecord(method, path, key)
+		if err == nil && time.Since(record.CreatedAt) < m.retention {
+			if record.RequestHash != requestHash {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request body"})
+				return
+			}
+			logging.AddAttrs(c.Request.Context(), "idempotency_key", key, "idempotent_replay", true)
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, record.ContentType, []byte(record.Body))
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// 服务端错误允许客户端重试，不记录
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		record = &models.IdempotencyRecord{
+			Key:         key,
+			Method:      method,
+			Path:        path,
+			RequestHash: requestHash,
+			StatusCode:  status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.String(),
+			CreatedAt:   time.Now(),
+		}
+		if err := repo.SaveIdempotencyRecord(*record); err != nil {
+			logging.FromContext(c.Request.Context()).Error("save idempotency record failed", "idempotency_key", key, "error", err)
+		}
+	}
+}
+
+// Cleanup 定期清理超过保留期的幂等记录，直到 stop 被关闭
+func (m *Idempotency) Cleanup(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := m.repo.DeleteIdempotencyRecordsBefore(time.Now().Add(-m.retention)); err != nil {
+				logging.Component("idempotency").Error("clean up idempotency records failed", "error", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"time"
+	"workout-tracker/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 请求ID和用户请求头
+const (
+	RequestIDHeader = "X-Request-ID"
+	UserHeader      = "X-User" // 可选，客户端用来标识操作者
+)
+
+// 过长的请求ID可能被用来污染日志
+const maxRequestIDLength = 128
+
+// 路径参数中的密钥（如日历订阅令牌）不写入日志
+var secretParams = map[string]bool{"token": true}
+
+// RequestLogger 为每个请求分配请求ID，把带有请求信息的 logger 放入 context，
+// 并在请求结束时输出一条访问日志
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		route := c.FullPath()
+		args := []any{"request_id", requestID, "method", c.Request.Method, "route", route}
+		if user := c.GetHeader(UserHeader); user != "" {
+			args = append(args, "user", user)
+		}
+		// 路径参数通常就是实体ID；包含密钥时访问日志只记录路由模板
+		path := c.Request.URL.Path
+		for _, param := range c.Params {
+			if secretParams[param.Key] {
+				args = append(args, param.Key, "[redacted]")
+				path = route
+				continue
+			}
+			args = append(args, param.Key, param.Value)
+		}
+		ctx := logging.NewContext(c.Request.Context(), slog.Default().With(args...))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case route == "/metrics" || route == "/healthz" || route == "/readyz":
+			// 监控抓取和健康检查过于频繁
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if err := c.Errors.Last(); err != nil {
+			attrs = append(attrs, slog.Any("error", err.Err))
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "http request", attrs...)
+	}
+}
+
+// Recovery 记录 panic 并返回 500
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("panic recovered", "panic", recovered, "stack", string(debug.Stack()))
+		c.AbortWithStatus(500)
+	})
+}
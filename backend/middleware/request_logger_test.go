@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestLoggerRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogger())
+	router.GET("/api/calendar/:token/feed.ics", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/sessions/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		path    string
+		hidden  string
+		present []string
+	}{
+		{"/api/calendar/s3cr3t-token/feed.ics", "s3cr3t-token", []string{`"token":"[redacted]"`, `"path":"/api/calendar/:token/feed.ics"`}},
+		{"/api/sessions/abc", "", []string{`"id":"abc"`, `"path":"/api/sessions/abc"`}},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		out := buf.String()
+		if tt.hidden != "" && strings.Contains(out, tt.hidden) {
+			t.Errorf("%s: log contains secret: %s", tt.path, out)
+		}
+		for _, want := range tt.present {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: log missing %s: %s", tt.path, want, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"path"
+	"workout-tracker/uploads"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadHeaders 为上传目录中的文件设置安全的响应头：
+// 只有允许的图片和视频按原类型内联显示，其他文件一律作为附件下载，
+// 并禁止浏览器猜测类型或在页面上下文中执行
+func UploadHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := path.Base(c.Param("filepath"))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+
+		contentType, disposition := uploads.ContentHeaders(name)
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", disposition)
+		c.Next()
+	}
+}
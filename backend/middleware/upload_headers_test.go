@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/uploads/*filepath", UploadHeaders(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		path        string
+		contentType string
+		disposition string
+	}{
+		{"/uploads/abc.jpg", "image/jpeg", "inline; filename=abc.jpg"},
+		{"/uploads/abc.webm", "video/webm", "inline; filename=abc.webm"},
+		// 不在允许列表中的文件一律下载，不在页面中打开
+		{"/uploads/evil.html", "application/octet-stream", "attachment; filename=evil.html"},
+		{"/uploads/icon.svg", "application/octet-stream", "attachment; filename=icon.svg"},
+		{"/uploads/nested/../abc.png", "image/png", "inline; filename=abc.png"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := w.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.path, got, tt.contentType)
+		}
+		if got := w.Header().Get("Content-Disposition"); got != tt.disposition {
+			t.Errorf("%s: Content-Disposition = %q, want %q", tt.path, got, tt.disposition)
+		}
+		if w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Header().Get("Content-Security-Policy") == "" {
+			t.Errorf("%s: missing security headers: %v", tt.path, w.Header())
+		}
+	}
+}
@@ -10,19 +10,109 @@ type Exercise struct {
 	Name             string    `json:"name"`
 	Description      string    `json:"description"`
 	ImageURL         string    `json:"imageUrl"`
-	BodyPart         string    `json:"bodyPart"`         // 身体部位：胸、背、腿、肩、臂等
+	ImageVariants    []ImageVariant `json:"imageVariants,omitempty"` // 上传时生成的缩放版本，由服务端根据 imageUrl 填写
+	Clips            []Clip    `json:"clips,omitempty"`  // 演示视频或动图
+	BodyPart         string    `json:"bodyPart"`         // 身体部位：胸、背、腿、肩、臂等，旧版本的自由文本，已由肌群代替
+	PrimaryMuscles   []string  `json:"primaryMuscles"`             // 主要肌群ID，null 表示尚未分类（按 bodyPart 迁移），[] 表示明确不设置
+	SecondaryMuscles []string  `json:"secondaryMuscles,omitempty"` // 次要肌群ID
+	Equipment        []string  `json:"equipment,omitempty"`        // 器械ID
+	MovementPattern  string    `json:"movementPattern,omitempty"`  // 动作模式ID：推、拉、髋铰链、蹲、负重行走等
+	Difficulty       string    `json:"difficulty,omitempty"`       // 难度ID
+	Tracking         string    `json:"tracking,omitempty"`         // 记录方式，未设置时为次数+重量
 	CaloriesPerRep   float64   `json:"caloriesPerRep"`   // 每次消耗卡路里
 	CaloriesPerMinute float64  `json:"caloriesPerMinute"` // 每分钟消耗卡路里
 	CreatedAt        time.Time `json:"createdAt"`
 }
 
-// ExerciseSet 组模型
+// 动作的记录方式，决定训练计划和训练记录中每组使用的字段
+const (
+	TrackReps         = "reps"          // 次数，例如俯卧撑
+	TrackRepsWeight   = "reps_weight"   // 次数+重量，例如卧推
+	TrackTime         = "time"          // 时长，例如平板支撑
+	TrackDistance     = "distance"      // 距离
+	TrackTimeDistance = "time_distance" // 时长+距离，例如划船机、跑步机间歇
+)
+
+// ExerciseSet 训练计划中的一个动作，Targets 为每组的目标；
+// Sets/Reps/Weight/Unit/Duration/Distance/RestTime 是兼容旧客户端的摘要，由服务端根据 Targets 填写
 type ExerciseSet struct {
-	ExerciseID string `json:"exerciseId"`
-	Sets       int    `json:"sets"`       // 组数
-	Reps       int    `json:"reps"`       // 每组次数
-	Weight     int    `json:"weight"`     // 重量
-	RestTime   int    `json:"restTime"`   // 组间休息时间(秒)
+	ExerciseID string      `json:"exerciseId"`
+	Sets       int         `json:"sets"`       // 组数
+	Reps       int         `json:"reps"`       // 每组次数
+	Weight     float64     `json:"weight"`     // 重量
+	Unit       string      `json:"unit,omitempty"` // 重量单位 kg/lb
+	Duration   int         `json:"duration,omitempty"` // 每组时长(秒)
+	Distance   float64     `json:"distance,omitempty"` // 每组距离(米)
+	RestTime   int         `json:"restTime"`   // 组间休息时间(秒)
+	Targets    []SetTarget `json:"targets,omitempty"` // 每组的目标
+	Block      string      `json:"block,omitempty"`   // 所属训练块ID，为空时单独按常规组进行
+	Progression *ProgressionRule `json:"progression,omitempty"` // 渐进超负荷规则，为空时不给出建议
+}
+
+// 渐进超负荷规则类型
+const (
+	ProgressionLinear = "linear" // 完成所有目标次数后加重
+	ProgressionDouble = "double" // 先在次数范围内增加次数，所有组达到上限后加重
+	ProgressionRPE    = "rpe"    // 按记录的 RPE 与目标 RPE 的差距调整重量
+)
+
+// ProgressionRule 一个动作的渐进超负荷规则
+type ProgressionRule struct {
+	Type          string  `json:"type"`
+	Increment     float64 `json:"increment,omitempty"`     // 每次加减的重量，单位与目标相同，默认 kg 为 2.5，lb 为 5
+	Sessions      int     `json:"sessions,omitempty"`      // 连续多少次训练达标后加重，默认 1
+	DeloadAfter   int     `json:"deloadAfter,omitempty"`   // 连续多少次训练未完成后减重，默认 3
+	DeloadPercent float64 `json:"deloadPercent,omitempty"` // 减重的比例(%)，默认 10
+	TargetRPE     float64 `json:"targetRpe,omitempty"`     // rpe 规则的目标，未设置时使用正式组目标的 rpe，都没有时为 8
+}
+
+// 组的类型
+const (
+	SetWarmup  = "warmup"  // 热身组
+	SetWorking = "working" // 正式组
+	SetDrop    = "drop"    // 递减组
+	SetAMRAP   = "amrap"   // 力竭组，尽可能多做
+)
+
+// 重量单位
+const (
+	UnitKg = "kg"
+	UnitLb = "lb"
+)
+
+// SetTarget 一组的目标
+type SetTarget struct {
+	Type     string  `json:"type"`              // warmup、working、drop、amrap
+	Reps     int     `json:"reps,omitempty"`    // 目标次数，次数范围的下限
+	RepsMax  int     `json:"repsMax,omitempty"` // 次数范围的上限，例如 8-12 次
+	Weight   float64 `json:"weight,omitempty"`  // 重量，可以是小数，例如 12.5
+	Unit     string  `json:"unit,omitempty"`    // kg（默认）或 lb
+	Duration int     `json:"duration,omitempty"` // 时长(秒)
+	Distance float64 `json:"distance,omitempty"` // 距离(米)
+	RPE      float64 `json:"rpe,omitempty"`      // 目标自觉用力程度，1-10
+	Tempo    string  `json:"tempo,omitempty"`    // 节奏：离心-底部停顿-向心-顶部停顿(秒)，例如 3-1-X-0
+	RestTime int     `json:"restTime,omitempty"` // 本组后的休息时间(秒)
+}
+
+// 训练块的类型
+const (
+	BlockStraight = "straight" // 常规：完成一个动作的所有组后进行下一个动作
+	BlockSuperset = "superset" // 超级组：交替进行两个或多个动作（A1/A2），每轮后休息
+	BlockCircuit  = "circuit"  // 循环：依次完成所有动作为一轮，每轮后休息
+	BlockEMOM     = "emom"     // 每个间隔（默认每分钟）开始一轮，剩余时间休息
+	BlockAMRAP    = "amrap"    // 限定时间内完成尽可能多的轮数
+)
+
+// WorkoutBlock 训练块，训练计划中连续的几个动作通过 ExerciseSet.Block 引用同一个训练块。
+// 超级组、循环和 EMOM 的第 i 轮使用每个动作的第 i 组目标，AMRAP 每轮使用第一组目标
+type WorkoutBlock struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name,omitempty"`      // 显示名称，例如 "A"
+	Rounds    int    `json:"rounds,omitempty"`    // 轮数，AMRAP 不限轮数
+	RoundRest int    `json:"roundRest,omitempty"` // 每轮之后的休息时间(秒)
+	Interval  int    `json:"interval,omitempty"`  // EMOM 每轮的时间(秒)
+	TimeCap   int    `json:"timeCap,omitempty"`   // AMRAP 的限定时间(秒)
 }
 
 // Workout 训练计划模型
@@ -32,9 +122,18 @@ type Workout struct {
 	Description string        `json:"description"`
 	BodyPart    string        `json:"bodyPart"`
 	Exercises   []ExerciseSet `json:"exercises"`
+	Blocks      []WorkoutBlock `json:"blocks,omitempty"` // 训练块，按在 Exercises 中出现的顺序排列
+	Schedule    *WorkoutSchedule `json:"schedule,omitempty"` // 每周固定的训练时间
 	CreatedAt   time.Time     `json:"createdAt"`
 }
 
+// WorkoutSchedule 训练计划的每周安排
+type WorkoutSchedule struct {
+	Weekdays []int  `json:"weekdays"` // 0=周日 1=周一 ... 6=周六
+	Time     string `json:"time"`     // 开始时间 HH:MM
+	Duration int    `json:"duration"` // 预计时长(分钟)
+}
+
 // WorkoutSession 训练记录模型
 type WorkoutSession struct {
 	ID              string              `json:"id"`
@@ -45,20 +144,95 @@ type WorkoutSession struct {
 	TotalTime       int                 `json:"totalTime"`       // 总时间(秒)
 	TotalCalories   float64             `json:"totalCalories"`   // 总消耗卡路里
 	Exercises       []CompletedExercise `json:"exercises"`
+	Blocks          []CompletedBlock    `json:"blocks,omitempty"` // 各训练块完成的轮数
+	Recommendations []Recommendation    `json:"recommendations,omitempty"` // 按训练计划创建时给出的本次目标建议
 	Notes           string              `json:"notes"`
 	IsCompleted     bool                `json:"isCompleted"`
 }
 
-// CompletedExercise 完成的动作记录
+// CompletedBlock 训练块的完成记录
+type CompletedBlock struct {
+	BlockID         string `json:"blockId"`
+	CompletedRounds int    `json:"completedRounds"`
+	Duration        int    `json:"duration,omitempty"` // 用时(秒)
+	IsCompleted     bool   `json:"isCompleted"`
+}
+
+// CompletedExercise 完成的动作记录，训练块中的动作第 i 组对应第 i 轮
 type CompletedExercise struct {
 	ExerciseID       string  `json:"exerciseId"`
+	Block            string  `json:"block,omitempty"` // 所属训练块ID
 	CompletedSets    int     `json:"completedSets"`
 	CompletedReps    []int   `json:"completedReps"`    // 每组实际完成次数
+	CompletedWeights []float64 `json:"completedWeights,omitempty"` // 每组实际重量(公斤)
+	CompletedDurations []int   `json:"completedDurations,omitempty"` // 每组实际时长(秒)
+	CompletedDistances []float64 `json:"completedDistances,omitempty"` // 每组实际距离(米)
+	SetLogs          []SetLog `json:"setLogs,omitempty"` // 每组的用力程度、节奏和备注，与组一一对应
 	ActualRestTimes  []int   `json:"actualRestTimes"`  // 每组实际休息时间
 	CaloriesBurned   float64 `json:"caloriesBurned"`   // 该动作消耗的卡路里
 	IsCompleted      bool    `json:"isCompleted"`
 }
 
+// SetLog 一组的主观记录，都是可选的
+type SetLog struct {
+	RPE       float64 `json:"rpe,omitempty"`       // 自觉用力程度，1-10，可以是 0.5 的倍数
+	RIR       *int    `json:"rir,omitempty"`       // 保留次数(Reps In Reserve)，0 表示力竭
+	Tempo     string  `json:"tempo,omitempty"`     // 实际节奏，格式同 SetTarget.Tempo
+	Pain      bool    `json:"pain,omitempty"`      // 出现疼痛
+	FormIssue bool    `json:"formIssue,omitempty"` // 动作变形
+	Note      string  `json:"note,omitempty"`
+}
+
+// 个人记录类型
+const (
+	RecordWeight = "weight" // 最大重量
+	RecordReps   = "reps"   // 某一重量下的最多次数
+	RecordE1RM   = "e1rm"   // 最大估算一次最大重量(1RM)
+	RecordVolume = "volume" // 单次训练中该动作的最大训练量
+)
+
+// 估算 1RM 的公式
+const (
+	FormulaEpley    = "epley"
+	FormulaBrzycki  = "brzycki"
+	FormulaLombardi = "lombardi"
+)
+
+// PersonalRecord 个人记录，训练完成时与之前的训练比较产生
+type PersonalRecord struct {
+	ID         string    `json:"id"`
+	ExerciseID string    `json:"exerciseId"`
+	Type       string    `json:"type"`
+	Value      float64   `json:"value"`              // weight 和 e1rm 为公斤，reps 为次数，volume 为次数 x 公斤
+	Previous   float64   `json:"previous"`           // 被打破的记录，第一次记录时为 0
+	Weight     float64   `json:"weight"`             // 创造记录的一组的重量(公斤)，volume 记录为 0
+	Reps       int       `json:"reps"`               // 创造记录的一组的次数，volume 记录为总次数
+	Formula    string    `json:"formula,omitempty"`  // e1rm 记录使用的公式
+	SessionID  string    `json:"sessionId"`
+	Block      string    `json:"block,omitempty"`
+	SetNumber  int       `json:"setNumber,omitempty"` // 第几组，从 1 开始，volume 记录为 0
+	AchievedAt time.Time `json:"achievedAt"`
+}
+
+// 建议的调整
+const (
+	ActionIncrease = "increase" // 加重
+	ActionHold     = "hold"     // 保持重量
+	ActionDecrease = "decrease" // 减重
+	ActionDeload   = "deload"   // 按比例减重
+)
+
+// Recommendation 按渐进超负荷规则给出的下次训练目标
+type Recommendation struct {
+	ExerciseID string      `json:"exerciseId"`
+	Block      string      `json:"block,omitempty"`
+	Rule       string      `json:"rule"`
+	Action     string      `json:"action"`
+	Targets    []SetTarget `json:"targets"`
+	Reason     string      `json:"reason"`
+	BasedOn    []string    `json:"basedOn"` // 参考的训练记录ID，最近的在前
+}
+
 // Statistics 统计数据模型
 type Statistics struct {
 	Date           time.Time          `json:"date"`
@@ -68,3 +242,185 @@ type Statistics struct {
 	BodyParts      map[string]int     `json:"bodyParts"`        // 各部位训练次数
 	Exercises      map[string]int     `json:"exercises"`        // 各动作训练次数
 }
+
+// IdempotencyRecord 幂等请求记录，用于重放首次请求的响应
+type IdempotencyRecord struct {
+	Key         string    `json:"key"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	RequestHash string    `json:"requestHash"`  // 请求体哈希，防止同一个Key复用于不同请求
+	StatusCode  int       `json:"statusCode"`
+	ContentType string    `json:"contentType"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// 可同步的实体类型
+const (
+	EntityExercise = "exercise"
+	EntityWorkout  = "workout"
+	EntitySession  = "session"
+)
+
+// 变更类型
+const (
+	ChangeUpsert = "upsert"
+	ChangeDelete = "delete"
+)
+
+// ChangeRecord 数据变更记录，用于增量同步
+type ChangeRecord struct {
+	Seq       int64     `json:"seq"`
+	Entity    string    `json:"entity"`
+	EntityID  string    `json:"entityId"`
+	Op        string    `json:"op"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// ChangeLog 变更日志，每个实体只保留最后一次变更
+type ChangeLog struct {
+	LastSeq     int64                           `json:"lastSeq"`
+	Changes     []ChangeRecord                  `json:"changes"`
+	FieldClocks map[string]map[string]time.Time `json:"fieldClocks"` // 实体 -> 字段 -> 最后修改时间
+	Mutations   map[string]time.Time            `json:"mutations"`   // 已处理的客户端变更ID
+}
+
+// WebhookSubscription Webhook 订阅
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`           // 订阅的事件类型，"*" 表示全部
+	Secret    string    `json:"secret,omitempty"` // 用于 HMAC 签名
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Webhook 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead" // 重试次数用尽，进入死信列表
+)
+
+// WebhookDelivery Webhook 投递记录
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscriptionId"`
+	EventID        string     `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastStatusCode int        `json:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// 外部训练记录导入状态
+const (
+	HistoryImportPending   = "pending"   // 等待确认动作映射
+	HistoryImportCommitted = "committed" // 已写入训练记录
+)
+
+// HistoryImportRow 从其他应用导出文件中解析出的一组
+type HistoryImportRow struct {
+	WorkoutName  string    `json:"workoutName"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Exercise     string    `json:"exercise"` // 来源应用中的动作名称
+	SetIndex     int       `json:"setIndex"` // 热身组等不编号的组为 0
+	SetType      string    `json:"setType,omitempty"` // 组类型，同 SetTarget.Type
+	Weight       float64   `json:"weight"` // 公斤
+	Reps         int       `json:"reps"`
+	Seconds      int       `json:"seconds"`
+	Distance     float64   `json:"distance,omitempty"` // 米
+	RPE          float64   `json:"rpe,omitempty"`
+	Notes        string    `json:"notes,omitempty"` // 这一组的备注
+	WorkoutNotes string    `json:"workoutNotes"`
+}
+
+// HistoryImport 待确认的外部训练记录导入
+type HistoryImport struct {
+	ID          string             `json:"id"`
+	Source      string             `json:"source"` // strong、hevy
+	FileName    string             `json:"fileName"`
+	Status      string             `json:"status"`
+	Rows        []HistoryImportRow `json:"rows"`
+	SkippedRows int                `json:"skippedRows"` // 计时器等未导入的行
+	CreatedAt   time.Time          `json:"createdAt"`
+	CommittedAt *time.Time         `json:"committedAt,omitempty"`
+}
+
+// CalendarFeed 日历订阅，每个人使用自己的令牌订阅，可单独撤销
+type CalendarFeed struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	TokenHash      string     `json:"tokenHash,omitempty"` // 只保存令牌的 SHA-256，接口中不返回
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty"`
+}
+
+// ImageVariant 图片的一个缩放版本
+type ImageVariant struct {
+	URL    string `json:"url"`
+	Type   string `json:"type"` // MIME 类型，image/webp 或与原图相同
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int    `json:"size"`
+}
+
+// Clip 动作的演示视频或动图，客户端只需提供 url，其余字段由服务端根据上传记录填写
+type Clip struct {
+	URL      string  `json:"url"`
+	Type     string  `json:"type,omitempty"`
+	Poster   string  `json:"poster,omitempty"` // 封面图地址
+	Width    int     `json:"width,omitempty"`
+	Height   int     `json:"height,omitempty"`
+	Duration float64 `json:"duration,omitempty"` // 秒
+	Size     int     `json:"size,omitempty"`
+}
+
+// Media 上传并处理过的图片、视频和动图
+type Media struct {
+	URL       string         `json:"url"`
+	Hash      string         `json:"hash,omitempty"` // 上传内容的 SHA-256，相同文件再次上传时直接复用
+	Type      string         `json:"type"`
+	Width     int            `json:"width"`
+	Height    int            `json:"height"`
+	Size      int            `json:"size"`
+	Variants  []ImageVariant `json:"variants"`
+	Duration  float64        `json:"duration,omitempty"` // 视频和动图的时长，秒
+	Codecs    []string       `json:"codecs,omitempty"`   // 视频和音频编码，例如 avc1、mp4a
+	Poster    string         `json:"poster,omitempty"`   // 视频和动图的封面图地址
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// UploadSession 分片上传会话，客户端按 offset 继续上传剩余部分
+type UploadSession struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`            // 文件总大小
+	Offset    int64     `json:"offset"`          // 已接收的字节数
+	Media     *Media    `json:"media,omitempty"` // 上传完成后的文件
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// 动作分类的类型
+const (
+	TermMuscle     = "muscle"     // 肌群
+	TermEquipment  = "equipment"  // 器械
+	TermPattern    = "pattern"    // 动作模式
+	TermDifficulty = "difficulty" // 难度
+)
+
+// TaxonomyTerm 动作分类中的一项，动作通过 ID 引用
+type TaxonomyTerm struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases,omitempty"` // 其他写法，迁移旧的 bodyPart 时用于匹配
+	CreatedAt time.Time `json:"createdAt"`
+}
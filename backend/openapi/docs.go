@@ -0,0 +1,8 @@
+package openapi
+
+import _ "embed"
+
+// DocsHTML 内置的接口文档页面，从 /api/openapi.json 加载文档后渲染
+//
+//go:embed docs.html
+var DocsHTML []byte
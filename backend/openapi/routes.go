@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"workout-tracker/archive"
+	"workout-tracker/calendar"
+	"workout-tracker/delta"
+	"workout-tracker/events"
+	"workout-tracker/health"
+	"workout-tracker/history"
+	"workout-tracker/models"
+	"workout-tracker/patch"
+	"workout-tracker/presenter"
+	"workout-tracker/records"
+	"workout-tracker/taxonomy"
+	"workout-tracker/uploads"
+	"workout-tracker/webhooks"
+)
+
+var mergePatchTypes = []string{"application/merge-patch+json", "application/json"}
+
+var jsonPatchRequest = map[string]interface{}{"application/json-patch+json": []patch.Operation{}}
+
+func historyColumns() string {
+	keys := make([]string, len(history.Columns))
+	for i, column := range history.Columns {
+		keys[i] = column.Key
+	}
+	return strings.Join(keys, ",")
+}
+
+var idempotencyHeader = []Parameter{
+	{Name: "Idempotency-Key", Description: "幂等键，重试时重放首次请求的响应"},
+}
+
+var contentRangeHeader = []Parameter{
+	{Name: "Content-Range", Description: "分片在文件中的位置，例如 bytes 0-8388607/52428800", Required: true},
+}
+
+var uploadRequest = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"file": {Type: "string", Format: "binary"},
+	},
+}
+
+// Routes 所有对外提供的路由，新增路由时需要同步补充
+var Routes = []Route{
+	// 动作相关
+	{Method: http.MethodGet, Path: "/api/exercises", Tag: "exercises", Summary: "获取所有动作", Response: []models.Exercise{}},
+	{Method: http.MethodPost, Path: "/api/exercises", Headers: idempotencyHeader, Tag: "exercises", Summary: "创建新动作", Request: models.Exercise{}, Response: models.Exercise{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/exercises/:id", Tag: "exercises", Summary: "更新动作", Request: models.Exercise{}, Response: models.Exercise{}},
+	{Method: http.MethodPatch, Path: "/api/exercises/:id", Tag: "exercises", Summary: "部分更新动作", Request: models.Exercise{}, RequestTypes: mergePatchTypes, AltRequests: jsonPatchRequest, Response: models.Exercise{}},
+	{Method: http.MethodDelete, Path: "/api/exercises/:id", Tag: "exercises", Summary: "删除动作", Response: MessageResponse{}},
+	{Method: http.MethodGet, Path: "/api/exercises/:id/records", Tag: "exercises", Summary: "动作的个人记录：最大重量、每个重量的最多次数、最大估算 1RM 和单次训练最大训练量，以及全部历史", Response: records.Summary{}, Query: []Parameter{
+		{Name: "formula", Description: "1RM 估算公式，默认由 E1RM_FORMULA 设置（epley）", Schema: &Schema{Type: "string", Enum: records.Formulas}},
+	}},
+
+	// 动作分类
+	{Method: http.MethodGet, Path: "/api/taxonomy", Tag: "taxonomy", Summary: "按类型分组获取全部分类（muscle、equipment、pattern、difficulty）", Response: map[string][]models.TaxonomyTerm{}},
+	{Method: http.MethodPost, Path: "/api/taxonomy/migrate", Tag: "taxonomy", Summary: "按 bodyPart 为还没有分类的动作填写主要肌群", Response: taxonomy.MigrationReport{}, Query: []Parameter{
+		{Name: "dryRun", Description: "为 true 时只返回迁移结果，不修改动作", Schema: &Schema{Type: "boolean"}},
+	}},
+	{Method: http.MethodGet, Path: "/api/taxonomy/:kind", Tag: "taxonomy", Summary: "获取一种类型的分类", Response: []models.TaxonomyTerm{}},
+	{Method: http.MethodPost, Path: "/api/taxonomy/:kind", Tag: "taxonomy", Summary: "创建分类，同类型中名称不能重复", Request: taxonomy.TermRequest{}, Response: models.TaxonomyTerm{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/taxonomy/:kind/:id", Tag: "taxonomy", Summary: "修改分类的名称和别名", Request: taxonomy.TermRequest{}, Response: models.TaxonomyTerm{}},
+	{Method: http.MethodDelete, Path: "/api/taxonomy/:kind/:id", Tag: "taxonomy", Summary: "删除分类，仍被动作引用时返回 409", Response: MessageResponse{}},
+
+	// 训练计划相关
+	{Method: http.MethodGet, Path: "/api/workouts", Tag: "workouts", Summary: "获取所有训练计划", Response: []models.Workout{}},
+	{Method: http.MethodPost, Path: "/api/workouts", Headers: idempotencyHeader, Tag: "workouts", Summary: "创建新训练计划", Request: models.Workout{}, Response: models.Workout{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/workouts/:id", Tag: "workouts", Summary: "获取特定训练计划", Response: models.Workout{}},
+	{Method: http.MethodGet, Path: "/api/workouts/:id/recommendations", Tag: "workouts", Summary: "预览按计划开始下一次训练时各动作的渐进超负荷建议和理由", Response: []models.Recommendation{}},
+	{Method: http.MethodPut, Path: "/api/workouts/:id", Tag: "workouts", Summary: "更新训练计划", Request: models.Workout{}, Response: models.Workout{}},
+	{Method: http.MethodPatch, Path: "/api/workouts/:id", Tag: "workouts", Summary: "部分更新训练计划", Request: models.Workout{}, RequestTypes: mergePatchTypes, AltRequests: jsonPatchRequest, Response: models.Workout{}},
+	{Method: http.MethodDelete, Path: "/api/workouts/:id", Tag: "workouts", Summary: "删除训练计划", Response: MessageResponse{}},
+
+	// 训练记录相关
+	{Method: http.MethodGet, Path: "/api/sessions", Tag: "sessions", Summary: "获取训练记录", Response: []models.WorkoutSession{}, Query: []Parameter{
+		{Name: "start", Description: "开始日期 (YYYY-MM-DD)", Schema: &Schema{Type: "string", Format: "date"}},
+		{Name: "end", Description: "结束日期 (YYYY-MM-DD)", Schema: &Schema{Type: "string", Format: "date"}},
+	}},
+	{Method: http.MethodPost, Path: "/api/sessions", Headers: idempotencyHeader, Tag: "sessions", Summary: "创建新训练记录", Request: models.WorkoutSession{}, Response: presenter.SessionResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/sessions/:id", Tag: "sessions", Summary: "获取特定训练记录及这次训练产生的个人记录", Response: presenter.SessionResponse{}},
+	{Method: http.MethodPut, Path: "/api/sessions/:id", Headers: idempotencyHeader, Tag: "sessions", Summary: "更新训练记录，标记完成时检测个人记录", Request: models.WorkoutSession{}, Response: presenter.SessionResponse{}},
+	{Method: http.MethodPatch, Path: "/api/sessions/:id", Headers: idempotencyHeader, Tag: "sessions", Summary: "部分更新训练记录，标记完成时检测个人记录", Request: models.WorkoutSession{}, RequestTypes: mergePatchTypes, AltRequests: jsonPatchRequest, Response: presenter.SessionResponse{}},
+
+	{Method: http.MethodGet, Path: "/api/sessions/stream", Tag: "sessions", Summary: "训练开始/结束事件流 (SSE)", Response: &Schema{Type: "string"}, ResponseType: "text/event-stream"},
+	{Method: http.MethodGet, Path: "/api/sessions/export", Tag: "sessions", Summary: "按组导出训练历史 (CSV/XLSX)", Response: &Schema{Type: "string", Format: "binary"}, ResponseType: "text/csv", Query: []Parameter{
+		{Name: "format", Description: "csv（默认）或 xlsx", Schema: &Schema{Type: "string", Enum: []string{"csv", "xlsx"}}},
+		{Name: "from", Description: "开始日期 YYYY-MM-DD（包含）", Schema: &Schema{Type: "string", Format: "date"}},
+		{Name: "to", Description: "结束日期 YYYY-MM-DD（包含）", Schema: &Schema{Type: "string", Format: "date"}},
+		{Name: "columns", Description: "逗号分隔的列名及顺序：" + historyColumns(), Schema: &Schema{Type: "string"}},
+	}},
+	{Method: http.MethodGet, Path: "/api/sessions/:id/stream", Tag: "sessions", Summary: "训练记录实时事件流 (SSE)", Response: &Schema{Type: "string"}, ResponseType: "text/event-stream"},
+	{Method: http.MethodPost, Path: "/api/sessions/:id/events", Tag: "sessions", Summary: "上报休息开始/结束事件", Request: &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type": {Type: "string", Enum: []string{"rest.started", "rest.ended"}},
+			"data": {Type: "object"},
+		},
+	}, Response: events.Event{}, Status: http.StatusAccepted},
+
+	// 离线同步
+	{Method: http.MethodPost, Path: "/api/sync", Tag: "sync", Summary: "提交离线变更并获取服务端增量", Request: delta.Request{}, Response: delta.Response{}},
+
+	// Webhook
+	{Method: http.MethodGet, Path: "/api/webhooks", Tag: "webhooks", Summary: "获取所有 Webhook 订阅", Response: []models.WebhookSubscription{}},
+	{Method: http.MethodPost, Path: "/api/webhooks", Tag: "webhooks", Summary: "创建 Webhook 订阅（仅此时返回签名密钥）", Request: webhooks.SubscriptionRequest{}, Response: models.WebhookSubscription{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/webhooks/deliveries", Tag: "webhooks", Summary: "获取投递记录，status=dead 查看死信列表", Response: []models.WebhookDelivery{}, Query: []Parameter{
+		{Name: "status", Description: "pending / succeeded / dead", Schema: &Schema{Type: "string", Enum: []string{models.DeliveryPending, models.DeliverySucceeded, models.DeliveryDead}}},
+	}},
+	{Method: http.MethodPost, Path: "/api/webhooks/deliveries/:id/retry", Tag: "webhooks", Summary: "重新投递死信", Response: models.WebhookDelivery{}, Status: http.StatusAccepted},
+	{Method: http.MethodGet, Path: "/api/webhooks/:id", Tag: "webhooks", Summary: "获取 Webhook 订阅", Response: models.WebhookSubscription{}},
+	{Method: http.MethodPut, Path: "/api/webhooks/:id", Tag: "webhooks", Summary: "更新 Webhook 订阅", Request: webhooks.SubscriptionRequest{}, Response: models.WebhookSubscription{}},
+	{Method: http.MethodDelete, Path: "/api/webhooks/:id", Tag: "webhooks", Summary: "删除 Webhook 订阅", Response: MessageResponse{}},
+	{Method: http.MethodGet, Path: "/api/webhooks/:id/deliveries", Tag: "webhooks", Summary: "Webhook 投递日志", Response: []models.WebhookDelivery{}},
+
+	// 导出与导入
+	{Method: http.MethodGet, Path: "/api/export", Tag: "archive", Summary: "导出全部数据和上传文件 (zip)", Response: &Schema{Type: "string", Format: "binary"}, ResponseType: "application/zip"},
+	{Method: http.MethodPost, Path: "/api/import", Tag: "archive", Summary: "导入 zip 导出包", Request: uploadRequest, RequestTypes: []string{"multipart/form-data"}, Response: archive.ImportReport{}, Query: []Parameter{
+		{Name: "mode", Description: "merge（默认）或 replace", Schema: &Schema{Type: "string", Enum: []string{archive.ModeMerge, archive.ModeReplace}}},
+		{Name: "dryRun", Description: "为 true 时只返回导入报告，不写入数据", Schema: &Schema{Type: "boolean"}},
+	}},
+
+	{Method: http.MethodPost, Path: "/api/history/imports", Tag: "archive", Summary: "上传 Strong/Hevy 导出的 CSV 并预览", Request: uploadRequest, RequestTypes: []string{"multipart/form-data"}, Response: history.Preview{}, Status: http.StatusCreated, Query: []Parameter{
+		{Name: "source", Description: "auto（默认，根据表头识别）、strong 或 hevy", Schema: &Schema{Type: "string", Enum: []string{history.SourceAuto, history.SourceStrong, history.SourceHevy}}},
+		{Name: "weightUnit", Description: "Strong 导出的重量单位，kg（默认）或 lb", Schema: &Schema{Type: "string", Enum: []string{history.UnitKg, history.UnitLb}}},
+	}},
+	{Method: http.MethodGet, Path: "/api/history/imports/:id", Tag: "archive", Summary: "获取导入预览", Response: history.Preview{}},
+	{Method: http.MethodPost, Path: "/api/history/imports/:id/commit", Tag: "archive", Summary: "确认动作映射并写入训练记录", Request: history.Confirmation{}, Response: history.CommitResult{}},
+	{Method: http.MethodDelete, Path: "/api/history/imports/:id", Tag: "archive", Summary: "放弃导入", Response: MessageResponse{}},
+
+	// 日历订阅
+	{Method: http.MethodGet, Path: "/api/calendar/feeds", Tag: "calendar", Summary: "获取所有日历订阅", Response: []models.CalendarFeed{}},
+	{Method: http.MethodPost, Path: "/api/calendar/feeds", Tag: "calendar", Summary: "创建日历订阅，仅创建时返回令牌和订阅地址", Request: calendar.FeedRequest{}, Response: calendar.FeedResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/api/calendar/feeds/:id", Tag: "calendar", Summary: "撤销日历订阅", Response: MessageResponse{}},
+	{Method: http.MethodGet, Path: "/api/calendar/:token/feed.ics", Tag: "calendar", Summary: "iCalendar 订阅：每周训练安排和已完成的训练", Response: &Schema{Type: "string"}, ResponseType: "text/calendar"},
+
+	// 统计相关
+	{Method: http.MethodGet, Path: "/api/statistics", Tag: "statistics", Summary: "获取统计数据", Response: presenter.StatisticsResponse{}},
+	{Method: http.MethodGet, Path: "/api/statistics/rpe", Tag: "statistics", Summary: "每个动作的平均 RPE 走势，只记录了 RIR 的组按 10-RIR 换算", Response: []presenter.RPETrend{}, Query: []Parameter{
+		{Name: "exerciseId", Description: "只统计这个动作，不存在时返回 404", Schema: &Schema{Type: "string"}},
+		{Name: "period", Description: "week（默认，从周日开始）或 month", Schema: &Schema{Type: "string", Enum: []string{presenter.PeriodWeek, presenter.PeriodMonth}}},
+		{Name: "from", Description: "开始日期 YYYY-MM-DD（包含）", Schema: &Schema{Type: "string", Format: "date"}},
+		{Name: "to", Description: "结束日期 YYYY-MM-DD（包含）", Schema: &Schema{Type: "string", Format: "date"}},
+	}},
+
+	// 文件上传
+	{Method: http.MethodPost, Path: "/api/upload", Tag: "uploads", Summary: "上传图片或视频（JPG、PNG、GIF、WebP、MP4、WebM），按文件内容校验类型，超过大小上限返回 413，类型不支持返回 415；图片会去除 EXIF、按方向摆正并生成缩放版本；视频和动图会校验容器和编码（MP4: H.264/HEVC/AV1/VP9，WebM: VP8/VP9/AV1），编码不支持时返回 415，并生成封面图；文件按内容的 SHA-256 命名，相同内容只保存一份", Request: uploadRequest, RequestTypes: []string{"multipart/form-data"}, Response: models.Media{}},
+	{Method: http.MethodPost, Path: "/api/uploads/sessions", Tag: "uploads", Summary: "创建分片上传会话，用于大文件和断点续传", Request: uploads.SessionRequest{}, Response: models.UploadSession{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/uploads/sessions/:id", Tag: "uploads", Summary: "查询分片上传会话已接收的字节数", Response: models.UploadSession{}},
+	{Method: http.MethodPut, Path: "/api/uploads/sessions/:id", Tag: "uploads", Summary: "上传一个分片，必须从已接收的位置开始，否则返回 409 和当前 offset；最后一个分片写入后返回的 media 为上传结果", Headers: contentRangeHeader, Request: &Schema{Type: "string", Format: "binary"}, RequestTypes: []string{"application/octet-stream"}, Response: models.UploadSession{}},
+	{Method: http.MethodDelete, Path: "/api/uploads/sessions/:id", Tag: "uploads", Summary: "取消分片上传", Response: MessageResponse{}},
+	{Method: http.MethodPost, Path: "/api/uploads/gc", Tag: "uploads", Summary: "清理未被引用且超过保留期的上传文件", Response: uploads.SweepReport{}, Query: []Parameter{
+		{Name: "dryRun", Description: "为 true 时只返回将被清理的文件，不删除", Schema: &Schema{Type: "boolean"}},
+	}},
+	{Method: http.MethodPost, Path: "/api/uploads/migrate", Tag: "uploads", Summary: "把本地目录中原有的上传文件全部复制到对象存储，未使用对象存储时返回 409", Response: uploads.MigrationReport{}},
+
+	// 接口文档
+	{Method: http.MethodGet, Path: "/api/openapi.json", Tag: "docs", Summary: "OpenAPI 文档", Response: &Schema{Type: "object"}},
+	{Method: http.MethodGet, Path: "/api/docs", Tag: "docs", Summary: "接口文档页面", Response: &Schema{Type: "string"}, ResponseType: "text/html"},
+
+	// 监控
+	{Method: http.MethodGet, Path: "/metrics", Tag: "monitoring", Summary: "Prometheus 指标", Response: &Schema{Type: "string"}, ResponseType: "text/plain"},
+	{Method: http.MethodGet, Path: "/healthz", Tag: "monitoring", Summary: "存活检查", Response: health.Report{}},
+	{Method: http.MethodGet, Path: "/readyz", Tag: "monitoring", Summary: "就绪检查：数据目录可写、数据文件可解析、上传存储可用，未就绪时返回 503", Response: health.Report{}},
+
+	// 静态文件与页面
+	{Method: http.MethodGet, Path: "/uploads/*filepath", Tag: "pages", Summary: "已上传的文件，支持 Range 请求用于视频拖动进度；对象存储配置了预签名时返回 302 重定向到临时地址", Response: &Schema{Type: "string", Format: "binary"}, ResponseType: "application/octet-stream"},
+	{Method: http.MethodGet, Path: "/static/*filepath", Tag: "pages", Summary: "前端静态文件", Response: &Schema{Type: "string", Format: "binary"}, ResponseType: "application/octet-stream"},
+	{Method: http.MethodGet, Path: "/", Tag: "pages", Summary: "重定向到后台管理页面", Status: http.StatusFound},
+	{Method: http.MethodGet, Path: "/mobile", Tag: "pages", Summary: "重定向到移动端训练页面", Status: http.StatusFound},
+}
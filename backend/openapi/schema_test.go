@@ -0,0 +1,72 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"workout-tracker/taxonomy"
+	"workout-tracker/uploads"
+)
+
+func responseSchema(t *testing.T, doc *Document, path, method string) *Schema {
+	t.Helper()
+	op := doc.Paths[path][method]
+	if op == nil {
+		t.Fatalf("%s %s not documented", method, path)
+	}
+	return op.Responses["200"].Content["application/json"].Schema
+}
+
+func TestSameNamedTypesGetDistinctComponents(t *testing.T) {
+	doc := Build(Routes)
+	tests := []struct {
+		path string
+		typ  interface{}
+	}{
+		{"/api/taxonomy/migrate", taxonomy.MigrationReport{}},
+		{"/api/uploads/migrate", uploads.MigrationReport{}},
+	}
+	refs := make(map[string]bool)
+	for _, tt := range tests {
+		ref := responseSchema(t, doc, tt.path, "post").Ref
+		if refs[ref] {
+			t.Fatalf("%s reuses component %s", tt.path, ref)
+		}
+		refs[ref] = true
+
+		// 组件的字段与该路由实际返回的类型一致
+		component := doc.Components.Schemas[ref[len("#/components/schemas/"):]]
+		if component == nil {
+			t.Fatalf("%s: component %s missing", tt.path, ref)
+		}
+		want := newSchemaRegistry().structSchema(reflect.TypeOf(tt.typ))
+		if len(component.Properties) != len(want.Properties) {
+			t.Fatalf("%s: component %s has %d properties, want %d", tt.path, ref, len(component.Properties), len(want.Properties))
+		}
+		for name := range want.Properties {
+			if component.Properties[name] == nil {
+				t.Errorf("%s: component %s missing property %s", tt.path, ref, name)
+			}
+		}
+	}
+}
+
+func TestComponentName(t *testing.T) {
+	type MigrationReport struct{}
+	r := newSchemaRegistry()
+	tests := []struct {
+		typ  reflect.Type
+		want string
+	}{
+		{reflect.TypeOf(taxonomy.MigrationReport{}), "MigrationReport"},
+		{reflect.TypeOf(uploads.MigrationReport{}), "uploads.MigrationReport"},
+		{reflect.TypeOf(MigrationReport{}), "openapi.MigrationReport"},
+		// 同一类型再次查询得到相同的名字
+		{reflect.TypeOf(uploads.MigrationReport{}), "uploads.MigrationReport"},
+		{reflect.TypeOf(taxonomy.MigrationReport{}), "MigrationReport"},
+	}
+	for _, tt := range tests {
+		if got := r.componentName(tt.typ); got != tt.want {
+			t.Errorf("componentName(%v) = %q, want %q", tt.typ, got, tt.want)
+		}
+	}
+}
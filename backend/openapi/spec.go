@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Document OpenAPI 3 文档
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	OperationID string              `json:"operationId,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Route 描述一个注册在 gin 上的路由，用于生成文档
+type Route struct {
+	Method  string
+	Path    string // gin 风格路径，例如 /api/workouts/:id
+	Tag     string
+	Summary string
+	Query   []Parameter
+	Headers []Parameter
+
+	Request      interface{}            // 请求体类型的零值，nil 表示无请求体
+	RequestTypes []string               // 默认 application/json
+	AltRequests  map[string]interface{} // 其他 Content-Type 对应的请求体
+
+	Response     interface{} // 响应体类型的零值，nil 表示无响应体
+	ResponseType string      // 默认 application/json
+	Status       int         // 默认 200
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// MessageResponse 操作结果响应
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Build 根据路由表生成 OpenAPI 文档
+func Build(routes []Route) *Document {
+	registry := newSchemaRegistry()
+	errorSchema := registry.SchemaFor(ErrorResponse{})
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: "Workout Tracker API", Version: "1.0.0"},
+		Paths:   make(map[string]map[string]*Operation),
+	}
+
+	for _, route := range routes {
+		path, params := convertPath(route.Path)
+		op := &Operation{
+			Summary:     route.Summary,
+			OperationID: operationID(route.Method, route.Path),
+			Responses:   make(map[string]Response),
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+
+		op.Parameters = append(op.Parameters, params...)
+		for _, q := range route.Query {
+			q.In = "query"
+			if q.Schema == nil {
+				q.Schema = &Schema{Type: "string"}
+			}
+			op.Parameters = append(op.Parameters, q)
+		}
+		for _, h := range route.Headers {
+			h.In = "header"
+			if h.Schema == nil {
+				h.Schema = &Schema{Type: "string"}
+			}
+			op.Parameters = append(op.Parameters, h)
+		}
+
+		if route.Request != nil {
+			types := route.RequestTypes
+			if len(types) == 0 {
+				types = []string{"application/json"}
+			}
+			body := &RequestBody{Required: true, Content: make(map[string]MediaType)}
+			schema := registry.SchemaFor(route.Request)
+			for _, t := range types {
+				body.Content[t] = MediaType{Schema: schema}
+			}
+			for t, alt := range route.AltRequests {
+				body.Content[t] = MediaType{Schema: registry.SchemaFor(alt)}
+			}
+			op.RequestBody = body
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := Response{Description: http.StatusText(status)}
+		if route.Response != nil {
+			contentType := route.ResponseType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			resp.Content = map[string]MediaType{contentType: {Schema: registry.SchemaFor(route.Response)}}
+		}
+		op.Responses[fmt.Sprint(status)] = resp
+		if strings.HasPrefix(route.Path, "/api") {
+			op.Responses["default"] = Response{
+				Description: "Error",
+				Content:     map[string]MediaType{"application/json": {Schema: errorSchema}},
+			}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = registry.schemas
+	return doc
+}
+
+// Check 检查所有注册在 gin 上的路由都已写入文档
+func Check(doc *Document, registered gin.RoutesInfo) error {
+	var missing []string
+	for _, route := range registered {
+		// Static 会同时注册 HEAD，文档中只描述 GET
+		if route.Method == http.MethodHead {
+			continue
+		}
+		path, _ := convertPath(route.Path)
+		if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("routes missing from OpenAPI spec: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// convertPath 将 gin 路径参数 :id / *filepath 转换为 {id}，并生成路径参数
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		if segment == "" {
+			continue
+		}
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
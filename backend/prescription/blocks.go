@@ -0,0 +1,151 @@
+package prescription
+
+import (
+	"fmt"
+	"workout-tracker/models"
+)
+
+// EMOM 默认每分钟一轮
+const defaultInterval = 60
+
+// BlockTypeNames 训练块类型的显示名称
+var BlockTypeNames = map[string]string{
+	models.BlockStraight: "常规组",
+	models.BlockSuperset: "超级组",
+	models.BlockCircuit:  "循环",
+	models.BlockEMOM:     "EMOM",
+	models.BlockAMRAP:    "AMRAP",
+}
+
+// IsBlockType 是否为支持的训练块类型
+func IsBlockType(t string) bool {
+	switch t {
+	case models.BlockStraight, models.BlockSuperset, models.BlockCircuit, models.BlockEMOM, models.BlockAMRAP:
+		return true
+	}
+	return false
+}
+
+// 按轮进行的训练块，每个动作的第 i 组对应第 i 轮
+func roundBased(t string) bool {
+	return t == models.BlockSuperset || t == models.BlockCircuit || t == models.BlockEMOM
+}
+
+func validateBlock(block *models.WorkoutBlock) error {
+	if block.Type == "" {
+		block.Type = models.BlockStraight
+	}
+	switch {
+	case block.ID == "":
+		return fmt.Errorf("id must not be empty")
+	case !IsBlockType(block.Type):
+		return fmt.Errorf("unknown block type %q", block.Type)
+	case block.Rounds < 0 || block.RoundRest < 0 || block.Interval < 0 || block.TimeCap < 0:
+		return fmt.Errorf("rounds, roundRest, interval and timeCap must not be negative")
+	}
+
+	switch block.Type {
+	case models.BlockStraight:
+		block.Rounds, block.Interval, block.TimeCap = 0, 0, 0
+	case models.BlockEMOM:
+		if block.Interval == 0 {
+			block.Interval = defaultInterval
+		}
+		block.TimeCap = 0
+	case models.BlockAMRAP:
+		if block.TimeCap == 0 {
+			return fmt.Errorf("amrap block requires timeCap")
+		}
+		block.Rounds, block.Interval = 0, 0
+	default:
+		block.Interval, block.TimeCap = 0, 0
+	}
+	return nil
+}
+
+// 按轮进行的训练块中目标少于轮数的动作重复最后一组目标
+func fillRounds(block *models.WorkoutBlock, sets []*models.ExerciseSet) error {
+	if block.Rounds == 0 {
+		for _, set := range sets {
+			if len(set.Targets) > block.Rounds {
+				block.Rounds = len(set.Targets)
+			}
+		}
+	}
+	for _, set := range sets {
+		switch {
+		case len(set.Targets) == 0:
+			return fmt.Errorf("exercise %s has no targets", set.ExerciseID)
+		case len(set.Targets) > block.Rounds:
+			return fmt.Errorf("exercise %s has %d targets but the block has %d rounds", set.ExerciseID, len(set.Targets), block.Rounds)
+		}
+		for len(set.Targets) < block.Rounds {
+			set.Targets = append(set.Targets, set.Targets[len(set.Targets)-1])
+		}
+		summarize(set)
+	}
+	return nil
+}
+
+// 检查训练块：ID 唯一，被引用的训练块存在，同一训练块的动作连续且至少有一个，
+// 超级组至少两个动作。训练块按在 Exercises 中出现的顺序排列
+func normalizeBlocks(workout *models.Workout) error {
+	byID := make(map[string]*models.WorkoutBlock, len(workout.Blocks))
+	for i := range workout.Blocks {
+		block := &workout.Blocks[i]
+		if err := validateBlock(block); err != nil {
+			return fmt.Errorf("blocks[%d]: %v", i, err)
+		}
+		if _, ok := byID[block.ID]; ok {
+			return fmt.Errorf("blocks[%d]: duplicate block id %q", i, block.ID)
+		}
+		byID[block.ID] = block
+	}
+
+	var order []string
+	members := make(map[string][]*models.ExerciseSet)
+	previous := ""
+	for i := range workout.Exercises {
+		set := &workout.Exercises[i]
+		if set.Block == "" {
+			previous = ""
+			continue
+		}
+		if _, ok := byID[set.Block]; !ok {
+			return fmt.Errorf("exercises[%d]: unknown block %q", i, set.Block)
+		}
+		if set.Block != previous {
+			if _, seen := members[set.Block]; seen {
+				return fmt.Errorf("exercises[%d]: exercises of block %q must be consecutive", i, set.Block)
+			}
+			order = append(order, set.Block)
+		}
+		members[set.Block] = append(members[set.Block], set)
+		previous = set.Block
+	}
+
+	blocks := make([]models.WorkoutBlock, 0, len(order))
+	for _, id := range order {
+		block := byID[id]
+		sets := members[id]
+		if block.Type == models.BlockSuperset && len(sets) < 2 {
+			return fmt.Errorf("block %q: superset requires at least two exercises", id)
+		}
+		if roundBased(block.Type) {
+			if err := fillRounds(block, sets); err != nil {
+				return fmt.Errorf("block %q: %v", id, err)
+			}
+		}
+		blocks = append(blocks, *block)
+	}
+	for _, block := range workout.Blocks {
+		if _, ok := members[block.ID]; !ok {
+			return fmt.Errorf("block %q has no exercises", block.ID)
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = nil
+	}
+	workout.Blocks = blocks
+	return nil
+}
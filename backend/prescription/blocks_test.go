@@ -0,0 +1,126 @@
+package prescription
+
+import (
+	"reflect"
+	"testing"
+	"workout-tracker/models"
+)
+
+func TestNormalizeWorkoutBlocks(t *testing.T) {
+	target := func(reps int) models.SetTarget { return models.SetTarget{Reps: reps} }
+	tests := []struct {
+		name      string
+		blocks    []models.WorkoutBlock
+		exercises []models.ExerciseSet
+		rounds    []int // 规范化后每个动作的组数
+		want      []models.WorkoutBlock
+		err       string
+	}{
+		{
+			name:   "superset fills rounds with the last target",
+			blocks: []models.WorkoutBlock{{ID: "a", Type: models.BlockSuperset}},
+			exercises: []models.ExerciseSet{
+				{ExerciseID: "e1", Block: "a", Targets: []models.SetTarget{target(10), target(8), target(6)}},
+				{ExerciseID: "e2", Block: "a", Targets: []models.SetTarget{target(12)}},
+			},
+			rounds: []int{3, 3},
+			want:   []models.WorkoutBlock{{ID: "a", Type: models.BlockSuperset, Rounds: 3}},
+		},
+		{
+			name:      "emom gets the default interval",
+			blocks:    []models.WorkoutBlock{{ID: "a", Type: models.BlockEMOM, Rounds: 2, TimeCap: 300}},
+			exercises: []models.ExerciseSet{{ExerciseID: "e1", Block: "a", Targets: []models.SetTarget{target(5)}}},
+			rounds:    []int{2},
+			want:      []models.WorkoutBlock{{ID: "a", Type: models.BlockEMOM, Rounds: 2, Interval: defaultInterval}},
+		},
+		{
+			name: "blocks follow exercise order and type defaults to straight",
+			blocks: []models.WorkoutBlock{
+				{ID: "b", Type: models.BlockAMRAP, TimeCap: 600, Rounds: 4},
+				{ID: "a", Rounds: 3},
+			},
+			exercises: []models.ExerciseSet{
+				{ExerciseID: "e1", Block: "a", Targets: []models.SetTarget{target(5)}},
+				{ExerciseID: "e2"},
+				{ExerciseID: "e3", Block: "b", Targets: []models.SetTarget{target(10)}},
+			},
+			rounds: []int{1, 0, 1},
+			want: []models.WorkoutBlock{
+				{ID: "a", Type: models.BlockStraight},
+				{ID: "b", Type: models.BlockAMRAP, TimeCap: 600},
+			},
+		},
+		{
+			name:      "superset needs two exercises",
+			blocks:    []models.WorkoutBlock{{ID: "a", Type: models.BlockSuperset}},
+			exercises: []models.ExerciseSet{{ExerciseID: "e1", Block: "a", Targets: []models.SetTarget{target(5)}}},
+			err:       `block "a": superset requires at least two exercises`,
+		},
+		{
+			name:   "more targets than rounds",
+			blocks: []models.WorkoutBlock{{ID: "a", Type: models.BlockCircuit, Rounds: 1}},
+			exercises: []models.ExerciseSet{
+				{ExerciseID: "e1", Block: "a", Targets: []models.SetTarget{target(5), target(5)}},
+			},
+			err: `block "a": exercise e1 has 2 targets but the block has 1 rounds`,
+		},
+		{
+			name:   "block members must be consecutive",
+			blocks: []models.WorkoutBlock{{ID: "a"}},
+			exercises: []models.ExerciseSet{
+				{ExerciseID: "e1", Block: "a"},
+				{ExerciseID: "e2"},
+				{ExerciseID: "e3", Block: "a"},
+			},
+			err: `exercises[2]: exercises of block "a" must be consecutive`,
+		},
+		{
+			name:      "unknown block",
+			exercises: []models.ExerciseSet{{ExerciseID: "e1", Block: "x"}},
+			err:       `exercises[0]: unknown block "x"`,
+		},
+		{
+			name:   "unused block",
+			blocks: []models.WorkoutBlock{{ID: "a"}},
+			err:    `block "a" has no exercises`,
+		},
+		{
+			name:   "duplicate block id",
+			blocks: []models.WorkoutBlock{{ID: "a"}, {ID: "a"}},
+			err:    `blocks[1]: duplicate block id "a"`,
+		},
+		{
+			name:   "amrap without time cap",
+			blocks: []models.WorkoutBlock{{ID: "a", Type: models.BlockAMRAP}},
+			err:    "blocks[0]: amrap block requires timeCap",
+		},
+		{
+			name:      "exercise errors carry their index",
+			exercises: []models.ExerciseSet{{ExerciseID: "e1"}, {ExerciseID: "e2", Targets: []models.SetTarget{{Reps: -1}}}},
+			err:       "exercises[1].targets[0]: reps must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workout := models.Workout{Blocks: tt.blocks, Exercises: tt.exercises}
+			err := NormalizeWorkout(&workout, map[string]string{})
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, set := range workout.Exercises {
+				if len(set.Targets) != tt.rounds[i] || set.Sets != tt.rounds[i] {
+					t.Errorf("exercises[%d]: %d targets, sets %d, want %d", i, len(set.Targets), set.Sets, tt.rounds[i])
+				}
+			}
+			if !reflect.DeepEqual(workout.Blocks, tt.want) {
+				t.Errorf("blocks = %+v, want %+v", workout.Blocks, tt.want)
+			}
+		})
+	}
+}
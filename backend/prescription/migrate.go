@@ -0,0 +1,45 @@
+package prescription
+
+import (
+	"workout-tracker/logging"
+	"workout-tracker/repository"
+	"workout-tracker/tracking"
+)
+
+// Migrate 把旧格式的训练计划展开为每组的目标，返回修改的训练计划数量。
+// 数据无效的训练计划保持不变并记录警告，可以重复执行
+func Migrate(repo *repository.FileRepository) (int, error) {
+	log := logging.Component("prescription")
+	workouts, err := repo.GetAllWorkouts()
+	if err != nil {
+		return 0, err
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return 0, err
+	}
+	modes := tracking.ByExercise(exercises)
+
+	migrated := 0
+	for _, workout := range workouts {
+		legacy := false
+		for _, set := range workout.Exercises {
+			if Legacy(set) {
+				legacy = true
+				break
+			}
+		}
+		if !legacy {
+			continue
+		}
+		if err := NormalizeWorkout(&workout, modes); err != nil {
+			log.Warn("migrate workout sets failed", "workout_id", workout.ID, "error", err)
+			continue
+		}
+		if err := repo.SaveWorkout(workout); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,139 @@
+package prescription
+
+import (
+	"fmt"
+	"workout-tracker/effort"
+	"workout-tracker/models"
+	"workout-tracker/tracking"
+)
+
+// 1 磅对应的公斤数
+const kgPerLb = 0.45359237
+
+// Kilograms 把重量换算为公斤
+func Kilograms(weight float64, unit string) float64 {
+	if unit == models.UnitLb {
+		return weight * kgPerLb
+	}
+	return weight
+}
+
+// IsSetType 是否为支持的组类型
+func IsSetType(t string) bool {
+	switch t {
+	case models.SetWarmup, models.SetWorking, models.SetDrop, models.SetAMRAP:
+		return true
+	}
+	return false
+}
+
+// Legacy 是否为只有组数、次数、重量和休息时间的旧格式
+func Legacy(set models.ExerciseSet) bool {
+	return len(set.Targets) == 0 && set.Sets > 0
+}
+
+// 旧格式展开为相同的正式组
+func expand(set models.ExerciseSet) []models.SetTarget {
+	targets := make([]models.SetTarget, set.Sets)
+	for i := range targets {
+		targets[i] = models.SetTarget{
+			Type:     models.SetWorking,
+			Reps:     set.Reps,
+			Weight:   set.Weight,
+			Unit:     set.Unit,
+			Duration: set.Duration,
+			Distance: set.Distance,
+			RestTime: set.RestTime,
+		}
+	}
+	return targets
+}
+
+// 去掉记录方式不使用的字段
+func prune(target *models.SetTarget, fields tracking.Fields) {
+	if !fields.Reps {
+		target.Reps, target.RepsMax = 0, 0
+	}
+	if !fields.Weight {
+		target.Weight, target.Unit = 0, ""
+	}
+	if !fields.Duration {
+		target.Duration = 0
+	}
+	if !fields.Distance {
+		target.Distance = 0
+	}
+}
+
+func validate(target *models.SetTarget, fields tracking.Fields) error {
+	if target.Type == "" {
+		target.Type = models.SetWorking
+	}
+	prune(target, fields)
+	if fields.Weight && target.Unit == "" {
+		target.Unit = models.UnitKg
+	}
+	switch {
+	case !IsSetType(target.Type):
+		return fmt.Errorf("unknown set type %q", target.Type)
+	case fields.Weight && target.Unit != models.UnitKg && target.Unit != models.UnitLb:
+		return fmt.Errorf("unit must be kg or lb")
+	case target.Reps < 0 || target.RepsMax < 0:
+		return fmt.Errorf("reps must not be negative")
+	case target.RepsMax > 0 && target.RepsMax < target.Reps:
+		return fmt.Errorf("repsMax must not be less than reps")
+	case target.Weight < 0:
+		return fmt.Errorf("weight must not be negative")
+	case target.Duration < 0:
+		return fmt.Errorf("duration must not be negative")
+	case target.Distance < 0:
+		return fmt.Errorf("distance must not be negative")
+	case target.RestTime < 0:
+		return fmt.Errorf("restTime must not be negative")
+	}
+	return effort.ValidateTarget(target)
+}
+
+// 摘要取第一个正式组（没有时取第一组），热身组不计入
+func summarize(set *models.ExerciseSet) {
+	set.Sets = len(set.Targets)
+	if len(set.Targets) == 0 {
+		set.Reps, set.Weight, set.Unit, set.Duration, set.Distance, set.RestTime = 0, 0, "", 0, 0, 0
+		return
+	}
+	main := set.Targets[0]
+	for _, target := range set.Targets {
+		if target.Type != models.SetWarmup {
+			main = target
+			break
+		}
+	}
+	set.Reps, set.Weight, set.Unit, set.RestTime = main.Reps, main.Weight, main.Unit, main.RestTime
+	set.Duration, set.Distance = main.Duration, main.Distance
+}
+
+// Normalize 旧格式展开为每组的目标，按动作的记录方式去掉不使用的字段，检查每组的目标并更新摘要
+func Normalize(set *models.ExerciseSet, mode string) error {
+	if Legacy(*set) {
+		set.Targets = expand(*set)
+	}
+	fields := tracking.FieldsOf(mode)
+	for i := range set.Targets {
+		if err := validate(&set.Targets[i], fields); err != nil {
+			return fmt.Errorf("targets[%d]: %v", i, err)
+		}
+	}
+	summarize(set)
+	return validateProgression(set, fields)
+}
+
+// NormalizeWorkout 对训练计划中的每个动作调用 Normalize 并检查训练块，modes 为动作ID -> 记录方式
+func NormalizeWorkout(workout *models.Workout, modes map[string]string) error {
+	for i := range workout.Exercises {
+		set := &workout.Exercises[i]
+		if err := Normalize(set, modes[set.ExerciseID]); err != nil {
+			return fmt.Errorf("exercises[%d].%v", i, err)
+		}
+	}
+	return normalizeBlocks(workout)
+}
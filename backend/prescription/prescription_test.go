@@ -0,0 +1,160 @@
+package prescription
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     models.ExerciseSet
+		mode    string
+		targets []models.SetTarget
+		summary [3]float64 // 组数、次数、重量
+		err     string
+	}{
+		{
+			name: "legacy format expands to working sets",
+			set:  models.ExerciseSet{Sets: 3, Reps: 8, Weight: 60, RestTime: 90},
+			mode: models.TrackRepsWeight,
+			targets: []models.SetTarget{
+				{Type: models.SetWorking, Reps: 8, Weight: 60, Unit: models.UnitKg, RestTime: 90},
+				{Type: models.SetWorking, Reps: 8, Weight: 60, Unit: models.UnitKg, RestTime: 90},
+				{Type: models.SetWorking, Reps: 8, Weight: 60, Unit: models.UnitKg, RestTime: 90},
+			},
+			summary: [3]float64{3, 8, 60},
+		},
+		{
+			name: "summary skips warmups",
+			set: models.ExerciseSet{Targets: []models.SetTarget{
+				{Type: models.SetWarmup, Reps: 12, Weight: 20},
+				{Reps: 5, Weight: 100, Unit: models.UnitLb},
+			}},
+			mode: models.TrackRepsWeight,
+			targets: []models.SetTarget{
+				{Type: models.SetWarmup, Reps: 12, Weight: 20, Unit: models.UnitKg},
+				{Type: models.SetWorking, Reps: 5, Weight: 100, Unit: models.UnitLb},
+			},
+			summary: [3]float64{2, 5, 100},
+		},
+		{
+			name: "only warmups summarize the first set",
+			set:  models.ExerciseSet{Targets: []models.SetTarget{{Type: models.SetWarmup, Reps: 10, Weight: 20}}},
+			mode: models.TrackRepsWeight,
+			targets: []models.SetTarget{
+				{Type: models.SetWarmup, Reps: 10, Weight: 20, Unit: models.UnitKg},
+			},
+			summary: [3]float64{1, 10, 20},
+		},
+		{
+			name: "unused fields are dropped",
+			set:  models.ExerciseSet{Targets: []models.SetTarget{{Reps: 10, RepsMax: 12, Weight: 20, Duration: 60, Distance: 400}}},
+			mode: models.TrackTime,
+			targets: []models.SetTarget{
+				{Type: models.SetWorking, Duration: 60},
+			},
+			summary: [3]float64{1, 0, 0},
+		},
+		{
+			name: "tempo is normalized",
+			set:  models.ExerciseSet{Targets: []models.SetTarget{{Reps: 10, Tempo: "3-1-x-0"}}},
+			mode: models.TrackReps,
+			targets: []models.SetTarget{
+				{Type: models.SetWorking, Reps: 10, Tempo: "3-1-X-0"},
+			},
+			summary: [3]float64{1, 10, 0},
+		},
+		{name: "unknown set type", set: models.ExerciseSet{Targets: []models.SetTarget{{Type: "giant", Reps: 5}}}, err: `targets[0]: unknown set type "giant"`},
+		{name: "unknown unit", set: models.ExerciseSet{Targets: []models.SetTarget{{Reps: 5, Weight: 5, Unit: "stone"}}}, mode: models.TrackRepsWeight, err: "targets[0]: unit must be kg or lb"},
+		{name: "reps range reversed", set: models.ExerciseSet{Targets: []models.SetTarget{{Reps: 5}, {Reps: 10, RepsMax: 8}}}, err: "targets[1]: repsMax must not be less than reps"},
+		{name: "negative weight", set: models.ExerciseSet{Targets: []models.SetTarget{{Reps: 5, Weight: -1}}}, mode: models.TrackRepsWeight, err: "targets[0]: weight must not be negative"},
+		{name: "negative rest", set: models.ExerciseSet{Targets: []models.SetTarget{{Reps: 5, RestTime: -1}}}, err: "targets[0]: restTime must not be negative"},
+		{name: "rpe out of range", set: models.ExerciseSet{Targets: []models.SetTarget{{Reps: 5, RPE: 11}}}, err: "targets[0]: rpe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := tt.set
+			err := Normalize(&set, tt.mode)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(set.Targets, tt.targets) {
+				t.Errorf("targets = %+v, want %+v", set.Targets, tt.targets)
+			}
+			if got := [3]float64{float64(set.Sets), float64(set.Reps), set.Weight}; got != tt.summary {
+				t.Errorf("summary = %v, want %v", got, tt.summary)
+			}
+		})
+	}
+}
+
+func TestUnitConversion(t *testing.T) {
+	tests := []struct {
+		weight float64
+		unit   string
+		kg     float64
+		back   float64
+	}{
+		{100, models.UnitKg, 100, 100},
+		{100, models.UnitLb, 45.359237, 100},
+		{0, models.UnitLb, 0, 0},
+		{2.5, "", 2.5, 2.5},
+	}
+	for _, tt := range tests {
+		kg := Kilograms(tt.weight, tt.unit)
+		if kg != tt.kg {
+			t.Errorf("Kilograms(%v, %q) = %v, want %v", tt.weight, tt.unit, kg, tt.kg)
+		}
+		if back := FromKilograms(kg, tt.unit); back != tt.back {
+			t.Errorf("FromKilograms(%v, %q) = %v, want %v", kg, tt.unit, back, tt.back)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	for _, workout := range []models.Workout{
+		{ID: "legacy", Exercises: []models.ExerciseSet{{ExerciseID: "squat", Sets: 2, Reps: 5, Weight: 100}}},
+		{ID: "current", Exercises: []models.ExerciseSet{{ExerciseID: "squat", Targets: []models.SetTarget{{Type: models.SetWorking, Reps: 5, Weight: 100, Unit: models.UnitKg}}}}},
+		// 数据无效的训练计划保持不变
+		{ID: "invalid", Exercises: []models.ExerciseSet{{ExerciseID: "squat", Sets: 1, Reps: 5, Weight: 20, Unit: "stone"}}},
+	} {
+		if err := repo.SaveWorkout(workout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrated, err := Migrate(repo)
+	if err != nil || migrated != 1 {
+		t.Fatalf("migrated = %d, %v", migrated, err)
+	}
+	workout, err := repo.GetWorkoutByID("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targets := workout.Exercises[0].Targets; len(targets) != 2 || targets[1].Reps != 5 || targets[1].Weight != 100 {
+		t.Errorf("targets = %+v", targets)
+	}
+	invalid, err := repo.GetWorkoutByID("invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalid.Exercises[0].Targets) != 0 {
+		t.Errorf("invalid workout changed: %+v", invalid.Exercises[0])
+	}
+
+	// 再次执行没有需要迁移的训练计划
+	if migrated, err := Migrate(repo); err != nil || migrated != 0 {
+		t.Errorf("second migration = %d, %v", migrated, err)
+	}
+}
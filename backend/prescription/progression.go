@@ -0,0 +1,96 @@
+package prescription
+
+import (
+	"fmt"
+	"math"
+	"workout-tracker/effort"
+	"workout-tracker/models"
+	"workout-tracker/tracking"
+)
+
+// 渐进超负荷规则的默认值
+const (
+	DefaultIncrementKg   = 2.5
+	DefaultIncrementLb   = 5
+	DefaultDeloadAfter   = 3
+	DefaultDeloadPercent = 10
+	DefaultTargetRPE     = 8
+)
+
+// ProgressionNames 渐进超负荷规则的显示名称
+var ProgressionNames = map[string]string{
+	models.ProgressionLinear: "线性递增",
+	models.ProgressionDouble: "双重递增",
+	models.ProgressionRPE:    "按 RPE 调整",
+}
+
+// FromKilograms 把公斤换算为指定单位，保留两位小数
+func FromKilograms(kg float64, unit string) float64 {
+	if unit == models.UnitLb {
+		kg /= kgPerLb
+	}
+	return math.Round(kg*100) / 100
+}
+
+// Working 是否为正式组，热身组不参与渐进超负荷
+func Working(target models.SetTarget) bool {
+	return target.Type != models.SetWarmup
+}
+
+// 检查渐进超负荷规则并填写默认值。规则调整的是重量，只适用于次数+重量的动作；
+// 双重递增要求每个正式组都设置了次数范围
+func validateProgression(set *models.ExerciseSet, fields tracking.Fields) error {
+	rule := set.Progression
+	if rule == nil {
+		return nil
+	}
+	if _, ok := ProgressionNames[rule.Type]; !ok {
+		return fmt.Errorf("progression.type: unknown progression type %q", rule.Type)
+	}
+	if !fields.Reps || !fields.Weight {
+		return fmt.Errorf("progression: requires an exercise tracked by reps and weight")
+	}
+	if rule.Increment < 0 || rule.Sessions < 0 || rule.DeloadAfter < 0 || rule.DeloadPercent < 0 {
+		return fmt.Errorf("progression: values must not be negative")
+	}
+	if rule.DeloadPercent >= 100 {
+		return fmt.Errorf("progression.deloadPercent: must be less than 100")
+	}
+	if err := effort.ValidateRPE(rule.TargetRPE); err != nil {
+		return fmt.Errorf("progression.targetRpe: %v", err)
+	}
+
+	working := 0
+	for i, target := range set.Targets {
+		if !Working(target) {
+			continue
+		}
+		working++
+		if rule.Type == models.ProgressionDouble && target.RepsMax <= target.Reps {
+			return fmt.Errorf("targets[%d]: double progression requires repsMax greater than reps", i)
+		}
+	}
+	if working == 0 {
+		return fmt.Errorf("progression: requires at least one working set")
+	}
+
+	if rule.Increment == 0 {
+		rule.Increment = DefaultIncrementKg
+		if set.Unit == models.UnitLb {
+			rule.Increment = DefaultIncrementLb
+		}
+	}
+	if rule.Sessions == 0 {
+		rule.Sessions = 1
+	}
+	if rule.DeloadAfter == 0 {
+		rule.DeloadAfter = DefaultDeloadAfter
+	}
+	if rule.DeloadPercent == 0 {
+		rule.DeloadPercent = DefaultDeloadPercent
+	}
+	if rule.Type != models.ProgressionRPE {
+		rule.TargetRPE = 0
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package prescription
+
+import (
+	"strings"
+	"testing"
+	"workout-tracker/models"
+)
+
+func TestValidateProgression(t *testing.T) {
+	working := []models.SetTarget{{Type: models.SetWarmup, Reps: 10, Weight: 20}, {Reps: 5, Weight: 60}}
+	ranged := []models.SetTarget{{Type: models.SetWarmup, Reps: 10, Weight: 20}, {Reps: 8, RepsMax: 12, Weight: 40}}
+	tests := []struct {
+		name    string
+		mode    string
+		unit    string
+		targets []models.SetTarget
+		rule    models.ProgressionRule
+		want    models.ProgressionRule
+		err     string
+	}{
+		{
+			name:    "linear defaults in kg",
+			mode:    models.TrackRepsWeight,
+			targets: working,
+			rule:    models.ProgressionRule{Type: models.ProgressionLinear, TargetRPE: 9},
+			want:    models.ProgressionRule{Type: models.ProgressionLinear, Increment: DefaultIncrementKg, Sessions: 1, DeloadAfter: DefaultDeloadAfter, DeloadPercent: DefaultDeloadPercent},
+		},
+		{
+			name:    "defaults follow the summary unit",
+			mode:    models.TrackRepsWeight,
+			unit:    models.UnitLb,
+			targets: working,
+			rule:    models.ProgressionRule{Type: models.ProgressionRPE, TargetRPE: 7.5, Sessions: 2},
+			want:    models.ProgressionRule{Type: models.ProgressionRPE, Increment: DefaultIncrementLb, Sessions: 2, DeloadAfter: DefaultDeloadAfter, DeloadPercent: DefaultDeloadPercent, TargetRPE: 7.5},
+		},
+		{
+			name:    "double progression with rep ranges",
+			mode:    models.TrackRepsWeight,
+			targets: ranged,
+			rule:    models.ProgressionRule{Type: models.ProgressionDouble, Increment: 1, DeloadAfter: 2, DeloadPercent: 5},
+			want:    models.ProgressionRule{Type: models.ProgressionDouble, Increment: 1, Sessions: 1, DeloadAfter: 2, DeloadPercent: 5},
+		},
+		{name: "unknown type", mode: models.TrackRepsWeight, targets: working, rule: models.ProgressionRule{Type: "wave"}, err: `progression.type: unknown progression type "wave"`},
+		{name: "not weighted", mode: models.TrackReps, targets: []models.SetTarget{{Reps: 10}}, rule: models.ProgressionRule{Type: models.ProgressionLinear}, err: "progression: requires an exercise tracked by reps and weight"},
+		{name: "negative increment", mode: models.TrackRepsWeight, targets: working, rule: models.ProgressionRule{Type: models.ProgressionLinear, Increment: -1}, err: "progression: values must not be negative"},
+		{name: "full deload", mode: models.TrackRepsWeight, targets: working, rule: models.ProgressionRule{Type: models.ProgressionLinear, DeloadPercent: 100}, err: "progression.deloadPercent: must be less than 100"},
+		{name: "bad target rpe", mode: models.TrackRepsWeight, targets: working, rule: models.ProgressionRule{Type: models.ProgressionRPE, TargetRPE: 12}, err: "progression.targetRpe:"},
+		{name: "double without range", mode: models.TrackRepsWeight, targets: working, rule: models.ProgressionRule{Type: models.ProgressionDouble}, err: "targets[1]: double progression requires repsMax greater than reps"},
+		{name: "warmups only", mode: models.TrackRepsWeight, targets: working[:1], rule: models.ProgressionRule{Type: models.ProgressionLinear}, err: "progression: requires at least one working set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := append([]models.SetTarget(nil), tt.targets...)
+			for i := range targets {
+				targets[i].Unit = tt.unit
+			}
+			rule := tt.rule
+			set := models.ExerciseSet{Targets: targets, Progression: &rule}
+			err := Normalize(&set, tt.mode)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *set.Progression != tt.want {
+				t.Errorf("rule = %+v, want %+v", *set.Progression, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package presenter
+
+import (
+	"math"
+	"sort"
+	"time"
+	"workout-tracker/effort"
+	"workout-tracker/models"
+)
+
+// RPE 走势的统计周期
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// RPETrend 一个动作的平均 RPE 走势
+type RPETrend struct {
+	ExerciseID string     `json:"exerciseId"`
+	Name       string     `json:"name"`
+	AverageRPE float64    `json:"averageRpe"` // 整个时间范围
+	Sets       int        `json:"sets"`       // 记录了 RPE 或 RIR 的组数
+	Points     []RPEPoint `json:"points"`
+}
+
+// RPEPoint 一个周期内的平均 RPE
+type RPEPoint struct {
+	Period     string  `json:"period"` // 按周为周日的日期 2006-01-02，按月为 2006-01
+	AverageRPE float64 `json:"averageRpe"`
+	Sets       int     `json:"sets"`
+}
+
+type rpeSum struct {
+	total float64
+	sets  int
+}
+
+func (s *rpeSum) add(rpe float64) {
+	s.total += rpe
+	s.sets++
+}
+
+func (s rpeSum) average() float64 {
+	return math.Round(s.total/float64(s.sets)*10) / 10
+}
+
+// FormatRPETrends 统计已完成训练中每个动作的平均 RPE，只记录了 RIR 的组按 10-RIR 换算
+// exerciseID 为空时统计所有动作，from/to 为零值时不限制
+func (p *WorkoutPresenter) FormatRPETrends(sessions []models.WorkoutSession, exercises []models.Exercise, exerciseID, period string, from, to time.Time) []RPETrend {
+	names := make(map[string]string, len(exercises))
+	for _, exercise := range exercises {
+		names[exercise.ID] = exercise.Name
+	}
+
+	totals := make(map[string]*rpeSum)
+	byPeriod := make(map[string]map[string]*rpeSum)
+	for _, session := range sessions {
+		if !session.IsCompleted {
+			continue
+		}
+		if (!from.IsZero() && session.Date.Before(from)) || (!to.IsZero() && !session.Date.Before(to)) {
+			continue
+		}
+		key := p.periodKey(session.Date, period)
+		for _, exercise := range session.Exercises {
+			if exerciseID != "" && exercise.ExerciseID != exerciseID {
+				continue
+			}
+			for _, log := range exercise.SetLogs {
+				rpe := effort.Effective(log)
+				if rpe == 0 {
+					continue
+				}
+				if totals[exercise.ExerciseID] == nil {
+					totals[exercise.ExerciseID] = &rpeSum{}
+					byPeriod[exercise.ExerciseID] = make(map[string]*rpeSum)
+				}
+				totals[exercise.ExerciseID].add(rpe)
+				if byPeriod[exercise.ExerciseID][key] == nil {
+					byPeriod[exercise.ExerciseID][key] = &rpeSum{}
+				}
+				byPeriod[exercise.ExerciseID][key].add(rpe)
+			}
+		}
+	}
+
+	result := make([]RPETrend, 0, len(totals))
+	for id, total := range totals {
+		trend := RPETrend{
+			ExerciseID: id,
+			Name:       names[id],
+			AverageRPE: total.average(),
+			Sets:       total.sets,
+			Points:     []RPEPoint{},
+		}
+		for key, sum := range byPeriod[id] {
+			trend.Points = append(trend.Points, RPEPoint{Period: key, AverageRPE: sum.average(), Sets: sum.sets})
+		}
+		sort.Slice(trend.Points, func(i, j int) bool { return trend.Points[i].Period < trend.Points[j].Period })
+		result = append(result, trend)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ExerciseID < result[j].ExerciseID
+	})
+	return result
+}
+
+// 统计周期的标识，与本周统计一致从周日开始
+func (p *WorkoutPresenter) periodKey(t time.Time, period string) string {
+	if period == PeriodMonth {
+		return t.Format("2006-01")
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return p.FormatDate(day.AddDate(0, 0, -int(day.Weekday())))
+}
@@ -0,0 +1,56 @@
+package presenter
+
+import (
+	"testing"
+	"time"
+	"workout-tracker/models"
+)
+
+func TestFormatRPETrends(t *testing.T) {
+	rir := func(v int) *int { return &v }
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 18, 0, 0, 0, time.UTC) }
+	exercises := []models.Exercise{{ID: "squat", Name: "深蹲"}, {ID: "bench", Name: "卧推"}}
+	sessions := []models.WorkoutSession{
+		// 2026-03-01 是周日
+		{Date: day(2), IsCompleted: true, Exercises: []models.CompletedExercise{
+			{ExerciseID: "squat", SetLogs: []models.SetLog{{RPE: 7}, {RPE: 8}, {Note: "no effort recorded"}}},
+			{ExerciseID: "bench", SetLogs: []models.SetLog{{RIR: rir(2)}}},
+		}},
+		{Date: day(9), IsCompleted: true, Exercises: []models.CompletedExercise{
+			{ExerciseID: "squat", SetLogs: []models.SetLog{{RPE: 9}, {RIR: rir(0)}}},
+		}},
+		// 未完成的训练不计入
+		{Date: day(10), Exercises: []models.CompletedExercise{
+			{ExerciseID: "squat", SetLogs: []models.SetLog{{RPE: 1}}},
+		}},
+	}
+	p := NewWorkoutPresenter()
+
+	trends := p.FormatRPETrends(sessions, exercises, "", PeriodWeek, time.Time{}, time.Time{})
+	if len(trends) != 2 || trends[0].ExerciseID != "bench" || trends[1].ExerciseID != "squat" {
+		t.Fatalf("trends = %+v", trends)
+	}
+	if bench := trends[0]; bench.AverageRPE != 8 || bench.Sets != 1 {
+		t.Errorf("bench = %+v", bench)
+	}
+	squat := trends[1]
+	want := []RPEPoint{{Period: "2026-03-01", AverageRPE: 7.5, Sets: 2}, {Period: "2026-03-08", AverageRPE: 9.5, Sets: 2}}
+	if squat.Name != "深蹲" || squat.AverageRPE != 8.5 || squat.Sets != 4 || len(squat.Points) != 2 || squat.Points[0] != want[0] || squat.Points[1] != want[1] {
+		t.Errorf("squat = %+v", squat)
+	}
+
+	monthly := p.FormatRPETrends(sessions, exercises, "squat", PeriodMonth, time.Time{}, time.Time{})
+	if len(monthly) != 1 || len(monthly[0].Points) != 1 || monthly[0].Points[0] != (RPEPoint{Period: "2026-03", AverageRPE: 8.5, Sets: 4}) {
+		t.Errorf("monthly = %+v", monthly)
+	}
+
+	// to 不包含在范围内
+	ranged := p.FormatRPETrends(sessions, exercises, "squat", PeriodWeek, day(3), day(9))
+	if len(ranged) != 0 {
+		t.Errorf("ranged = %+v", ranged)
+	}
+	ranged = p.FormatRPETrends(sessions, exercises, "squat", PeriodWeek, day(3), day(10))
+	if len(ranged) != 1 || ranged[0].Sets != 2 {
+		t.Errorf("ranged = %+v", ranged)
+	}
+}
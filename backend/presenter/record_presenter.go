@@ -0,0 +1,9 @@
+package presenter
+
+import "workout-tracker/models"
+
+// SessionResponse 训练记录和这次训练产生的个人记录
+type SessionResponse struct {
+	models.WorkoutSession
+	Records []models.PersonalRecord `json:"records"`
+}
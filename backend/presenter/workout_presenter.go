@@ -45,25 +45,37 @@ type StatisticsResponse struct {
 }
 
 type DayStats struct {
-	Date         string `json:"date"`
-	TotalTime    string `json:"totalTime"`
-	WorkoutCount int    `json:"workoutCount"`
-	Exercises    int    `json:"exercises"`
+	Date         string     `json:"date"`
+	TotalTime    string     `json:"totalTime"`
+	WorkoutCount int        `json:"workoutCount"`
+	Exercises    int        `json:"exercises"`
+	Work         WorkTotals `json:"work"`
 }
 
 type WeekStats struct {
-	StartDate    string `json:"startDate"`
-	EndDate      string `json:"endDate"`
-	TotalTime    string `json:"totalTime"`
-	WorkoutCount int    `json:"workoutCount"`
-	AvgPerDay    string `json:"avgPerDay"`
+	StartDate    string     `json:"startDate"`
+	EndDate      string     `json:"endDate"`
+	TotalTime    string     `json:"totalTime"`
+	WorkoutCount int        `json:"workoutCount"`
+	AvgPerDay    string     `json:"avgPerDay"`
+	Work         WorkTotals `json:"work"`
 }
 
 type MonthStats struct {
-	Month        string `json:"month"`
-	TotalTime    string `json:"totalTime"`
-	WorkoutCount int    `json:"workoutCount"`
-	AvgPerDay    string `json:"avgPerDay"`
+	Month        string     `json:"month"`
+	TotalTime    string     `json:"totalTime"`
+	WorkoutCount int        `json:"workoutCount"`
+	AvgPerDay    string     `json:"avgPerDay"`
+	Work         WorkTotals `json:"work"`
+}
+
+// 已记录的训练量，按各记录方式的字段分别累计
+type WorkTotals struct {
+	Sets     int     `json:"sets"`
+	Reps     int     `json:"reps"`
+	Volume   float64 `json:"volume"`   // 次数 x 重量(公斤)
+	Duration int     `json:"duration"` // 秒
+	Distance float64 `json:"distance"` // 米
 }
 
 type BodyPartStatistics struct {
@@ -101,12 +113,14 @@ func (p *WorkoutPresenter) FormatStatistics(sessions []models.WorkoutSession) St
 
 func (p *WorkoutPresenter) calculateDayStats(sessions []models.WorkoutSession, date time.Time) DayStats {
 	var totalTime, workoutCount, exerciseCount int
+	var work WorkTotals
 	
 	for _, session := range sessions {
 		if p.isSameDay(session.Date, date) && session.IsCompleted {
 			totalTime += session.TotalTime
 			workoutCount++
 			exerciseCount += len(session.Exercises)
+			p.addWork(&work, session)
 		}
 	}
 
@@ -115,16 +129,19 @@ func (p *WorkoutPresenter) calculateDayStats(sessions []models.WorkoutSession, d
 		TotalTime:    p.FormatDuration(totalTime),
 		WorkoutCount: workoutCount,
 		Exercises:    exerciseCount,
+		Work:         work,
 	}
 }
 
 func (p *WorkoutPresenter) calculateWeekStats(sessions []models.WorkoutSession, start, end time.Time) WeekStats {
 	var totalTime, workoutCount int
+	var work WorkTotals
 	
 	for _, session := range sessions {
 		if session.Date.After(start) && session.Date.Before(end) && session.IsCompleted {
 			totalTime += session.TotalTime
 			workoutCount++
+			p.addWork(&work, session)
 		}
 	}
 
@@ -139,16 +156,19 @@ func (p *WorkoutPresenter) calculateWeekStats(sessions []models.WorkoutSession,
 		TotalTime:    p.FormatDuration(totalTime),
 		WorkoutCount: workoutCount,
 		AvgPerDay:    p.FormatDuration(avgPerDay),
+		Work:         work,
 	}
 }
 
 func (p *WorkoutPresenter) calculateMonthStats(sessions []models.WorkoutSession, start, end time.Time) MonthStats {
 	var totalTime, workoutCount int
+	var work WorkTotals
 	
 	for _, session := range sessions {
 		if session.Date.After(start) && session.Date.Before(end) && session.IsCompleted {
 			totalTime += session.TotalTime
 			workoutCount++
+			p.addWork(&work, session)
 		}
 	}
 
@@ -163,6 +183,7 @@ func (p *WorkoutPresenter) calculateMonthStats(sessions []models.WorkoutSession,
 		TotalTime:    p.FormatDuration(totalTime),
 		WorkoutCount: workoutCount,
 		AvgPerDay:    p.FormatDuration(avgPerDay),
+		Work:         work,
 	}
 }
 
@@ -198,6 +219,29 @@ func (p *WorkoutPresenter) calculateBodyPartStats(sessions []models.WorkoutSessi
 	return result
 }
 
+// 累计一次训练中已完成各组的次数、重量、时长和距离
+func (p *WorkoutPresenter) addWork(work *WorkTotals, session models.WorkoutSession) {
+	for _, exercise := range session.Exercises {
+		for i := 0; i < exercise.CompletedSets; i++ {
+			work.Sets++
+			reps := 0
+			if i < len(exercise.CompletedReps) {
+				reps = exercise.CompletedReps[i]
+				work.Reps += reps
+			}
+			if i < len(exercise.CompletedWeights) {
+				work.Volume += float64(reps) * exercise.CompletedWeights[i]
+			}
+			if i < len(exercise.CompletedDurations) {
+				work.Duration += exercise.CompletedDurations[i]
+			}
+			if i < len(exercise.CompletedDistances) {
+				work.Distance += exercise.CompletedDistances[i]
+			}
+		}
+	}
+}
+
 func (p *WorkoutPresenter) isSameDay(t1, t2 time.Time) bool {
 	y1, m1, d1 := t1.Date()
 	y2, m2, d2 := t2.Date()
@@ -0,0 +1,38 @@
+package presenter
+
+import (
+	"testing"
+	"time"
+	"workout-tracker/models"
+)
+
+func TestFormatStatisticsWorkTotals(t *testing.T) {
+	now := time.Now()
+	sessions := []models.WorkoutSession{
+		{
+			Date:        now,
+			IsCompleted: true,
+			Exercises: []models.CompletedExercise{
+				// 次数+重量
+				{CompletedSets: 2, CompletedReps: []int{5, 4}, CompletedWeights: []float64{100, 100}},
+				// 只记录次数
+				{CompletedSets: 2, CompletedReps: []int{10, 8}},
+				// 时长+距离
+				{CompletedSets: 2, CompletedDurations: []int{300, 320}, CompletedDistances: []float64{1000, 1000}},
+				// 记录少于完成组数时只累计已记录的部分
+				{CompletedSets: 2, CompletedDurations: []int{60}},
+			},
+		},
+		// 未完成的训练不计入
+		{Date: now, Exercises: []models.CompletedExercise{{CompletedSets: 3, CompletedReps: []int{5, 5, 5}}}},
+	}
+
+	stats := NewWorkoutPresenter().FormatStatistics(sessions)
+	want := WorkTotals{Sets: 8, Reps: 27, Volume: 900, Duration: 680, Distance: 2000}
+	if stats.TodayStats.Work != want {
+		t.Errorf("today = %+v, want %+v", stats.TodayStats.Work, want)
+	}
+	if stats.WeekStats.Work != want || stats.MonthStats.Work != want {
+		t.Errorf("week = %+v, month = %+v", stats.WeekStats.Work, stats.MonthStats.Work)
+	}
+}
@@ -0,0 +1,259 @@
+package progression
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"workout-tracker/effort"
+	"workout-tracker/models"
+	"workout-tracker/prescription"
+)
+
+// Recommend 根据训练计划最近几次已完成的训练，为每个设置了渐进超负荷规则的动作给出下次训练的目标
+func Recommend(workout models.Workout, sessions []models.WorkoutSession) []models.Recommendation {
+	history := recent(workout.ID, sessions)
+	var result []models.Recommendation
+	for _, set := range workout.Exercises {
+		if set.Progression != nil {
+			result = append(result, recommend(set, history))
+		}
+	}
+	return result
+}
+
+// 训练计划已完成的训练，最近的在前
+func recent(workoutID string, sessions []models.WorkoutSession) []models.WorkoutSession {
+	var result []models.WorkoutSession
+	for _, session := range sessions {
+		if session.WorkoutID == workoutID && session.IsCompleted {
+			result = append(result, session)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.After(result[j].Date)
+	})
+	return result
+}
+
+func recommend(set models.ExerciseSet, history []models.WorkoutSession) models.Recommendation {
+	rule := *set.Progression
+	limit := rule.Sessions
+	if rule.DeloadAfter > limit {
+		limit = rule.DeloadAfter
+	}
+
+	// 最近几次训练中这个动作的记录，同一个动作可以出现在不同的训练块中
+	var outcomes []models.CompletedExercise
+	basedOn := []string{}
+	for _, session := range history {
+		for _, completed := range session.Exercises {
+			if completed.ExerciseID == set.ExerciseID && completed.Block == set.Block {
+				outcomes = append(outcomes, completed)
+				basedOn = append(basedOn, session.ID)
+				break
+			}
+		}
+		if len(outcomes) >= limit {
+			break
+		}
+	}
+
+	rec := models.Recommendation{
+		ExerciseID: set.ExerciseID,
+		Block:      set.Block,
+		Rule:       rule.Type,
+		Action:     models.ActionHold,
+		BasedOn:    basedOn,
+	}
+	if len(outcomes) == 0 {
+		rec.Targets = append([]models.SetTarget{}, set.Targets...)
+		rec.Reason = "还没有训练记录，使用计划中的目标"
+		return rec
+	}
+
+	rec.Targets = lastWeights(set, outcomes[0])
+	switch rule.Type {
+	case models.ProgressionLinear:
+		linear(&rec, set, rule, outcomes)
+	case models.ProgressionDouble:
+		double(&rec, set, rule, outcomes)
+	case models.ProgressionRPE:
+		byRPE(&rec, set, rule, outcomes[0])
+	}
+	return rec
+}
+
+// 线性递增：连续 Sessions 次完成所有目标次数后加重，连续 DeloadAfter 次未完成时减重
+func linear(rec *models.Recommendation, set models.ExerciseSet, rule models.ProgressionRule, outcomes []models.CompletedExercise) {
+	succeeded := streak(outcomes, func(c models.CompletedExercise) bool { return reached(set, c, false) })
+	failed := streak(outcomes, func(c models.CompletedExercise) bool { return !reached(set, c, false) })
+	switch {
+	case succeeded >= rule.Sessions:
+		increase(rec, rule.Increment)
+		rec.Reason = fmt.Sprintf("连续 %d 次训练完成了所有目标次数，正式组加重 %g%s", succeeded, rule.Increment, set.Unit)
+	case failed >= rule.DeloadAfter:
+		deload(rec, rule)
+		rec.Reason = fmt.Sprintf("连续 %d 次训练没有完成目标次数，正式组减重 %g%%", failed, rule.DeloadPercent)
+	case succeeded > 0:
+		rec.Reason = fmt.Sprintf("已连续 %d 次完成目标次数，再完成 %d 次后加重", succeeded, rule.Sessions-succeeded)
+	default:
+		rec.Reason = fmt.Sprintf("上次训练没有完成所有目标次数，保持重量（连续 %d 次未完成后减重）", rule.DeloadAfter)
+	}
+}
+
+// 双重递增：连续 Sessions 次所有正式组都达到次数上限后加重并回到次数下限，
+// 否则保持重量，每组比上次多做一次；连续 DeloadAfter 次没有达到次数下限时减重
+func double(rec *models.Recommendation, set models.ExerciseSet, rule models.ProgressionRule, outcomes []models.CompletedExercise) {
+	topped := streak(outcomes, func(c models.CompletedExercise) bool { return reached(set, c, true) })
+	failed := streak(outcomes, func(c models.CompletedExercise) bool { return !reached(set, c, false) })
+	switch {
+	case topped >= rule.Sessions:
+		increase(rec, rule.Increment)
+		rec.Reason = fmt.Sprintf("连续 %d 次训练所有正式组都达到了次数上限，加重 %g%s 并回到次数下限", topped, rule.Increment, set.Unit)
+	case failed >= rule.DeloadAfter:
+		deload(rec, rule)
+		rec.Reason = fmt.Sprintf("连续 %d 次训练没有达到次数下限，正式组减重 %g%%", failed, rule.DeloadPercent)
+	default:
+		last := outcomes[0]
+		for i := range rec.Targets {
+			target := &rec.Targets[i]
+			if !prescription.Working(*target) || i >= len(last.CompletedReps) || i >= last.CompletedSets {
+				continue
+			}
+			target.Reps = min(max(last.CompletedReps[i]+1, target.Reps), target.RepsMax)
+		}
+		rec.Reason = "保持重量，每组比上次多做一次，所有组达到次数上限后加重"
+	}
+}
+
+// 按 RPE 调整：上次正式组的平均 RPE 比目标低 1 以上并完成了所有目标次数时加重，
+// 比目标高 1 以上或没有完成目标次数时减重
+func byRPE(rec *models.Recommendation, set models.ExerciseSet, rule models.ProgressionRule, last models.CompletedExercise) {
+	target := targetRPE(set, rule)
+	average, ok := averageRPE(set, last)
+	met := reached(set, last, false)
+	switch {
+	case !ok && !met:
+		decrease(rec, rule.Increment)
+		rec.Reason = fmt.Sprintf("上次训练没有完成所有目标次数，减重 %g%s", rule.Increment, set.Unit)
+	case !ok:
+		rec.Reason = "上次训练没有记录 RPE，保持重量"
+	case !met || average >= target+1:
+		decrease(rec, rule.Increment)
+		rec.Reason = fmt.Sprintf("上次平均 RPE %.1f，目标 %g，减重 %g%s", average, target, rule.Increment, set.Unit)
+		if !met {
+			rec.Reason = fmt.Sprintf("上次训练没有完成所有目标次数（平均 RPE %.1f），减重 %g%s", average, rule.Increment, set.Unit)
+		}
+	case average <= target-1:
+		increase(rec, rule.Increment)
+		rec.Reason = fmt.Sprintf("上次平均 RPE %.1f，低于目标 %g，加重 %g%s", average, target, rule.Increment, set.Unit)
+	default:
+		rec.Reason = fmt.Sprintf("上次平均 RPE %.1f，接近目标 %g，保持重量", average, target)
+	}
+}
+
+// 正式组是否都完成了目标次数，top 为 true 时要求达到次数上限
+func reached(set models.ExerciseSet, completed models.CompletedExercise, top bool) bool {
+	for i, target := range set.Targets {
+		if !prescription.Working(target) {
+			continue
+		}
+		if i >= completed.CompletedSets || i >= len(completed.CompletedReps) {
+			return false
+		}
+		goal := target.Reps
+		if top && target.RepsMax > 0 {
+			goal = target.RepsMax
+		}
+		if completed.CompletedReps[i] < goal {
+			return false
+		}
+	}
+	return true
+}
+
+// 从最近一次开始连续满足条件的训练次数
+func streak(outcomes []models.CompletedExercise, ok func(models.CompletedExercise) bool) int {
+	n := 0
+	for _, outcome := range outcomes {
+		if !ok(outcome) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// 计划中的目标，重量使用上次训练实际记录的重量，这样加重会在上次的基础上累积
+func lastWeights(set models.ExerciseSet, last models.CompletedExercise) []models.SetTarget {
+	targets := append([]models.SetTarget{}, set.Targets...)
+	for i := range targets {
+		if i < len(last.CompletedWeights) && i < last.CompletedSets && last.CompletedWeights[i] > 0 {
+			targets[i].Weight = prescription.FromKilograms(last.CompletedWeights[i], targets[i].Unit)
+		}
+	}
+	return targets
+}
+
+func targetRPE(set models.ExerciseSet, rule models.ProgressionRule) float64 {
+	if rule.TargetRPE > 0 {
+		return rule.TargetRPE
+	}
+	for _, target := range set.Targets {
+		if prescription.Working(target) && target.RPE > 0 {
+			return target.RPE
+		}
+	}
+	return prescription.DefaultTargetRPE
+}
+
+// 正式组记录的平均 RPE，只记录了 RIR 的组按 10-RIR 换算
+func averageRPE(set models.ExerciseSet, completed models.CompletedExercise) (float64, bool) {
+	total, n := 0.0, 0
+	for i, target := range set.Targets {
+		if !prescription.Working(target) || i >= len(completed.SetLogs) {
+			continue
+		}
+		if rpe := effort.Effective(completed.SetLogs[i]); rpe > 0 {
+			total += rpe
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return total / float64(n), true
+}
+
+func increase(rec *models.Recommendation, increment float64) {
+	rec.Action = models.ActionIncrease
+	for i := range rec.Targets {
+		if prescription.Working(rec.Targets[i]) {
+			rec.Targets[i].Weight = round(rec.Targets[i].Weight + increment)
+		}
+	}
+}
+
+func decrease(rec *models.Recommendation, increment float64) {
+	rec.Action = models.ActionDecrease
+	for i := range rec.Targets {
+		if prescription.Working(rec.Targets[i]) {
+			rec.Targets[i].Weight = round(math.Max(0, rec.Targets[i].Weight-increment))
+		}
+	}
+}
+
+// 按比例减重，取最接近的加重幅度的整数倍
+func deload(rec *models.Recommendation, rule models.ProgressionRule) {
+	rec.Action = models.ActionDeload
+	for i := range rec.Targets {
+		if prescription.Working(rec.Targets[i]) {
+			weight := rec.Targets[i].Weight * (1 - rule.DeloadPercent/100)
+			rec.Targets[i].Weight = round(math.Round(weight/rule.Increment) * rule.Increment)
+		}
+	}
+}
+
+func round(weight float64) float64 {
+	return math.Round(weight*100) / 100
+}
@@ -0,0 +1,166 @@
+package progression
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"workout-tracker/models"
+)
+
+func target(setType string, reps, repsMax int, weight float64) models.SetTarget {
+	return models.SetTarget{Type: setType, Reps: reps, RepsMax: repsMax, Weight: weight, Unit: models.UnitKg}
+}
+
+func plan(rule models.ProgressionRule, targets ...models.SetTarget) models.ExerciseSet {
+	return models.ExerciseSet{ExerciseID: "squat", Sets: len(targets), Targets: targets, Progression: &rule}
+}
+
+// 深蹲的完成情况，rpe 与组一一对应，0 表示没有记录
+func outcome(reps []int, weights []float64, rpe ...float64) models.CompletedExercise {
+	completed := models.CompletedExercise{ExerciseID: "squat", CompletedSets: len(reps), CompletedReps: reps, CompletedWeights: weights}
+	for _, value := range rpe {
+		completed.SetLogs = append(completed.SetLogs, models.SetLog{RPE: value})
+	}
+	return completed
+}
+
+func done(id string, day int, exercises ...models.CompletedExercise) models.WorkoutSession {
+	return models.WorkoutSession{
+		ID:          id,
+		WorkoutID:   "w1",
+		Date:        time.Date(2026, 3, day, 7, 0, 0, 0, time.UTC),
+		IsCompleted: true,
+		Exercises:   exercises,
+	}
+}
+
+// 目标的简短表示：重量x次数，有次数范围时为 重量x下限-上限
+func describeTargets(targets []models.SetTarget) string {
+	var parts []string
+	for _, t := range targets {
+		if t.RepsMax > 0 {
+			parts = append(parts, fmt.Sprintf("%gx%d-%d", t.Weight, t.Reps, t.RepsMax))
+		} else {
+			parts = append(parts, fmt.Sprintf("%gx%d", t.Weight, t.Reps))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestRecommend(t *testing.T) {
+	linear := plan(models.ProgressionRule{Type: models.ProgressionLinear, Increment: 2.5, Sessions: 1, DeloadAfter: 3, DeloadPercent: 10},
+		target(models.SetWarmup, 10, 0, 40), target(models.SetWorking, 5, 0, 100), target(models.SetWorking, 5, 0, 100))
+	linearTwice := linear
+	linearTwice.Progression = &models.ProgressionRule{Type: models.ProgressionLinear, Increment: 2.5, Sessions: 2, DeloadAfter: 3, DeloadPercent: 10}
+	inBlock := linear
+	inBlock.Block = "b1"
+	pounds := plan(models.ProgressionRule{Type: models.ProgressionLinear, Increment: 5, Sessions: 1, DeloadAfter: 3, DeloadPercent: 10},
+		models.SetTarget{Type: models.SetWorking, Reps: 5, Weight: 135, Unit: models.UnitLb})
+	met := outcome([]int{10, 5, 5}, []float64{40, 100, 100})
+	missed := outcome([]int{10, 5, 4}, []float64{40, 100, 100})
+	other := done("o1", 9, outcome([]int{10, 5, 5}, []float64{40, 100, 100}))
+	other.WorkoutID = "w2"
+	unfinished := done("u1", 10, met)
+	unfinished.IsCompleted = false
+
+	double := plan(models.ProgressionRule{Type: models.ProgressionDouble, Increment: 2.5, Sessions: 1, DeloadAfter: 2, DeloadPercent: 10},
+		target(models.SetWarmup, 10, 0, 40), target(models.SetWorking, 8, 12, 60), target(models.SetWorking, 8, 12, 60))
+
+	byRPE := plan(models.ProgressionRule{Type: models.ProgressionRPE, Increment: 2.5},
+		target(models.SetWarmup, 10, 0, 40), target(models.SetWorking, 5, 0, 100), target(models.SetWorking, 5, 0, 100))
+	byRPE.Targets[1].RPE, byRPE.Targets[2].RPE = 8, 8
+	byRuleRPE := byRPE
+	byRuleRPE.Progression = &models.ProgressionRule{Type: models.ProgressionRPE, Increment: 2.5, TargetRPE: 9}
+	rir := 1
+	withRIR := outcome([]int{10, 5, 5}, []float64{40, 100, 100})
+	withRIR.SetLogs = []models.SetLog{{}, {RIR: &rir}, {RIR: &rir}}
+
+	tests := []struct {
+		name     string
+		set      models.ExerciseSet
+		sessions []models.WorkoutSession
+		action   string
+		targets  string
+		basedOn  []string
+	}{
+		{"no history uses the plan", linear, nil, models.ActionHold, "40x10 100x5 100x5", []string{}},
+		{"other block has no history", inBlock, []models.WorkoutSession{done("s1", 1, met)}, models.ActionHold, "40x10 100x5 100x5", []string{}},
+
+		{"linear increases after meeting targets", linear, []models.WorkoutSession{done("s1", 1, met)},
+			models.ActionIncrease, "40x10 102.5x5 102.5x5", []string{"s1"}},
+		{"linear builds on the last logged weight", linear, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 5, 5}, []float64{45, 105, 105}))},
+			models.ActionIncrease, "45x10 107.5x5 107.5x5", []string{"s1"}},
+		{"linear ignores other workouts and unfinished sessions", linear, []models.WorkoutSession{other, unfinished, done("s1", 1, met)},
+			models.ActionIncrease, "40x10 102.5x5 102.5x5", []string{"s1"}},
+		{"linear needs consecutive successes", linearTwice, []models.WorkoutSession{done("s2", 2, met), done("s1", 1, missed)},
+			models.ActionHold, "40x10 100x5 100x5", []string{"s2", "s1"}},
+		{"linear holds after a miss", linear, []models.WorkoutSession{done("s1", 1, met), done("s2", 2, missed)},
+			models.ActionHold, "40x10 100x5 100x5", []string{"s2", "s1"}},
+		{"linear deloads after repeated misses", linear, []models.WorkoutSession{done("s1", 1, missed), done("s2", 2, missed), done("s3", 3, missed), done("s4", 4, missed)},
+			models.ActionDeload, "40x10 90x5 90x5", []string{"s4", "s3", "s2"}},
+		{"linear converts logged kilograms to pounds", pounds, []models.WorkoutSession{done("s1", 1, outcome([]int{5}, []float64{102.06}))},
+			models.ActionIncrease, "230x5", []string{"s1"}},
+
+		{"double adds a rep to each set", double, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 10, 9}, []float64{40, 60, 60}))},
+			models.ActionHold, "40x10 60x11-12 60x10-12", []string{"s1"}},
+		{"double keeps reps within the range", double, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 6, 12}, []float64{40, 60, 60}))},
+			models.ActionHold, "40x10 60x8-12 60x12-12", []string{"s1"}},
+		{"double increases at the top of the range", double, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 12, 12}, []float64{40, 60, 60}))},
+			models.ActionIncrease, "40x10 62.5x8-12 62.5x8-12", []string{"s1"}},
+		{"double deloads below the range", double, []models.WorkoutSession{
+			done("s1", 1, outcome([]int{10, 6, 6}, []float64{40, 60, 60})),
+			done("s2", 2, outcome([]int{10, 7, 6}, []float64{40, 60, 60})),
+		}, models.ActionDeload, "40x10 55x8-12 55x8-12", []string{"s2", "s1"}},
+
+		{"rpe below target increases", byRPE, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 5, 5}, []float64{40, 100, 100}, 0, 6.5, 7))},
+			models.ActionIncrease, "40x10 102.5x5 102.5x5", []string{"s1"}},
+		{"rpe near target holds", byRPE, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 5, 5}, []float64{40, 100, 100}, 0, 8, 8.5))},
+			models.ActionHold, "40x10 100x5 100x5", []string{"s1"}},
+		{"rpe above target decreases", byRPE, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 5, 5}, []float64{40, 100, 100}, 0, 9, 9))},
+			models.ActionDecrease, "40x10 97.5x5 97.5x5", []string{"s1"}},
+		{"rir counts as rpe", byRPE, []models.WorkoutSession{done("s1", 1, withRIR)},
+			models.ActionDecrease, "40x10 97.5x5 97.5x5", []string{"s1"}},
+		{"rpe with missed reps decreases", byRPE, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 5, 4}, []float64{40, 100, 100}, 0, 7, 7))},
+			models.ActionDecrease, "40x10 97.5x5 97.5x5", []string{"s1"}},
+		{"rpe not logged holds", byRPE, []models.WorkoutSession{done("s1", 1, met)},
+			models.ActionHold, "40x10 100x5 100x5", []string{"s1"}},
+		{"rpe not logged and missed reps decreases", byRPE, []models.WorkoutSession{done("s1", 1, missed)},
+			models.ActionDecrease, "40x10 97.5x5 97.5x5", []string{"s1"}},
+		{"rule target rpe overrides the sets", byRuleRPE, []models.WorkoutSession{done("s1", 1, outcome([]int{10, 5, 5}, []float64{40, 100, 100}, 0, 8, 8))},
+			models.ActionIncrease, "40x10 102.5x5 102.5x5", []string{"s1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 没有渐进超负荷规则的动作不给出建议
+			workout := models.Workout{ID: "w1", Exercises: []models.ExerciseSet{tt.set, {ExerciseID: "curl", Sets: 3, Reps: 10}}}
+			recs := Recommend(workout, tt.sessions)
+			if len(recs) != 1 {
+				t.Fatalf("got %d recommendations", len(recs))
+			}
+			rec := recs[0]
+			if rec.ExerciseID != "squat" || rec.Rule != tt.set.Progression.Type || rec.Reason == "" {
+				t.Errorf("recommendation = %+v", rec)
+			}
+			if rec.Action != tt.action {
+				t.Errorf("action = %s, want %s (%s)", rec.Action, tt.action, rec.Reason)
+			}
+			if got := describeTargets(rec.Targets); got != tt.targets {
+				t.Errorf("targets = %s, want %s", got, tt.targets)
+			}
+			if fmt.Sprint(rec.BasedOn) != fmt.Sprint(tt.basedOn) {
+				t.Errorf("basedOn = %v, want %v", rec.BasedOn, tt.basedOn)
+			}
+		})
+	}
+}
+
+func TestRecommendDoesNotChangeThePlan(t *testing.T) {
+	set := plan(models.ProgressionRule{Type: models.ProgressionLinear, Increment: 2.5, Sessions: 1, DeloadAfter: 3, DeloadPercent: 10},
+		target(models.SetWorking, 5, 0, 100))
+	workout := models.Workout{ID: "w1", Exercises: []models.ExerciseSet{set}}
+	Recommend(workout, []models.WorkoutSession{done("s1", 1, outcome([]int{5}, []float64{100}))})
+	if set.Targets[0].Weight != 100 {
+		t.Errorf("plan target changed to %g", set.Targets[0].Weight)
+	}
+}
@@ -0,0 +1,112 @@
+package records
+
+import (
+	"slices"
+	"sync"
+	"workout-tracker/logging"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+// Detect 和 Rebuild 读取训练记录、计算并写回个人记录期间持有，
+// 避免同时完成的两次训练都基于旧记录判断出同一条新记录，或追加的记录被重新计算的结果覆盖
+var mu sync.Mutex
+
+// Detect 在训练记录保存后调用：训练刚标记完成时与之前已完成的训练比较，保存并返回新的个人记录。
+// 之后还有已完成的训练时（补录较早的训练）重新计算全部记录。
+// 修改已完成训练的各组数据或取消完成时也重新计算，修正后的记录不作为新记录返回
+func Detect(repo *repository.FileRepository, before *models.WorkoutSession, after models.WorkoutSession) ([]models.PersonalRecord, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if before != nil && before.IsCompleted {
+		if !after.IsCompleted || !sameSets(*before, after) {
+			_, err := rebuild(repo)
+			return nil, err
+		}
+		return nil, nil
+	}
+	if !after.IsCompleted {
+		return nil, nil
+	}
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		return nil, err
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTracker(exercises)
+	for _, session := range completed(sessions) {
+		if session.ID == after.ID {
+			continue
+		}
+		if session.Date.After(after.Date) {
+			if _, err := rebuild(repo); err != nil {
+				return nil, err
+			}
+			return repo.GetRecordsBySession(after.ID)
+		}
+		t.add(session)
+	}
+
+	found := t.check(after)
+	if err := repo.SetSessionRecords(after.ID, found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// 两次保存之间影响个人记录的数据是否相同：日期、完成时间和各动作已完成的组
+func sameSets(a, b models.WorkoutSession) bool {
+	if !a.Date.Equal(b.Date) || !finishedAt(a).Equal(finishedAt(b)) || len(a.Exercises) != len(b.Exercises) {
+		return false
+	}
+	for i := range a.Exercises {
+		x, y := a.Exercises[i], b.Exercises[i]
+		if x.ExerciseID != y.ExerciseID || x.Block != y.Block || x.CompletedSets != y.CompletedSets ||
+			!slices.Equal(x.CompletedReps, y.CompletedReps) || !slices.Equal(x.CompletedWeights, y.CompletedWeights) {
+			return false
+		}
+	}
+	return true
+}
+
+// Rebuild 按所有已完成的训练重新计算个人记录，用于导入和删除训练记录之后，返回记录数量
+func Rebuild(repo *repository.FileRepository) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return rebuild(repo)
+}
+
+func rebuild(repo *repository.FileRepository) (int, error) {
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		return 0, err
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return 0, err
+	}
+	computed := Compute(sessions, exercises)
+	return len(computed), repo.ReplaceRecords(computed)
+}
+
+// Migrate 还没有计算过个人记录时根据已有的训练记录计算，返回记录数量
+func Migrate(repo *repository.FileRepository) (int, error) {
+	existing, err := repo.GetAllRecords()
+	if err != nil {
+		return 0, err
+	}
+	// 计算过之后即使没有记录也会写入空数组
+	if existing != nil {
+		return 0, nil
+	}
+	count, err := Rebuild(repo)
+	if err == nil && count > 0 {
+		logging.Component("records").Debug("personal records computed from history", "records", count)
+	}
+	return count, err
+}
@@ -0,0 +1,241 @@
+package records
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+func completedSession(id string, date time.Time, weight float64) models.WorkoutSession {
+	return models.WorkoutSession{
+		ID:          id,
+		Date:        date,
+		IsCompleted: true,
+		Exercises: []models.CompletedExercise{
+			{ExerciseID: "bench", CompletedSets: 1, CompletedReps: []int{5}, CompletedWeights: []float64{weight}},
+		},
+	}
+}
+
+// 保存训练记录并检测个人记录，模拟处理 PUT/PATCH
+func save(t *testing.T, repo *repository.FileRepository, session models.WorkoutSession) []models.PersonalRecord {
+	t.Helper()
+	before, err := repo.GetSessionByID(session.ID)
+	if err != nil {
+		before = nil
+	}
+	if err := repo.SaveSession(session); err != nil {
+		t.Fatal(err)
+	}
+	found, err := Detect(repo, before, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func maxWeight(t *testing.T, repo *repository.FileRepository) float64 {
+	t.Helper()
+	list, err := repo.GetRecordsByExercise("bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := Summarize("bench", list, models.FormulaEpley)
+	if summary.Weight == nil {
+		return 0
+	}
+	return summary.Weight.Value
+}
+
+func TestDetectAfterEditingCompletedSession(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	if err := repo.SaveExercise(models.Exercise{ID: "bench", Name: "Bench"}); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+
+	// 录入时多打了一个 0
+	typo := completedSession("s1", day, 1000)
+	if found := save(t, repo, typo); len(found) == 0 {
+		t.Fatal("no records for the first session")
+	}
+	if got := maxWeight(t, repo); got != 1000 {
+		t.Fatalf("max weight = %v, want 1000", got)
+	}
+
+	// 修正重量后重新计算，修正不作为新记录返回
+	fixed := completedSession("s1", day, 100)
+	if found := save(t, repo, fixed); len(found) != 0 {
+		t.Errorf("correction returned %d records", len(found))
+	}
+	if got := maxWeight(t, repo); got != 100 {
+		t.Fatalf("max weight after correction = %v, want 100", got)
+	}
+
+	// 之后的真实记录不再被错误的重量挡住
+	next := completedSession("s2", day.AddDate(0, 0, 2), 105)
+	found := save(t, repo, next)
+	weightRecord := false
+	for _, record := range found {
+		weightRecord = weightRecord || (record.Type == models.RecordWeight && record.Value == 105 && record.Previous == 100)
+	}
+	if !weightRecord {
+		t.Errorf("no weight record for 105 after 100: %+v", found)
+	}
+
+	// 只修改备注等字段时不重新计算
+	list, err := repo.GetAllRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	noted := next
+	noted.Exercises = append([]models.CompletedExercise{}, next.Exercises...)
+	noted.Exercises[0].SetLogs = []models.SetLog{{Note: "felt easy"}}
+	save(t, repo, noted)
+	after, err := repo.GetAllRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(list) || after[0].ID != list[0].ID {
+		t.Error("records were rebuilt for an unrelated edit")
+	}
+
+	// 取消完成后这次训练的记录被移除
+	undone := next
+	undone.IsCompleted = false
+	save(t, repo, undone)
+	bySession, err := repo.GetRecordsBySession("s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bySession) != 0 {
+		t.Errorf("un-completed session still has %d records", len(bySession))
+	}
+	if got := maxWeight(t, repo); got != 100 {
+		t.Errorf("max weight after un-completing = %v, want 100", got)
+	}
+}
+
+func TestDetectConcurrentCompletions(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	if err := repo.SaveExercise(models.Exercise{ID: "bench", Name: "Bench"}); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+
+	// 同时完成多次训练，包括补录较早的训练，结果应与重新计算全部记录相同且没有重复
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		session := completedSession(fmt.Sprintf("s%d", i), day.AddDate(0, 0, i*7%16), float64(100+i*5%16))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.SaveSession(session); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := Detect(repo, nil, session); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	key := func(record models.PersonalRecord) string {
+		return fmt.Sprintf("%s/%s/%g", record.SessionID, record.Type, record.Value)
+	}
+	list, err := repo.GetAllRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, record := range list {
+		got[key(record)]++
+	}
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Compute(sessions, exercises)
+	if len(list) != len(want) {
+		t.Errorf("%d records, want %d: %v", len(list), len(want), got)
+	}
+	for _, record := range want {
+		if got[key(record)] != 1 {
+			t.Errorf("record %s stored %d times", key(record), got[key(record)])
+		}
+	}
+}
+
+func TestDetectAfterRebuildByAnotherRequest(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	if err := repo.SaveExercise(models.Exercise{ID: "bench", Name: "Bench"}); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+	later := completedSession("s2", day.AddDate(0, 0, 2), 110)
+	earlier := completedSession("s1", day, 100)
+
+	// 两个请求先后保存，补录较早训练的请求先检测并重新计算了全部记录
+	for _, session := range []models.WorkoutSession{later, earlier} {
+		if err := repo.SaveSession(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Detect(repo, nil, earlier); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := repo.GetRecordsBySession("s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Detect(repo, nil, later); err != nil {
+		t.Fatal(err)
+	}
+	detected, err := repo.GetRecordsBySession("s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebuilt) == 0 || len(detected) != len(rebuilt) {
+		t.Errorf("records for s2: %d after rebuild, %d after detect", len(rebuilt), len(detected))
+	}
+}
+
+func TestDetectWaitsForRebuild(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	if err := repo.SaveExercise(models.Exercise{ID: "bench", Name: "Bench"}); err != nil {
+		t.Fatal(err)
+	}
+	session := completedSession("s1", time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC), 100)
+	if err := repo.SaveSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟正在进行的重新计算
+	mu.Lock()
+	done := make(chan error)
+	go func() {
+		_, err := Detect(repo, nil, session)
+		done <- err
+	}()
+	select {
+	case <-done:
+		mu.Unlock()
+		t.Fatal("detect ran during a rebuild")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mu.Unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := maxWeight(t, repo); got != 100 {
+		t.Errorf("max weight = %v, want 100", got)
+	}
+}
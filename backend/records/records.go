@@ -0,0 +1,328 @@
+package records
+
+import (
+	"math"
+	"sort"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/tracking"
+
+	"github.com/google/uuid"
+)
+
+// MaxE1RMReps 超过这个次数的组误差太大，不估算 1RM
+const MaxE1RMReps = 12
+
+// Formulas 支持的 1RM 估算公式
+var Formulas = []string{models.FormulaEpley, models.FormulaBrzycki, models.FormulaLombardi}
+
+// DefaultFormula 未指定公式时使用，由 E1RM_FORMULA 设置
+var DefaultFormula = models.FormulaEpley
+
+// IsFormula 是否为支持的公式
+func IsFormula(formula string) bool {
+	for _, f := range Formulas {
+		if f == formula {
+			return true
+		}
+	}
+	return false
+}
+
+// E1RM 按公式估算一次最大重量(公斤)，保留一位小数；单次即为实际重量，
+// 次数超过 MaxE1RMReps 时返回 0
+func E1RM(weight float64, reps int, formula string) float64 {
+	if weight <= 0 || reps <= 0 || reps > MaxE1RMReps {
+		return 0
+	}
+	if reps == 1 {
+		return weight
+	}
+	var value float64
+	switch formula {
+	case models.FormulaBrzycki:
+		value = weight * 36 / float64(37-reps)
+	case models.FormulaLombardi:
+		value = weight * math.Pow(float64(reps), 0.1)
+	default:
+		value = weight * (1 + float64(reps)/30)
+	}
+	return math.Round(value*10) / 10
+}
+
+// 已完成的一组
+type loggedSet struct {
+	block  string
+	number int
+	reps   int
+	weight float64
+}
+
+// 一个动作到目前为止的最好成绩
+type best struct {
+	weight float64
+	reps   map[float64]int // 重量 -> 最多次数
+	e1rm   map[string]float64
+	volume float64
+}
+
+// 按时间顺序累计每个动作的最好成绩
+type tracker struct {
+	modes map[string]string
+	bests map[string]*best
+}
+
+func newTracker(exercises []models.Exercise) *tracker {
+	return &tracker{modes: tracking.ByExercise(exercises), bests: make(map[string]*best)}
+}
+
+func (t *tracker) best(exerciseID string) *best {
+	b, ok := t.bests[exerciseID]
+	if !ok {
+		b = &best{reps: make(map[float64]int), e1rm: make(map[string]float64)}
+		t.bests[exerciseID] = b
+	}
+	return b
+}
+
+// 训练中按次数记录的动作的已完成各组，按动作在训练中第一次出现的顺序排列；
+// 同一个动作出现在多个训练块中时合并
+func (t *tracker) sets(session models.WorkoutSession) ([]string, map[string][]loggedSet) {
+	var order []string
+	byExercise := make(map[string][]loggedSet)
+	for _, completed := range session.Exercises {
+		mode, ok := t.modes[completed.ExerciseID]
+		if !ok {
+			mode = models.TrackRepsWeight
+		}
+		fields := tracking.FieldsOf(mode)
+		if !fields.Reps {
+			continue
+		}
+		for i := 0; i < completed.CompletedSets && i < len(completed.CompletedReps); i++ {
+			set := loggedSet{block: completed.Block, number: i + 1, reps: completed.CompletedReps[i]}
+			if fields.Weight && i < len(completed.CompletedWeights) {
+				set.weight = completed.CompletedWeights[i]
+			}
+			if set.reps <= 0 {
+				continue
+			}
+			if _, seen := byExercise[completed.ExerciseID]; !seen {
+				order = append(order, completed.ExerciseID)
+			}
+			byExercise[completed.ExerciseID] = append(byExercise[completed.ExerciseID], set)
+		}
+	}
+	return order, byExercise
+}
+
+// 在某一重量或更重的重量下做过的最多次数
+func (b *best) repsAtLeast(weight float64) int {
+	most := 0
+	for w, reps := range b.reps {
+		if w >= weight && reps > most {
+			most = reps
+		}
+	}
+	return most
+}
+
+// 与之前的最好成绩比较，返回这次训练产生的个人记录，每种记录只取最好的一组
+func (t *tracker) check(session models.WorkoutSession) []models.PersonalRecord {
+	achievedAt := finishedAt(session)
+	record := func(exerciseID, recordType string, value, previous float64, set loggedSet) models.PersonalRecord {
+		return models.PersonalRecord{
+			ID:         uuid.New().String(),
+			ExerciseID: exerciseID,
+			Type:       recordType,
+			Value:      value,
+			Previous:   previous,
+			Weight:     set.weight,
+			Reps:       set.reps,
+			SessionID:  session.ID,
+			Block:      set.block,
+			SetNumber:  set.number,
+			AchievedAt: achievedAt,
+		}
+	}
+
+	var result []models.PersonalRecord
+	order, byExercise := t.sets(session)
+	for _, exerciseID := range order {
+		b := t.best(exerciseID)
+		sets := byExercise[exerciseID]
+
+		heaviest := sets[0]
+		volume, reps := 0.0, 0
+		for _, set := range sets {
+			if set.weight > heaviest.weight || (set.weight == heaviest.weight && set.reps > heaviest.reps) {
+				heaviest = set
+			}
+			volume += float64(set.reps) * set.weight
+			reps += set.reps
+		}
+		if heaviest.weight > b.weight {
+			result = append(result, record(exerciseID, models.RecordWeight, heaviest.weight, b.weight, heaviest))
+		}
+
+		// 某一重量的次数记录：之前在这个重量或更重的重量下没有做到这么多次，
+		// 并且这次训练中更重的组也没有做到
+		for i, set := range sets {
+			dominated := false
+			for j, other := range sets {
+				if j != i && ((other.weight > set.weight && other.reps >= set.reps) || (other.weight == set.weight && (other.reps > set.reps || (other.reps == set.reps && j < i)))) {
+					dominated = true
+					break
+				}
+			}
+			if previous := b.repsAtLeast(set.weight); !dominated && set.reps > previous {
+				result = append(result, record(exerciseID, models.RecordReps, float64(set.reps), float64(previous), set))
+			}
+		}
+
+		for _, formula := range Formulas {
+			var top loggedSet
+			value := 0.0
+			for _, set := range sets {
+				if e := E1RM(set.weight, set.reps, formula); e > value {
+					top, value = set, e
+				}
+			}
+			if value > b.e1rm[formula] {
+				r := record(exerciseID, models.RecordE1RM, value, b.e1rm[formula], top)
+				r.Formula = formula
+				result = append(result, r)
+			}
+		}
+
+		if volume > b.volume {
+			r := record(exerciseID, models.RecordVolume, math.Round(volume*100)/100, b.volume, loggedSet{})
+			r.Reps = reps
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// 把这次训练计入最好成绩
+func (t *tracker) add(session models.WorkoutSession) {
+	order, byExercise := t.sets(session)
+	for _, exerciseID := range order {
+		b := t.best(exerciseID)
+		volume := 0.0
+		for _, set := range byExercise[exerciseID] {
+			b.weight = math.Max(b.weight, set.weight)
+			if set.reps > b.reps[set.weight] {
+				b.reps[set.weight] = set.reps
+			}
+			for _, formula := range Formulas {
+				b.e1rm[formula] = math.Max(b.e1rm[formula], E1RM(set.weight, set.reps, formula))
+			}
+			volume += float64(set.reps) * set.weight
+		}
+		b.volume = math.Max(b.volume, math.Round(volume*100)/100)
+	}
+}
+
+// 已完成的训练，按日期排序
+func completed(sessions []models.WorkoutSession) []models.WorkoutSession {
+	var result []models.WorkoutSession
+	for _, session := range sessions {
+		if session.IsCompleted {
+			result = append(result, session)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
+	})
+	return result
+}
+
+// Compute 按时间顺序重新计算所有已完成训练产生的个人记录
+func Compute(sessions []models.WorkoutSession, exercises []models.Exercise) []models.PersonalRecord {
+	t := newTracker(exercises)
+	result := []models.PersonalRecord{}
+	for _, session := range completed(sessions) {
+		result = append(result, t.check(session)...)
+		t.add(session)
+	}
+	return result
+}
+
+// Visible 去掉其他公式的 1RM 记录
+func Visible(list []models.PersonalRecord, formula string) []models.PersonalRecord {
+	result := []models.PersonalRecord{}
+	for _, record := range list {
+		if record.Type != models.RecordE1RM || record.Formula == formula {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+// Summary 一个动作当前的个人记录和全部历史
+type Summary struct {
+	ExerciseID string                  `json:"exerciseId"`
+	Formula    string                  `json:"formula"`
+	Weight     *models.PersonalRecord  `json:"weight"`
+	E1RM       *models.PersonalRecord  `json:"e1rm"`
+	Volume     *models.PersonalRecord  `json:"volume"`
+	Reps       []models.PersonalRecord `json:"reps"`    // 每个重量当前的最多次数，按重量从重到轻排列
+	History    []models.PersonalRecord `json:"history"` // 按时间倒序
+}
+
+// Summarize 汇总一个动作的个人记录，记录只会越来越好，所以每种记录最新的一条就是当前记录
+func Summarize(exerciseID string, list []models.PersonalRecord, formula string) Summary {
+	history := Visible(list, formula)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].AchievedAt.After(history[j].AchievedAt)
+	})
+
+	summary := Summary{ExerciseID: exerciseID, Formula: formula, Reps: []models.PersonalRecord{}, History: history}
+	byWeight := make(map[float64]bool)
+	for i := range history {
+		record := &history[i]
+		switch record.Type {
+		case models.RecordWeight:
+			if summary.Weight == nil {
+				summary.Weight = record
+			}
+		case models.RecordE1RM:
+			if summary.E1RM == nil {
+				summary.E1RM = record
+			}
+		case models.RecordVolume:
+			if summary.Volume == nil {
+				summary.Volume = record
+			}
+		case models.RecordReps:
+			if !byWeight[record.Weight] {
+				byWeight[record.Weight] = true
+				summary.Reps = append(summary.Reps, *record)
+			}
+		}
+	}
+
+	// 更重的重量做到了同样多的次数时，较轻重量的记录已经没有意义
+	sort.SliceStable(summary.Reps, func(i, j int) bool {
+		return summary.Reps[i].Weight > summary.Reps[j].Weight
+	})
+	reps := summary.Reps[:0]
+	most := 0.0
+	for _, record := range summary.Reps {
+		if record.Value > most {
+			reps = append(reps, record)
+			most = record.Value
+		}
+	}
+	summary.Reps = reps
+	return summary
+}
+
+// 训练的完成时间，没有结束时间时使用训练日期
+func finishedAt(session models.WorkoutSession) time.Time {
+	if session.EndTime.IsZero() {
+		return session.Date
+	}
+	return session.EndTime
+}
@@ -0,0 +1,260 @@
+package records
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"workout-tracker/models"
+)
+
+func TestE1RM(t *testing.T) {
+	tests := []struct {
+		weight  float64
+		reps    int
+		formula string
+		want    float64
+	}{
+		{100, 5, models.FormulaEpley, 116.7},
+		{100, 5, models.FormulaBrzycki, 112.5},
+		{100, 5, models.FormulaLombardi, 117.5},
+		{100, 10, models.FormulaEpley, 133.3},
+		{100, 5, "", 116.7}, // 未知公式按 Epley 计算
+		{100, 1, models.FormulaBrzycki, 100},
+		{100, MaxE1RMReps, models.FormulaEpley, 140},
+		{100, MaxE1RMReps + 1, models.FormulaEpley, 0},
+		{0, 5, models.FormulaEpley, 0},
+		{100, 0, models.FormulaEpley, 0},
+	}
+	for _, tt := range tests {
+		if got := E1RM(tt.weight, tt.reps, tt.formula); got != tt.want {
+			t.Errorf("E1RM(%g, %d, %q) = %g, want %g", tt.weight, tt.reps, tt.formula, got, tt.want)
+		}
+	}
+}
+
+// 一个动作的已完成各组，sets 为 {次数, 重量}
+func logged(exerciseID string, sets ...[2]float64) models.CompletedExercise {
+	completed := models.CompletedExercise{ExerciseID: exerciseID, CompletedSets: len(sets)}
+	for _, set := range sets {
+		completed.CompletedReps = append(completed.CompletedReps, int(set[0]))
+		completed.CompletedWeights = append(completed.CompletedWeights, set[1])
+	}
+	return completed
+}
+
+func sessionOn(id string, day int, exercises ...models.CompletedExercise) models.WorkoutSession {
+	return models.WorkoutSession{
+		ID:          id,
+		Date:        time.Date(2026, 1, day, 18, 0, 0, 0, time.UTC),
+		IsCompleted: true,
+		Exercises:   exercises,
+	}
+}
+
+// 记录的简短表示：训练 类型 数值/之前的记录 重量x次数
+func describe(list []models.PersonalRecord) []string {
+	var result []string
+	for _, r := range list {
+		result = append(result, fmt.Sprintf("%s %s %s %g/%g %gx%d", r.SessionID, r.ExerciseID, r.Type, r.Value, r.Previous, r.Weight, r.Reps))
+	}
+	return result
+}
+
+func TestCompute(t *testing.T) {
+	incomplete := sessionOn("s0", 1, logged("bench", [2]float64{5, 200}))
+	incomplete.IsCompleted = false
+	partial := logged("bench", [2]float64{0, 120}, [2]float64{5, 100}, [2]float64{10, 100})
+	partial.CompletedSets = 2
+
+	tests := []struct {
+		name     string
+		sessions []models.WorkoutSession
+		want     []string
+	}{
+		{
+			name:     "first session sets every record",
+			sessions: []models.WorkoutSession{sessionOn("s1", 5, logged("bench", [2]float64{5, 100}, [2]float64{3, 110}))},
+			want: []string{
+				"s1 bench weight 110/0 110x3",
+				"s1 bench reps 5/0 100x5",
+				"s1 bench reps 3/0 110x3",
+				"s1 bench e1rm 121/0 110x3",
+				"s1 bench volume 830/0 0x8",
+			},
+		},
+		{
+			name: "sessions are replayed by date",
+			sessions: []models.WorkoutSession{
+				sessionOn("s2", 6, logged("bench", [2]float64{6, 100})),
+				sessionOn("s1", 5, logged("bench", [2]float64{5, 100})),
+			},
+			want: []string{
+				"s1 bench weight 100/0 100x5",
+				"s1 bench reps 5/0 100x5",
+				"s1 bench e1rm 116.7/0 100x5",
+				"s1 bench volume 500/0 0x5",
+				"s2 bench reps 6/5 100x6",
+				"s2 bench e1rm 120/116.7 100x6",
+				"s2 bench volume 600/500 0x6",
+			},
+		},
+		{
+			name: "more reps at a lighter weight",
+			sessions: []models.WorkoutSession{
+				sessionOn("s1", 5, logged("bench", [2]float64{5, 100})),
+				sessionOn("s2", 6, logged("bench", [2]float64{5, 90})),
+				sessionOn("s3", 7, logged("bench", [2]float64{8, 90})),
+			},
+			want: []string{
+				"s1 bench weight 100/0 100x5",
+				"s1 bench reps 5/0 100x5",
+				"s1 bench e1rm 116.7/0 100x5",
+				"s1 bench volume 500/0 0x5",
+				"s3 bench reps 8/5 90x8",
+				"s3 bench volume 720/500 0x8",
+			},
+		},
+		{
+			name:     "lighter set dominated by a heavier one",
+			sessions: []models.WorkoutSession{sessionOn("s1", 5, logged("bench", [2]float64{5, 100}, [2]float64{5, 90}, [2]float64{5, 100}))},
+			want: []string{
+				"s1 bench weight 100/0 100x5",
+				"s1 bench reps 5/0 100x5",
+				"s1 bench e1rm 116.7/0 100x5",
+				"s1 bench volume 1450/0 0x15",
+			},
+		},
+		{
+			name: "skips incomplete sessions, unfinished sets and timed exercises",
+			sessions: []models.WorkoutSession{
+				incomplete,
+				sessionOn("s1", 5, partial, logged("plank", [2]float64{60, 0})),
+			},
+			want: []string{
+				"s1 bench weight 100/0 100x5",
+				"s1 bench reps 5/0 100x5",
+				"s1 bench e1rm 116.7/0 100x5",
+				"s1 bench volume 500/0 0x5",
+			},
+		},
+		{
+			name: "bodyweight exercise only has rep records",
+			sessions: []models.WorkoutSession{
+				sessionOn("s1", 5, logged("pushup", [2]float64{20, 0})),
+				sessionOn("s2", 6, logged("pushup", [2]float64{25, 0})),
+			},
+			want: []string{
+				"s1 pushup reps 20/0 0x20",
+				"s2 pushup reps 25/20 0x25",
+			},
+		},
+	}
+	exercises := []models.Exercise{
+		{ID: "bench"},
+		{ID: "plank", Tracking: models.TrackTime},
+		{ID: "pushup", Tracking: models.TrackReps},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describe(Visible(Compute(tt.sessions, exercises), models.FormulaEpley))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("records:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeRecordsEveryFormula(t *testing.T) {
+	list := Compute([]models.WorkoutSession{sessionOn("s1", 5, logged("bench", [2]float64{5, 100}))}, nil)
+	want := map[string]float64{models.FormulaEpley: 116.7, models.FormulaBrzycki: 112.5, models.FormulaLombardi: 117.5}
+	for _, formula := range Formulas {
+		visible := Visible(list, formula)
+		found := false
+		for _, r := range visible {
+			if r.Type != models.RecordE1RM {
+				continue
+			}
+			if found || r.Formula != formula || r.Value != want[formula] {
+				t.Errorf("%s: unexpected record %+v", formula, r)
+			}
+			found = true
+		}
+		if !found {
+			t.Errorf("%s: no e1rm record", formula)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 18, 0, 0, 0, time.UTC) }
+	rec := func(recordType string, value, weight float64, d int) models.PersonalRecord {
+		return models.PersonalRecord{ExerciseID: "bench", Type: recordType, Value: value, Weight: weight, AchievedAt: day(d)}
+	}
+	e1rm := func(formula string, value float64, d int) models.PersonalRecord {
+		r := rec(models.RecordE1RM, value, 100, d)
+		r.Formula = formula
+		return r
+	}
+
+	list := []models.PersonalRecord{
+		rec(models.RecordWeight, 100, 100, 1),
+		rec(models.RecordWeight, 110, 110, 3),
+		rec(models.RecordVolume, 500, 0, 1),
+		rec(models.RecordVolume, 900, 0, 2),
+		e1rm(models.FormulaEpley, 116.7, 1),
+		e1rm(models.FormulaBrzycki, 112.5, 1),
+		e1rm(models.FormulaEpley, 120, 2),
+		rec(models.RecordReps, 5, 100, 1),
+		rec(models.RecordReps, 6, 100, 2),
+		rec(models.RecordReps, 8, 90, 1),
+		// 110 公斤做到 8 次后，90 和 100 公斤的次数记录都不再有意义
+		rec(models.RecordReps, 8, 110, 4),
+		rec(models.RecordReps, 12, 60, 1),
+	}
+
+	tests := []struct {
+		formula string
+		weight  float64
+		e1rm    float64
+		volume  float64
+		reps    []string // 重量x次数
+		history int
+	}{
+		{models.FormulaEpley, 110, 120, 900, []string{"110x8", "60x12"}, 11},
+		{models.FormulaBrzycki, 110, 112.5, 900, []string{"110x8", "60x12"}, 10},
+		{models.FormulaLombardi, 110, 0, 900, []string{"110x8", "60x12"}, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.formula, func(t *testing.T) {
+			summary := Summarize("bench", list, tt.formula)
+			if summary.Weight == nil || summary.Weight.Value != tt.weight {
+				t.Errorf("weight = %+v, want %g", summary.Weight, tt.weight)
+			}
+			e1rm := 0.0
+			if summary.E1RM != nil {
+				e1rm = summary.E1RM.Value
+			}
+			if e1rm != tt.e1rm {
+				t.Errorf("e1rm = %g, want %g", e1rm, tt.e1rm)
+			}
+			if summary.Volume == nil || summary.Volume.Value != tt.volume {
+				t.Errorf("volume = %+v, want %g", summary.Volume, tt.volume)
+			}
+			var reps []string
+			for _, r := range summary.Reps {
+				reps = append(reps, fmt.Sprintf("%gx%g", r.Weight, r.Value))
+			}
+			if fmt.Sprint(reps) != fmt.Sprint(tt.reps) {
+				t.Errorf("reps = %v, want %v", reps, tt.reps)
+			}
+			if len(summary.History) != tt.history {
+				t.Errorf("history has %d records, want %d", len(summary.History), tt.history)
+			}
+			for i := 1; i < len(summary.History); i++ {
+				if summary.History[i].AchievedAt.After(summary.History[i-1].AchievedAt) {
+					t.Fatalf("history is not newest first: %+v", summary.History)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"workout-tracker/models"
+)
+
+// CalendarFeed 相关方法
+func (r *FileRepository) GetAllCalendarFeeds() ([]models.CalendarFeed, error) {
+	var feeds []models.CalendarFeed
+	err := r.readJSONFile("calendar_feeds.json", &feeds)
+	return feeds, err
+}
+
+func (r *FileRepository) GetCalendarFeedByTokenHash(tokenHash string) (*models.CalendarFeed, error) {
+	feeds, err := r.GetAllCalendarFeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, feed := range feeds {
+		if feed.TokenHash == tokenHash {
+			return &feed, nil
+		}
+	}
+	return nil, fmt.Errorf("calendar feed not found")
+}
+
+func (r *FileRepository) SaveCalendarFeed(feed models.CalendarFeed) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feeds, err := r.GetAllCalendarFeeds()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, f := range feeds {
+		if f.ID == feed.ID {
+			feeds[i] = feed
+			found = true
+			break
+		}
+	}
+	if !found {
+		feeds = append(feeds, feed)
+	}
+
+	return r.writeJSONFile("calendar_feeds.json", feeds)
+}
+
+func (r *FileRepository) DeleteCalendarFeed(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feeds, err := r.GetAllCalendarFeeds()
+	if err != nil {
+		return err
+	}
+
+	for i, feed := range feeds {
+		if feed.ID == id {
+			feeds = append(feeds[:i], feeds[i+1:]...)
+			return r.writeJSONFile("calendar_feeds.json", feeds)
+		}
+	}
+	return fmt.Errorf("calendar feed not found")
+}
+
+// 记录订阅最后一次被拉取的时间
+func (r *FileRepository) TouchCalendarFeed(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feeds, err := r.GetAllCalendarFeeds()
+	if err != nil {
+		return err
+	}
+
+	for i, feed := range feeds {
+		if feed.ID == id {
+			feeds[i].LastAccessedAt = &at
+			return r.writeJSONFile("calendar_feeds.json", feeds)
+		}
+	}
+	return fmt.Errorf("calendar feed not found")
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+	"workout-tracker/models"
+)
+
+// 已处理的客户端变更ID保留30天
+const mutationRetention = 30 * 24 * time.Hour
+
+func changeKey(entity, id string) string {
+	return entity + "/" + id
+}
+
+func (r *FileRepository) GetChangeLog() (*models.ChangeLog, error) {
+	var changeLog models.ChangeLog
+	if err := r.readJSONFile("changes.json", &changeLog); err != nil {
+		return nil, err
+	}
+	if changeLog.FieldClocks == nil {
+		changeLog.FieldClocks = make(map[string]map[string]time.Time)
+	}
+	if changeLog.Mutations == nil {
+		changeLog.Mutations = make(map[string]time.Time)
+	}
+	return &changeLog, nil
+}
+
+func (r *FileRepository) saveChangeLog(changeLog *models.ChangeLog) error {
+	return r.writeJSONFile("changes.json", changeLog)
+}
+
+// 一次待记录的实体变更
+type pendingChange struct {
+	entity string
+	id     string
+	op     string
+	before interface{}
+	after  interface{}
+}
+
+// 记录一次实体变更，before/after 用于计算被修改的字段
+func (r *FileRepository) recordChange(entity, id, op string, before, after interface{}) error {
+	return r.recordChanges([]pendingChange{{entity: entity, id: id, op: op, before: before, after: after}})
+}
+
+// 批量记录实体变更，只写一次变更日志。调用方需要持有 r.mu
+func (r *FileRepository) recordChanges(pending []pendingChange) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	changeLog, err := r.GetChangeLog()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	latest := make(map[string]bool)
+	for _, change := range pending {
+		key := changeKey(change.entity, change.id)
+		latest[key] = true
+		if change.op == models.ChangeDelete {
+			delete(changeLog.FieldClocks, key)
+			continue
+		}
+		clocks := changeLog.FieldClocks[key]
+		if clocks == nil {
+			clocks = make(map[string]time.Time)
+			changeLog.FieldClocks[key] = clocks
+		}
+		for _, field := range changedFields(change.before, change.after) {
+			clocks[field] = now
+		}
+	}
+
+	// 每个实体只保留最后一次变更记录
+	var changes []models.ChangeRecord
+	for _, change := range changeLog.Changes {
+		if !latest[changeKey(change.Entity, change.EntityID)] {
+			changes = append(changes, change)
+		}
+	}
+	for _, change := range pending {
+		changeLog.LastSeq++
+		changes = append(changes, models.ChangeRecord{
+			Seq:       changeLog.LastSeq,
+			Entity:    change.entity,
+			EntityID:  change.id,
+			Op:        change.op,
+			ChangedAt: now,
+		})
+	}
+	changeLog.Changes = changes
+
+	if err := r.saveChangeLog(changeLog); err != nil {
+		return err
+	}
+	for _, change := range pending {
+		r.logger().Debug("entity changed", "entity", change.entity, "entity_id", change.id, "op", change.op)
+	}
+	return nil
+}
+
+// 比较两个对象的顶层JSON字段，返回不同的字段名
+func changedFields(before, after interface{}) []string {
+	beforeFields := toFieldMap(before)
+	afterFields := toFieldMap(after)
+
+	var fields []string
+	for field, value := range afterFields {
+		if old, ok := beforeFields[field]; !ok || string(old) != string(value) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func toFieldMap(v interface{}) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if v == nil {
+		return fields
+	}
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(bytes, &fields)
+	return fields
+}
+
+// SetFieldClocks 覆盖实体字段的修改时间，同步时使用客户端的修改时间
+func (r *FileRepository) SetFieldClocks(entity, id string, clocks map[string]time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changeLog, err := r.GetChangeLog()
+	if err != nil {
+		return err
+	}
+
+	key := changeKey(entity, id)
+	if changeLog.FieldClocks[key] == nil {
+		changeLog.FieldClocks[key] = make(map[string]time.Time)
+	}
+	for field, t := range clocks {
+		changeLog.FieldClocks[key][field] = t
+	}
+	return r.saveChangeLog(changeLog)
+}
+
+// GetFieldClocks 返回实体各字段的最后修改时间
+func (r *FileRepository) GetFieldClocks(entity, id string) (map[string]time.Time, error) {
+	changeLog, err := r.GetChangeLog()
+	if err != nil {
+		return nil, err
+	}
+	return changeLog.FieldClocks[changeKey(entity, id)], nil
+}
+
+// GetChangesSince 返回序号大于 seq 的变更记录和当前最大序号
+func (r *FileRepository) GetChangesSince(seq int64) ([]models.ChangeRecord, int64, error) {
+	changeLog, err := r.GetChangeLog()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result []models.ChangeRecord
+	for _, change := range changeLog.Changes {
+		if change.Seq > seq {
+			result = append(result, change)
+		}
+	}
+	return result, changeLog.LastSeq, nil
+}
+
+// IsMutationProcessed 检查客户端变更是否已经处理过
+func (r *FileRepository) IsMutationProcessed(mutationID string) (bool, error) {
+	changeLog, err := r.GetChangeLog()
+	if err != nil {
+		return false, err
+	}
+	_, ok := changeLog.Mutations[mutationID]
+	return ok, nil
+}
+
+// MarkMutationProcessed 记录已处理的客户端变更，并清理过期记录
+func (r *FileRepository) MarkMutationProcessed(mutationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changeLog, err := r.GetChangeLog()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for id, processedAt := range changeLog.Mutations {
+		if now.Sub(processedAt) > mutationRetention {
+			delete(changeLog.Mutations, id)
+		}
+	}
+	changeLog.Mutations[mutationID] = now
+	return r.saveChangeLog(changeLog)
+}
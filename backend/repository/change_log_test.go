@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"workout-tracker/models"
+)
+
+func TestConcurrentSavesGetContiguousSeqs(t *testing.T) {
+	repo := NewFileRepository(t.TempDir())
+
+	const n = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- repo.SaveSession(models.WorkoutSession{ID: fmt.Sprintf("s%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions, err := repo.GetAllSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != n {
+		t.Fatalf("saved %d sessions, got %d", n, len(sessions))
+	}
+
+	changes, lastSeq, err := repo.GetChangesSince(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastSeq != n || len(changes) != n {
+		t.Fatalf("lastSeq = %d, changes = %d, want %d", lastSeq, len(changes), n)
+	}
+	seqs := make([]int64, 0, n)
+	ids := make(map[string]bool)
+	for _, change := range changes {
+		seqs = append(seqs, change.Seq)
+		ids[change.EntityID] = true
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Fatalf("seqs not contiguous: %v", seqs)
+		}
+	}
+	if len(ids) != n {
+		t.Fatalf("changes cover %d sessions, want %d", len(ids), n)
+	}
+}
+
+func TestConcurrentMutationsAreKept(t *testing.T) {
+	repo := NewFileRepository(t.TempDir())
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if err := repo.MarkMutationProcessed(fmt.Sprintf("m%d", i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			if err := repo.SaveExercise(models.Exercise{ID: fmt.Sprintf("e%d", i)}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		ok, err := repo.IsMutationProcessed(fmt.Sprintf("m%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("mutation m%d lost", i)
+		}
+	}
+	_, lastSeq, err := repo.GetChangesSince(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastSeq != n {
+		t.Errorf("lastSeq = %d, want %d", lastSeq, n)
+	}
+}
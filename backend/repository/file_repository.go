@@ -1,21 +1,81 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
+	"workout-tracker/logging"
+	"workout-tracker/metrics"
 	"workout-tracker/models"
 )
 
 type FileRepository struct {
 	dataDir string
+	ctx     context.Context
+
+	// 写入时持有读锁，Flush 通过获取写锁等待所有写入完成
+	writes *sync.RWMutex
+
+	// 修改数据的方法在读取、修改、写回数据文件和变更日志期间持有，
+	// 避免并发请求读到同一份旧数据后互相覆盖，或分配到重复的变更序号
+	mu *sync.Mutex
 }
 
 func NewFileRepository(dataDir string) *FileRepository {
-	return &FileRepository{dataDir: dataDir}
+	return &FileRepository{dataDir: dataDir, writes: &sync.RWMutex{}, mu: &sync.Mutex{}}
+}
+
+// DataDir 数据文件所在目录
+func (r *FileRepository) DataDir() string {
+	return r.dataDir
+}
+
+// Flush 等待正在进行的写入完成，关闭服务前调用
+func (r *FileRepository) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.writes.Lock()
+		r.writes.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithContext 返回绑定请求 context 的仓库，日志中会带上请求ID等信息
+func (r *FileRepository) WithContext(ctx context.Context) *FileRepository {
+	scoped := *r
+	scoped.ctx = ctx
+	return &scoped
+}
+
+func (r *FileRepository) logger() *slog.Logger {
+	if r.ctx == nil {
+		return logging.Component("repository")
+	}
+	return logging.FromContext(r.ctx)
+}
+
+// 记录一次数据文件读写的耗时和错误
+func (r *FileRepository) observe(operation, filename string, start time.Time, err error) {
+	metrics.ObserveRepository(operation, filename, start, err)
+	duration := float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		r.logger().Error("repository "+operation+" failed", "operation", operation, "file", filename, "duration_ms", duration, "error", err)
+		return
+	}
+	r.logger().Debug("repository "+operation, "operation", operation, "file", filename, "duration_ms", duration)
 }
 
 // 确保数据目录存在
@@ -24,7 +84,11 @@ func (r *FileRepository) ensureDataDir() error {
 }
 
 // 读取JSON文件
-func (r *FileRepository) readJSONFile(filename string, data interface{}) error {
+func (r *FileRepository) readJSONFile(filename string, data interface{}) (err error) {
+	defer func(start time.Time) {
+		r.observe("read", filename, start, err)
+	}(time.Now())
+
 	filePath := filepath.Join(r.dataDir, filename)
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -48,7 +112,14 @@ func (r *FileRepository) readJSONFile(filename string, data interface{}) error {
 }
 
 // 写入JSON文件
-func (r *FileRepository) writeJSONFile(filename string, data interface{}) error {
+func (r *FileRepository) writeJSONFile(filename string, data interface{}) (err error) {
+	defer func(start time.Time) {
+		r.observe("write", filename, start, err)
+	}(time.Now())
+
+	r.writes.RLock()
+	defer r.writes.RUnlock()
+
 	if err := r.ensureDataDir(); err != nil {
 		return err
 	}
@@ -58,8 +129,28 @@ func (r *FileRepository) writeJSONFile(filename string, data interface{}) error
 		return err
 	}
 
-	filePath := filepath.Join(r.dataDir, filename)
-	return ioutil.WriteFile(filePath, bytes, 0644)
+	// 先写临时文件再重命名，进程中途退出也不会留下写了一半的数据文件
+	tmp, err := os.CreateTemp(r.dataDir, filename+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(r.dataDir, filename))
 }
 
 // Exercise 相关方法
@@ -70,6 +161,9 @@ func (r *FileRepository) GetAllExercises() ([]models.Exercise, error) {
 }
 
 func (r *FileRepository) SaveExercise(exercise models.Exercise) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	exercises, err := r.GetAllExercises()
 	if err != nil {
 		return err
@@ -77,8 +171,10 @@ func (r *FileRepository) SaveExercise(exercise models.Exercise) error {
 
 	// 检查是否已存在，更新或添加
 	found := false
+	var before interface{}
 	for i, ex := range exercises {
 		if ex.ID == exercise.ID {
+			before = ex
 			exercises[i] = exercise
 			found = true
 			break
@@ -88,7 +184,10 @@ func (r *FileRepository) SaveExercise(exercise models.Exercise) error {
 		exercises = append(exercises, exercise)
 	}
 
-	return r.writeJSONFile("exercises.json", exercises)
+	if err := r.writeJSONFile("exercises.json", exercises); err != nil {
+		return err
+	}
+	return r.recordChange(models.EntityExercise, exercise.ID, models.ChangeUpsert, before, exercise)
 }
 
 func (r *FileRepository) GetExerciseByID(id string) (*models.Exercise, error) {
@@ -106,6 +205,9 @@ func (r *FileRepository) GetExerciseByID(id string) (*models.Exercise, error) {
 }
 
 func (r *FileRepository) DeleteExercise(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	exercises, err := r.GetAllExercises()
 	if err != nil {
 		return err
@@ -114,7 +216,10 @@ func (r *FileRepository) DeleteExercise(id string) error {
 	for i, exercise := range exercises {
 		if exercise.ID == id {
 			exercises = append(exercises[:i], exercises[i+1:]...)
-			return r.writeJSONFile("exercises.json", exercises)
+			if err := r.writeJSONFile("exercises.json", exercises); err != nil {
+				return err
+			}
+			return r.recordChange(models.EntityExercise, id, models.ChangeDelete, nil, nil)
 		}
 	}
 	return fmt.Errorf("exercise not found")
@@ -128,14 +233,19 @@ func (r *FileRepository) GetAllWorkouts() ([]models.Workout, error) {
 }
 
 func (r *FileRepository) SaveWorkout(workout models.Workout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	workouts, err := r.GetAllWorkouts()
 	if err != nil {
 		return err
 	}
 
 	found := false
+	var before interface{}
 	for i, w := range workouts {
 		if w.ID == workout.ID {
+			before = w
 			workouts[i] = workout
 			found = true
 			break
@@ -145,7 +255,10 @@ func (r *FileRepository) SaveWorkout(workout models.Workout) error {
 		workouts = append(workouts, workout)
 	}
 
-	return r.writeJSONFile("workouts.json", workouts)
+	if err := r.writeJSONFile("workouts.json", workouts); err != nil {
+		return err
+	}
+	return r.recordChange(models.EntityWorkout, workout.ID, models.ChangeUpsert, before, workout)
 }
 
 func (r *FileRepository) GetWorkoutByID(id string) (*models.Workout, error) {
@@ -163,6 +276,9 @@ func (r *FileRepository) GetWorkoutByID(id string) (*models.Workout, error) {
 }
 
 func (r *FileRepository) DeleteWorkout(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	workouts, err := r.GetAllWorkouts()
 	if err != nil {
 		return err
@@ -171,7 +287,10 @@ func (r *FileRepository) DeleteWorkout(id string) error {
 	for i, workout := range workouts {
 		if workout.ID == id {
 			workouts = append(workouts[:i], workouts[i+1:]...)
-			return r.writeJSONFile("workouts.json", workouts)
+			if err := r.writeJSONFile("workouts.json", workouts); err != nil {
+				return err
+			}
+			return r.recordChange(models.EntityWorkout, id, models.ChangeDelete, nil, nil)
 		}
 	}
 	return fmt.Errorf("workout not found")
@@ -185,14 +304,19 @@ func (r *FileRepository) GetAllSessions() ([]models.WorkoutSession, error) {
 }
 
 func (r *FileRepository) SaveSession(session models.WorkoutSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	sessions, err := r.GetAllSessions()
 	if err != nil {
 		return err
 	}
 
 	found := false
+	var before interface{}
 	for i, s := range sessions {
 		if s.ID == session.ID {
+			before = s
 			sessions[i] = session
 			found = true
 			break
@@ -202,7 +326,10 @@ func (r *FileRepository) SaveSession(session models.WorkoutSession) error {
 		sessions = append(sessions, session)
 	}
 
-	return r.writeJSONFile("sessions.json", sessions)
+	if err := r.writeJSONFile("sessions.json", sessions); err != nil {
+		return err
+	}
+	return r.recordChange(models.EntitySession, session.ID, models.ChangeUpsert, before, session)
 }
 
 func (r *FileRepository) GetSessionsByDateRange(start, end time.Time) ([]models.WorkoutSession, error) {
@@ -233,3 +360,93 @@ func (r *FileRepository) GetSessionByID(id string) (*models.WorkoutSession, erro
 	}
 	return nil, fmt.Errorf("session not found")
 }
+
+func (r *FileRepository) DeleteSession(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions, err := r.GetAllSessions()
+	if err != nil {
+		return err
+	}
+
+	for i, session := range sessions {
+		if session.ID == id {
+			sessions = append(sessions[:i], sessions[i+1:]...)
+			if err := r.writeJSONFile("sessions.json", sessions); err != nil {
+				return err
+			}
+			return r.recordChange(models.EntitySession, id, models.ChangeDelete, nil, nil)
+		}
+	}
+	return fmt.Errorf("session not found")
+}
+
+// IdempotencyRecord 相关方法
+func (r *FileRepository) GetAllIdempotencyRecords() ([]models.IdempotencyRecord, error) {
+	var records []models.IdempotencyRecord
+	err := r.readJSONFile("idempotency.json", &records)
+	return records, err
+}
+
+func (r *FileRepository) GetIdempotencyRecord(method, path, key string) (*models.IdempotencyRecord, error) {
+	records, err := r.GetAllIdempotencyRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Method == method && record.Path == path && record.Key == key {
+			return &record, nil
+		}
+	}
+	return nil, fmt.Errorf("idempotency record not found")
+}
+
+func (r *FileRepository) SaveIdempotencyRecord(record models.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records, err := r.GetAllIdempotencyRecords()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, rec := range records {
+		if rec.Method == record.Method && rec.Path == record.Path && rec.Key == record.Key {
+			records[i] = record
+			found = true
+			break
+		}
+	}
+	if !found {
+		records = append(records, record)
+	}
+
+	return r.writeJSONFile("idempotency.json", records)
+}
+
+// 删除早于 before 的幂等记录，返回删除数量
+func (r *FileRepository) DeleteIdempotencyRecordsBefore(before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records, err := r.GetAllIdempotencyRecords()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []models.IdempotencyRecord
+	for _, record := range records {
+		if record.CreatedAt.After(before) {
+			kept = append(kept, record)
+		}
+	}
+
+	removed := len(records) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, r.writeJSONFile("idempotency.json", kept)
+}
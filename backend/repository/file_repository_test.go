@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"workout-tracker/models"
+)
+
+func TestIdempotencySaveDuringCleanup(t *testing.T) {
+	repo := NewFileRepository(t.TempDir())
+
+	// 过期记录，清理时会被删除
+	const expired = 20
+	for i := 0; i < expired; i++ {
+		record := models.IdempotencyRecord{Method: "POST", Path: "/api/sessions", Key: fmt.Sprintf("old%d", i), CreatedAt: time.Now().Add(-48 * time.Hour)}
+		if err := repo.SaveIdempotencyRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const n = 30
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			record := models.IdempotencyRecord{Method: "POST", Path: "/api/sessions", Key: fmt.Sprintf("new%d", i), CreatedAt: time.Now()}
+			if err := repo.SaveIdempotencyRecord(record); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.DeleteIdempotencyRecordsBefore(time.Now().Add(-24 * time.Hour)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records, err := repo.GetAllIdempotencyRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != n {
+		t.Fatalf("got %d records, want %d", len(records), n)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := repo.GetIdempotencyRecord("POST", "/api/sessions", fmt.Sprintf("new%d", i)); err != nil {
+			t.Errorf("record new%d lost", i)
+		}
+	}
+}
+
+func TestFlushWaitsForWrites(t *testing.T) {
+	repo := NewFileRepository(t.TempDir())
+
+	// 模拟一次正在进行的写入
+	repo.writes.RLock()
+	flushed := make(chan error, 1)
+	go func() { flushed <- repo.Flush(context.Background()) }()
+	select {
+	case <-flushed:
+		t.Fatal("Flush returned while a write was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	repo.writes.RUnlock()
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+
+	// 超时后不再等待
+	repo.writes.RLock()
+	defer repo.writes.RUnlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := repo.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush = %v, want deadline exceeded", err)
+	}
+}
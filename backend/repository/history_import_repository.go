@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"workout-tracker/models"
+)
+
+// HistoryImport 相关方法
+func (r *FileRepository) GetAllHistoryImports() ([]models.HistoryImport, error) {
+	var imports []models.HistoryImport
+	err := r.readJSONFile("history_imports.json", &imports)
+	return imports, err
+}
+
+func (r *FileRepository) GetHistoryImportByID(id string) (*models.HistoryImport, error) {
+	imports, err := r.GetAllHistoryImports()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range imports {
+		if item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("import not found")
+}
+
+func (r *FileRepository) SaveHistoryImport(item models.HistoryImport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imports, err := r.GetAllHistoryImports()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, existing := range imports {
+		if existing.ID == item.ID {
+			imports[i] = item
+			found = true
+			break
+		}
+	}
+	if !found {
+		imports = append(imports, item)
+	}
+
+	return r.writeJSONFile("history_imports.json", imports)
+}
+
+func (r *FileRepository) DeleteHistoryImport(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imports, err := r.GetAllHistoryImports()
+	if err != nil {
+		return err
+	}
+
+	for i, item := range imports {
+		if item.ID == id {
+			imports = append(imports[:i], imports[i+1:]...)
+			return r.writeJSONFile("history_imports.json", imports)
+		}
+	}
+	return fmt.Errorf("import not found")
+}
+
+// 删除早于 before 的导入记录，返回删除数量
+func (r *FileRepository) DeleteHistoryImportsBefore(before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imports, err := r.GetAllHistoryImports()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []models.HistoryImport
+	for _, item := range imports {
+		if item.CreatedAt.After(before) {
+			kept = append(kept, item)
+		}
+	}
+
+	removed := len(imports) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, r.writeJSONFile("history_imports.json", kept)
+}
+
+// GetExerciseAliases 已确认的外部动作名称 -> 动作ID
+func (r *FileRepository) GetExerciseAliases() (map[string]string, error) {
+	aliases := make(map[string]string)
+	err := r.readJSONFile("exercise_aliases.json", &aliases)
+	return aliases, err
+}
+
+func (r *FileRepository) SaveExerciseAliases(updated map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	aliases, err := r.GetExerciseAliases()
+	if err != nil {
+		return err
+	}
+	for name, id := range updated {
+		aliases[name] = id
+	}
+	return r.writeJSONFile("exercise_aliases.json", aliases)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import "workout-tracker/models"
+
+// ImportData 批量写入导入的数据，每个文件只写一次
+// replace 为 true 时替换全部现有数据，否则按ID新增或覆盖
+func (r *FileRepository) ImportData(exercises []models.Exercise, workouts []models.Workout, sessions []models.WorkoutSession, replace bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existingExercises, err := r.GetAllExercises()
+	if err != nil {
+		return err
+	}
+	existingWorkouts, err := r.GetAllWorkouts()
+	if err != nil {
+		return err
+	}
+	existingSessions, err := r.GetAllSessions()
+	if err != nil {
+		return err
+	}
+
+	var changes []pendingChange
+	exerciseChanges, err := importEntities(r, models.EntityExercise, "exercises.json", existingExercises, exercises,
+		func(e models.Exercise) string { return e.ID }, replace)
+	if err != nil {
+		return err
+	}
+	changes = append(changes, exerciseChanges...)
+
+	workoutChanges, err := importEntities(r, models.EntityWorkout, "workouts.json", existingWorkouts, workouts,
+		func(w models.Workout) string { return w.ID }, replace)
+	if err != nil {
+		return err
+	}
+	changes = append(changes, workoutChanges...)
+
+	sessionChanges, err := importEntities(r, models.EntitySession, "sessions.json", existingSessions, sessions,
+		func(s models.WorkoutSession) string { return s.ID }, replace)
+	if err != nil {
+		return err
+	}
+	changes = append(changes, sessionChanges...)
+
+	return r.recordChanges(changes)
+}
+
+// 写入一种实体的导入数据，返回需要记录的变更
+func importEntities[T any](r *FileRepository, entity, filename string, existing, imported []T, id func(T) string, replace bool) ([]pendingChange, error) {
+	before := make(map[string]T)
+	index := make(map[string]int)
+	for i, item := range existing {
+		before[id(item)] = item
+		index[id(item)] = i
+	}
+
+	var changes []pendingChange
+	for _, item := range imported {
+		var previous interface{}
+		if old, ok := before[id(item)]; ok {
+			previous = old
+		}
+		changes = append(changes, pendingChange{entity: entity, id: id(item), op: models.ChangeUpsert, before: previous, after: item})
+	}
+
+	result := imported
+	if replace {
+		// 不在导入数据中的现有实体记为删除
+		kept := make(map[string]bool)
+		for _, item := range imported {
+			kept[id(item)] = true
+		}
+		for _, item := range existing {
+			if !kept[id(item)] {
+				changes = append(changes, pendingChange{entity: entity, id: id(item), op: models.ChangeDelete})
+			}
+		}
+	} else {
+		// 按ID合并，已存在的覆盖，不存在的追加
+		result = append([]T{}, existing...)
+		for _, item := range imported {
+			if i, ok := index[id(item)]; ok {
+				result[i] = item
+			} else {
+				index[id(item)] = len(result)
+				result = append(result, item)
+			}
+		}
+	}
+
+	if err := r.writeJSONFile(filename, result); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"workout-tracker/models"
+)
+
+// Media 相关方法
+func (r *FileRepository) GetAllMedia() ([]models.Media, error) {
+	var media []models.Media
+	err := r.readJSONFile("media.json", &media)
+	return media, err
+}
+
+func (r *FileRepository) SaveMedia(item models.Media) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media, err := r.GetAllMedia()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, m := range media {
+		if m.URL == item.URL {
+			media[i] = item
+			found = true
+			break
+		}
+	}
+	if !found {
+		media = append(media, item)
+	}
+
+	return r.writeJSONFile("media.json", media)
+}
+
+// GetImageVariants 返回图片地址对应的缩放版本，不是处理过的图片时返回 nil
+func (r *FileRepository) GetImageVariants(url string) ([]models.ImageVariant, error) {
+	if url == "" {
+		return nil, nil
+	}
+	media, err := r.GetAllMedia()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range media {
+		if m.URL == url {
+			return m.Variants, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetMedia 按地址查找上传记录，不存在时返回 nil
+func (r *FileRepository) GetMedia(url string) (*models.Media, error) {
+	if url == "" {
+		return nil, nil
+	}
+	media, err := r.GetAllMedia()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range media {
+		if m.URL == url {
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+// ResolveExerciseMedia 根据上传记录填写动作图片的缩放版本和演示视频的尺寸、时长、封面
+func (r *FileRepository) ResolveExerciseMedia(exercise *models.Exercise) error {
+	variants, err := r.GetImageVariants(exercise.ImageURL)
+	if err != nil {
+		return err
+	}
+	exercise.ImageVariants = variants
+
+	for i, clip := range exercise.Clips {
+		item, err := r.GetMedia(clip.URL)
+		if err != nil {
+			return err
+		}
+		// 没有上传记录（例如导入的数据）时保留客户端提供的信息
+		if item == nil {
+			continue
+		}
+		clip.Type, clip.Width, clip.Height = item.Type, item.Width, item.Height
+		clip.Duration, clip.Size = item.Duration, item.Size
+		if item.Poster != "" {
+			clip.Poster = item.Poster
+		}
+		exercise.Clips[i] = clip
+	}
+	return nil
+}
+
+// DeleteMedia 删除文件已被清理的上传记录
+func (r *FileRepository) DeleteMedia(urls ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media, err := r.GetAllMedia()
+	if err != nil {
+		return err
+	}
+
+	removed := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		removed[url] = true
+	}
+	kept := media[:0]
+	for _, m := range media {
+		if !removed[m.URL] {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == len(media) {
+		return nil
+	}
+	return r.writeJSONFile("media.json", kept)
+}
+
+// GetMediaByHash 按上传内容的 SHA-256 查找已处理过的文件
+func (r *FileRepository) GetMediaByHash(hash string) (*models.Media, error) {
+	media, err := r.GetAllMedia()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range media {
+		if m.Hash == hash {
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import "workout-tracker/models"
+
+// PersonalRecord 相关方法，个人记录由训练记录计算得出，不进入变更日志
+func (r *FileRepository) GetAllRecords() ([]models.PersonalRecord, error) {
+	var records []models.PersonalRecord
+	err := r.readJSONFile("records.json", &records)
+	return records, err
+}
+
+func (r *FileRepository) GetRecordsByExercise(exerciseID string) ([]models.PersonalRecord, error) {
+	records, err := r.GetAllRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.PersonalRecord{}
+	for _, record := range records {
+		if record.ExerciseID == exerciseID {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+func (r *FileRepository) GetRecordsBySession(sessionID string) ([]models.PersonalRecord, error) {
+	records, err := r.GetAllRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.PersonalRecord{}
+	for _, record := range records {
+		if record.SessionID == sessionID {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// SetSessionRecords 保存一次训练产生的个人记录，替换这次训练已有的记录，
+// 训练保存后、检测记录前其他请求已经重新计算过全部记录时不会重复追加
+func (r *FileRepository) SetSessionRecords(sessionID string, added []models.PersonalRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records, err := r.GetAllRecords()
+	if err != nil {
+		return err
+	}
+	result := []models.PersonalRecord{}
+	for _, record := range records {
+		if record.SessionID != sessionID {
+			result = append(result, record)
+		}
+	}
+	if len(result) == len(records) && len(added) == 0 {
+		return nil
+	}
+	return r.writeJSONFile("records.json", append(result, added...))
+}
+
+// ReplaceRecords 重新计算后整体替换个人记录
+func (r *FileRepository) ReplaceRecords(records []models.PersonalRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if records == nil {
+		records = []models.PersonalRecord{}
+	}
+	return r.writeJSONFile("records.json", records)
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"workout-tracker/models"
+)
+
+// TaxonomyTerm 相关方法
+func (r *FileRepository) GetAllTaxonomyTerms() ([]models.TaxonomyTerm, error) {
+	var terms []models.TaxonomyTerm
+	err := r.readJSONFile("taxonomy.json", &terms)
+	return terms, err
+}
+
+func (r *FileRepository) GetTaxonomyTermsByKind(kind string) ([]models.TaxonomyTerm, error) {
+	terms, err := r.GetAllTaxonomyTerms()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.TaxonomyTerm{}
+	for _, term := range terms {
+		if term.Kind == kind {
+			result = append(result, term)
+		}
+	}
+	return result, nil
+}
+
+func (r *FileRepository) GetTaxonomyTerm(kind, id string) (*models.TaxonomyTerm, error) {
+	terms, err := r.GetAllTaxonomyTerms()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, term := range terms {
+		if term.Kind == kind && term.ID == id {
+			return &term, nil
+		}
+	}
+	return nil, fmt.Errorf("taxonomy term not found")
+}
+
+func (r *FileRepository) SaveTaxonomyTerm(term models.TaxonomyTerm) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	terms, err := r.GetAllTaxonomyTerms()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, t := range terms {
+		if t.ID == term.ID {
+			terms[i] = term
+			found = true
+			break
+		}
+	}
+	if !found {
+		terms = append(terms, term)
+	}
+
+	return r.writeJSONFile("taxonomy.json", terms)
+}
+
+func (r *FileRepository) DeleteTaxonomyTerm(kind, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	terms, err := r.GetAllTaxonomyTerms()
+	if err != nil {
+		return err
+	}
+
+	for i, term := range terms {
+		if term.Kind == kind && term.ID == id {
+			terms = append(terms[:i], terms[i+1:]...)
+			return r.writeJSONFile("taxonomy.json", terms)
+		}
+	}
+	return fmt.Errorf("taxonomy term not found")
+}
+
+// SeedTaxonomy 分类文件不存在时写入默认分类，返回是否写入。
+// 文件存在时（即使分类被全部删除）不再写入
+func (r *FileRepository) SeedTaxonomy(terms []models.TaxonomyTerm) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := os.Stat(filepath.Join(r.dataDir, "taxonomy.json"))
+	if err == nil {
+		return false, nil
+	}
+	if !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, r.writeJSONFile("taxonomy.json", terms)
+}
+
+// ImportTaxonomy 批量写入导入的分类，replace 为 true 时替换全部现有分类，否则按ID新增或覆盖
+func (r *FileRepository) ImportTaxonomy(terms []models.TaxonomyTerm, replace bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := terms
+	if !replace {
+		existing, err := r.GetAllTaxonomyTerms()
+		if err != nil {
+			return err
+		}
+		index := make(map[string]int)
+		for i, term := range existing {
+			index[term.ID] = i
+		}
+		result = append([]models.TaxonomyTerm{}, existing...)
+		for _, term := range terms {
+			if i, ok := index[term.ID]; ok {
+				result[i] = term
+			} else {
+				index[term.ID] = len(result)
+				result = append(result, term)
+			}
+		}
+	}
+	return r.writeJSONFile("taxonomy.json", result)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"workout-tracker/models"
+)
+
+// UploadSession 相关方法
+func (r *FileRepository) GetAllUploadSessions() ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	err := r.readJSONFile("upload_sessions.json", &sessions)
+	return sessions, err
+}
+
+func (r *FileRepository) GetUploadSessionByID(id string) (*models.UploadSession, error) {
+	sessions, err := r.GetAllUploadSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		if session.ID == id {
+			return &session, nil
+		}
+	}
+	return nil, fmt.Errorf("upload session not found")
+}
+
+func (r *FileRepository) SaveUploadSession(session models.UploadSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions, err := r.GetAllUploadSessions()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, existing := range sessions {
+		if existing.ID == session.ID {
+			sessions[i] = session
+			found = true
+			break
+		}
+	}
+	if !found {
+		sessions = append(sessions, session)
+	}
+
+	return r.writeJSONFile("upload_sessions.json", sessions)
+}
+
+func (r *FileRepository) DeleteUploadSession(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions, err := r.GetAllUploadSessions()
+	if err != nil {
+		return err
+	}
+
+	for i, session := range sessions {
+		if session.ID == id {
+			sessions = append(sessions[:i], sessions[i+1:]...)
+			return r.writeJSONFile("upload_sessions.json", sessions)
+		}
+	}
+	return fmt.Errorf("upload session not found")
+}
+
+// 删除在 before 之前过期的上传会话，返回被删除会话的 ID
+func (r *FileRepository) DeleteUploadSessionsExpiredBefore(before time.Time) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions, err := r.GetAllUploadSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []models.UploadSession
+	var removed []string
+	for _, session := range sessions {
+		if session.ExpiresAt.Before(before) {
+			removed = append(removed, session.ID)
+			continue
+		}
+		kept = append(kept, session)
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, r.writeJSONFile("upload_sessions.json", kept)
+}
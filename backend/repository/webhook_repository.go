@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"workout-tracker/models"
+)
+
+// WebhookSubscription 相关方法
+func (r *FileRepository) GetAllWebhooks() ([]models.WebhookSubscription, error) {
+	var webhooks []models.WebhookSubscription
+	err := r.readJSONFile("webhooks.json", &webhooks)
+	return webhooks, err
+}
+
+func (r *FileRepository) GetWebhookByID(id string) (*models.WebhookSubscription, error) {
+	webhooks, err := r.GetAllWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		if webhook.ID == id {
+			return &webhook, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook not found")
+}
+
+func (r *FileRepository) SaveWebhook(webhook models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhooks, err := r.GetAllWebhooks()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, w := range webhooks {
+		if w.ID == webhook.ID {
+			webhooks[i] = webhook
+			found = true
+			break
+		}
+	}
+	if !found {
+		webhooks = append(webhooks, webhook)
+	}
+
+	return r.writeJSONFile("webhooks.json", webhooks)
+}
+
+func (r *FileRepository) DeleteWebhook(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhooks, err := r.GetAllWebhooks()
+	if err != nil {
+		return err
+	}
+
+	for i, webhook := range webhooks {
+		if webhook.ID == id {
+			webhooks = append(webhooks[:i], webhooks[i+1:]...)
+			return r.writeJSONFile("webhooks.json", webhooks)
+		}
+	}
+	return fmt.Errorf("webhook not found")
+}
+
+// WebhookDelivery 相关方法
+func (r *FileRepository) GetAllWebhookDeliveries() ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.readJSONFile("webhook_deliveries.json", &deliveries)
+	return deliveries, err
+}
+
+func (r *FileRepository) GetWebhookDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	deliveries, err := r.GetAllWebhookDeliveries()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delivery := range deliveries {
+		if delivery.ID == id {
+			return &delivery, nil
+		}
+	}
+	return nil, fmt.Errorf("delivery not found")
+}
+
+func (r *FileRepository) SaveWebhookDeliveries(updated ...models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries, err := r.GetAllWebhookDeliveries()
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]int)
+	for i, delivery := range deliveries {
+		index[delivery.ID] = i
+	}
+	for _, delivery := range updated {
+		if i, ok := index[delivery.ID]; ok {
+			deliveries[i] = delivery
+		} else {
+			index[delivery.ID] = len(deliveries)
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return r.writeJSONFile("webhook_deliveries.json", deliveries)
+}
+
+// 删除早于 before 的成功投递记录，待投递和死信记录保留
+func (r *FileRepository) DeleteSucceededDeliveriesBefore(before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries, err := r.GetAllWebhookDeliveries()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []models.WebhookDelivery
+	for _, delivery := range deliveries {
+		if delivery.Status == models.DeliverySucceeded && delivery.CreatedAt.Before(before) {
+			continue
+		}
+		kept = append(kept, delivery)
+	}
+
+	removed := len(deliveries) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, r.writeJSONFile("webhook_deliveries.json", kept)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"workout-tracker/handlers"
+	"workout-tracker/metrics"
+	"workout-tracker/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeHandlers 注册路由需要的处理器
+type routeHandlers struct {
+	workout    *handlers.WorkoutHandler
+	taxonomy   *handlers.TaxonomyHandler
+	stream     *handlers.StreamHandler
+	history    *handlers.HistoryHandler
+	sync       *handlers.SyncHandler
+	webhook    *handlers.WebhookHandler
+	archive    *handlers.ArchiveHandler
+	calendar   *handlers.CalendarHandler
+	upload     *handlers.UploadHandler
+	docs       *handlers.DocsHandler
+	health     *handlers.HealthHandler
+	idempotent gin.HandlerFunc
+}
+
+// registerRoutes 注册所有路由，新增路由需要同时写入 openapi.Routes
+func registerRoutes(r *gin.Engine, h routeHandlers) {
+	// 静态文件服务
+	r.GET("/uploads/*filepath", middleware.UploadHeaders(), h.upload.Serve)
+	r.HEAD("/uploads/*filepath", middleware.UploadHeaders(), h.upload.Serve)
+	r.Static("/static", "../frontend")
+
+	// API 路由
+	api := r.Group("/api")
+	{
+		// 动作相关
+		api.GET("/exercises", h.workout.GetExercises)
+		api.POST("/exercises", h.idempotent, h.workout.CreateExercise)
+		api.PUT("/exercises/:id", h.workout.UpdateExercise)
+		api.PATCH("/exercises/:id", h.workout.PatchExercise)
+		api.DELETE("/exercises/:id", h.workout.DeleteExercise)
+		api.GET("/exercises/:id/records", h.workout.GetExerciseRecords)
+
+		// 动作分类
+		api.GET("/taxonomy", h.taxonomy.GetTaxonomy)
+		api.POST("/taxonomy/migrate", h.taxonomy.Migrate)
+		api.GET("/taxonomy/:kind", h.taxonomy.GetTerms)
+		api.POST("/taxonomy/:kind", h.taxonomy.CreateTerm)
+		api.PUT("/taxonomy/:kind/:id", h.taxonomy.UpdateTerm)
+		api.DELETE("/taxonomy/:kind/:id", h.taxonomy.DeleteTerm)
+
+		// 训练计划相关
+		api.GET("/workouts", h.workout.GetWorkouts)
+		api.POST("/workouts", h.idempotent, h.workout.CreateWorkout)
+		api.GET("/workouts/:id", h.workout.GetWorkout)
+		api.GET("/workouts/:id/recommendations", h.workout.GetRecommendations)
+		api.PUT("/workouts/:id", h.workout.UpdateWorkout)
+		api.PATCH("/workouts/:id", h.workout.PatchWorkout)
+		api.DELETE("/workouts/:id", h.workout.DeleteWorkout)
+
+		// 训练记录相关
+		api.GET("/sessions", h.workout.GetSessions)
+		api.POST("/sessions", h.idempotent, h.workout.CreateSession)
+		api.GET("/sessions/:id", h.workout.GetSession)
+		api.PUT("/sessions/:id", h.idempotent, h.workout.UpdateSession)
+		api.PATCH("/sessions/:id", h.idempotent, h.workout.PatchSession)
+		api.GET("/sessions/stream", h.stream.StreamSessions)
+		api.GET("/sessions/export", h.history.Export)
+		api.GET("/sessions/:id/stream", h.stream.StreamSession)
+		api.POST("/sessions/:id/events", h.stream.PostSessionEvent)
+
+		// 离线同步
+		api.POST("/sync", h.sync.Sync)
+
+		// Webhook
+		api.GET("/webhooks", h.webhook.GetWebhooks)
+		api.POST("/webhooks", h.webhook.CreateWebhook)
+		api.GET("/webhooks/deliveries", h.webhook.GetDeliveries)
+		api.POST("/webhooks/deliveries/:id/retry", h.webhook.RetryDelivery)
+		api.GET("/webhooks/:id", h.webhook.GetWebhook)
+		api.PUT("/webhooks/:id", h.webhook.UpdateWebhook)
+		api.DELETE("/webhooks/:id", h.webhook.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", h.webhook.GetWebhookDeliveries)
+
+		// 导出与导入
+		api.GET("/export", h.archive.Export)
+		api.POST("/import", h.archive.Import)
+		api.GET("/calendar/feeds", h.calendar.GetFeeds)
+		api.POST("/calendar/feeds", h.calendar.CreateFeed)
+		api.DELETE("/calendar/feeds/:id", h.calendar.DeleteFeed)
+		api.GET("/calendar/:token/feed.ics", h.calendar.GetICS)
+		api.POST("/history/imports", h.history.CreateImport)
+		api.GET("/history/imports/:id", h.history.GetImport)
+		api.POST("/history/imports/:id/commit", h.history.CommitImport)
+		api.DELETE("/history/imports/:id", h.history.DeleteImport)
+
+		// 统计相关
+		api.GET("/statistics", h.workout.GetStatistics)
+		api.GET("/statistics/rpe", h.workout.GetRPEStatistics)
+
+		// 文件上传
+		api.POST("/upload", h.upload.UploadFile)
+		api.POST("/uploads/sessions", h.upload.CreateUploadSession)
+		api.GET("/uploads/sessions/:id", h.upload.GetUploadSession)
+		api.PUT("/uploads/sessions/:id", h.upload.UploadChunk)
+		api.DELETE("/uploads/sessions/:id", h.upload.DeleteUploadSession)
+		api.POST("/uploads/gc", h.upload.CollectGarbage)
+		api.POST("/uploads/migrate", h.upload.Migrate)
+
+		// 接口文档
+		api.GET("/openapi.json", h.docs.GetSpec)
+		api.GET("/docs", h.docs.GetDocs)
+	}
+
+	// Prometheus 指标
+	r.GET("/metrics", metrics.Handler())
+
+	// 存活与就绪检查
+	r.GET("/healthz", h.health.Healthz)
+	r.GET("/readyz", h.health.Readyz)
+
+	// 根路径重定向到后台管理页面
+	r.GET("/", func(c *gin.Context) {
+		c.Redirect(302, "/static/admin.html")
+	})
+
+	// 移动端页面
+	r.GET("/mobile", func(c *gin.Context) {
+		// 保留查询参数
+		query := c.Request.URL.RawQuery
+		if query != "" {
+			c.Redirect(302, "/static/mobile.html?"+query)
+		} else {
+			c.Redirect(302, "/static/mobile.html")
+		}
+	})
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+	"workout-tracker/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 所有路由必须出现在接口文档中
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// 只检查注册了哪些路由，处理器不会被调用
+	registerRoutes(r, routeHandlers{idempotent: func(c *gin.Context) {}})
+	if err := openapi.Check(openapi.Build(openapi.Routes), r.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package taxonomy
+
+import (
+	"time"
+	"workout-tracker/models"
+)
+
+// 首次启动时写入的默认分类，ID 固定，便于客户端和导入数据直接引用
+var defaults = []struct {
+	kind    string
+	id      string
+	name    string
+	aliases []string
+}{
+	{models.TermMuscle, "chest", "胸肌", []string{"胸", "胸部", "chest"}},
+	{models.TermMuscle, "lats", "背阔肌", []string{"背阔", "lats"}},
+	{models.TermMuscle, "upper-back", "上背部", []string{"斜方肌", "菱形肌", "upper back"}},
+	{models.TermMuscle, "lower-back", "下背部", []string{"竖脊肌", "腰部", "lower back"}},
+	{models.TermMuscle, "shoulders", "三角肌", []string{"肩", "肩部", "肩膀", "shoulders"}},
+	{models.TermMuscle, "biceps", "肱二头肌", []string{"二头肌", "biceps"}},
+	{models.TermMuscle, "triceps", "肱三头肌", []string{"三头肌", "triceps"}},
+	{models.TermMuscle, "forearms", "前臂", []string{"小臂", "forearms"}},
+	{models.TermMuscle, "abs", "腹直肌", []string{"腹肌", "abs"}},
+	{models.TermMuscle, "obliques", "腹斜肌", []string{"obliques"}},
+	{models.TermMuscle, "glutes", "臀肌", []string{"臀", "臀部", "glutes"}},
+	{models.TermMuscle, "quads", "股四头肌", []string{"大腿前侧", "quadriceps"}},
+	{models.TermMuscle, "hamstrings", "腘绳肌", []string{"大腿后侧", "hamstrings"}},
+	{models.TermMuscle, "calves", "小腿", []string{"小腿肌", "calves"}},
+	{models.TermMuscle, "full-body", "全身", []string{"全身", "full body"}},
+
+	{models.TermEquipment, "bodyweight", "自重", []string{"徒手", "bodyweight"}},
+	{models.TermEquipment, "barbell", "杠铃", []string{"barbell"}},
+	{models.TermEquipment, "dumbbell", "哑铃", []string{"dumbbell"}},
+	{models.TermEquipment, "kettlebell", "壶铃", []string{"kettlebell"}},
+	{models.TermEquipment, "machine", "固定器械", []string{"器械", "machine"}},
+	{models.TermEquipment, "cable", "龙门架", []string{"绳索", "cable"}},
+	{models.TermEquipment, "band", "弹力带", []string{"band"}},
+	{models.TermEquipment, "pull-up-bar", "单杠", []string{"pull-up bar"}},
+	{models.TermEquipment, "bench", "训练凳", []string{"卧推凳", "bench"}},
+
+	{models.TermPattern, "push", "推", []string{"push"}},
+	{models.TermPattern, "pull", "拉", []string{"pull"}},
+	{models.TermPattern, "hinge", "髋铰链", []string{"hinge"}},
+	{models.TermPattern, "squat", "蹲", []string{"squat"}},
+	{models.TermPattern, "carry", "负重行走", []string{"carry"}},
+
+	{models.TermDifficulty, "beginner", "初级", []string{"入门", "beginner"}},
+	{models.TermDifficulty, "intermediate", "中级", []string{"intermediate"}},
+	{models.TermDifficulty, "advanced", "高级", []string{"advanced"}},
+}
+
+// 对应多个肌群的旧 bodyPart 写法，只对应一个肌群的写法通过分类的名称和别名匹配
+var bodyPartMuscles = map[string][]string{
+	"背":  {"lats", "upper-back"},
+	"背部": {"lats", "upper-back"},
+	"腿":  {"quads", "hamstrings", "glutes"},
+	"腿部": {"quads", "hamstrings", "glutes"},
+	"下肢": {"quads", "hamstrings", "glutes", "calves"},
+	"臂":  {"biceps", "triceps"},
+	"手臂": {"biceps", "triceps"},
+	"上肢": {"chest", "lats", "upper-back", "shoulders", "biceps", "triceps"},
+	"腹":  {"abs", "obliques"},
+	"腹部": {"abs", "obliques"},
+	"核心": {"abs", "obliques", "lower-back"},
+}
+
+// Defaults 默认分类
+func Defaults(now time.Time) []models.TaxonomyTerm {
+	terms := make([]models.TaxonomyTerm, 0, len(defaults))
+	for _, d := range defaults {
+		terms = append(terms, models.TaxonomyTerm{ID: d.id, Kind: d.kind, Name: d.name, Aliases: d.aliases, CreatedAt: now})
+	}
+	return terms
+}
@@ -0,0 +1,139 @@
+package taxonomy
+
+import (
+	"strings"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+// MigrationReport bodyPart 迁移结果
+type MigrationReport struct {
+	DryRun   bool                `json:"dryRun"`
+	Scanned  int                 `json:"scanned"` // 还没有主要肌群、填写了 bodyPart 的动作
+	Migrated []MigratedExercise  `json:"migrated"`
+	Unmapped map[string][]string `json:"unmapped"` // 无法匹配的写法 -> 动作ID，可以给肌群添加别名后重新迁移
+}
+
+// MigratedExercise 一个动作的迁移结果
+type MigratedExercise struct {
+	ExerciseID     string   `json:"exerciseId"`
+	Name           string   `json:"name"`
+	BodyPart       string   `json:"bodyPart"`
+	PrimaryMuscles []string `json:"primaryMuscles"`
+}
+
+// bodyPart 中分隔多个部位的符号，例如 "胸部、肩部"
+var bodyPartSeparators = strings.NewReplacer("、", ",", "，", ",", "/", ",", "+", ",", "＋", ",", ";", ",", "；", ",")
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// MapBodyPart 把旧的 bodyPart 映射为主要肌群ID，完全无法匹配时返回 nil
+func (idx Index) MapBodyPart(bodyPart string) []string {
+	muscles, _ := idx.mapBodyPart(bodyPart)
+	return muscles
+}
+
+// 同时返回无法匹配的部分
+func (idx Index) mapBodyPart(bodyPart string) ([]string, []string) {
+	// 名称和别名 -> 肌群ID
+	names := make(map[string]string)
+	for _, term := range idx {
+		if term.Kind != models.TermMuscle {
+			continue
+		}
+		for _, alias := range term.Aliases {
+			names[normalize(alias)] = term.ID
+		}
+	}
+	// 名称优先于其他肌群的别名
+	for _, term := range idx {
+		if term.Kind == models.TermMuscle {
+			names[normalize(term.Name)] = term.ID
+		}
+	}
+
+	var muscles, unmatched []string
+	seen := make(map[string]bool)
+	add := func(id string) bool {
+		if !idx.has(models.TermMuscle, id) {
+			return false
+		}
+		if !seen[id] {
+			seen[id] = true
+			muscles = append(muscles, id)
+		}
+		return true
+	}
+	for _, part := range strings.Split(bodyPartSeparators.Replace(bodyPart), ",") {
+		part = normalize(part)
+		if part == "" {
+			continue
+		}
+		matched := false
+		if id, ok := names[part]; ok {
+			matched = add(id)
+		} else {
+			for _, id := range bodyPartMuscles[part] {
+				if add(id) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, part)
+		}
+	}
+	return muscles, unmatched
+}
+
+// Seed 首次启动时写入默认分类
+func Seed(repo *repository.FileRepository) (bool, error) {
+	return repo.SeedTaxonomy(Defaults(time.Now()))
+}
+
+// Migrate 为还没有主要肌群的动作按 bodyPart 填写主要肌群，bodyPart 保留不变。
+// 已经设置过主要肌群（包括明确设置为空）的动作不会被修改，可以重复执行
+func Migrate(repo *repository.FileRepository, dryRun bool) (*MigrationReport, error) {
+	terms, err := repo.GetAllTaxonomyTerms()
+	if err != nil {
+		return nil, err
+	}
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return nil, err
+	}
+
+	index := NewIndex(terms)
+	report := &MigrationReport{DryRun: dryRun, Migrated: []MigratedExercise{}, Unmapped: map[string][]string{}}
+	for _, exercise := range exercises {
+		if exercise.PrimaryMuscles != nil || strings.TrimSpace(exercise.BodyPart) == "" {
+			continue
+		}
+		report.Scanned++
+
+		muscles, unmatched := index.mapBodyPart(exercise.BodyPart)
+		for _, part := range unmatched {
+			report.Unmapped[part] = append(report.Unmapped[part], exercise.ID)
+		}
+		if len(muscles) == 0 {
+			continue
+		}
+		report.Migrated = append(report.Migrated, MigratedExercise{
+			ExerciseID:     exercise.ID,
+			Name:           exercise.Name,
+			BodyPart:       exercise.BodyPart,
+			PrimaryMuscles: muscles,
+		})
+		if dryRun {
+			continue
+		}
+		exercise.PrimaryMuscles = muscles
+		if err := repo.SaveExercise(exercise); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
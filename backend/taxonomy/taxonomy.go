@@ -0,0 +1,177 @@
+package taxonomy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+// Kinds 所有分类类型
+var Kinds = []string{models.TermMuscle, models.TermEquipment, models.TermPattern, models.TermDifficulty}
+
+// ErrUnknownKind 不存在的分类类型
+var ErrUnknownKind = errors.New("unknown taxonomy kind")
+
+// IsKind 是否为支持的分类类型
+func IsKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// TermRequest 创建或更新分类的请求
+type TermRequest struct {
+	Name    string   `json:"name" binding:"required"`
+	Aliases []string `json:"aliases"`
+}
+
+// Normalize 去掉名称和别名两端的空白以及重复、空白的别名
+func (req *TermRequest) Normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+	seen := make(map[string]bool)
+	var aliases []string
+	for _, alias := range req.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		aliases = append(aliases, alias)
+	}
+	req.Aliases = aliases
+}
+
+// UnknownTermError 动作引用了不存在或类型不符的分类
+type UnknownTermError struct {
+	Field string
+	ID    string
+}
+
+func (e *UnknownTermError) Error() string {
+	return fmt.Sprintf("%s references unknown term %q", e.Field, e.ID)
+}
+
+// InUseError 分类仍被动作引用，不能删除
+type InUseError struct {
+	Exercises []string
+}
+
+func (e *InUseError) Error() string {
+	return fmt.Sprintf("term is referenced by %d exercises", len(e.Exercises))
+}
+
+// Index 按 ID 索引的分类
+type Index map[string]models.TaxonomyTerm
+
+func NewIndex(terms []models.TaxonomyTerm) Index {
+	index := make(Index, len(terms))
+	for _, term := range terms {
+		index[term.ID] = term
+	}
+	return index
+}
+
+func (idx Index) has(kind, id string) bool {
+	term, ok := idx[id]
+	return ok && term.Kind == kind
+}
+
+// 动作中引用分类的字段
+type reference struct {
+	field string
+	kind  string
+	ids   *[]string
+	id    *string
+}
+
+func references(exercise *models.Exercise) []reference {
+	return []reference{
+		{field: "primaryMuscles", kind: models.TermMuscle, ids: &exercise.PrimaryMuscles},
+		{field: "secondaryMuscles", kind: models.TermMuscle, ids: &exercise.SecondaryMuscles},
+		{field: "equipment", kind: models.TermEquipment, ids: &exercise.Equipment},
+		{field: "movementPattern", kind: models.TermPattern, id: &exercise.MovementPattern},
+		{field: "difficulty", kind: models.TermDifficulty, id: &exercise.Difficulty},
+	}
+}
+
+// Validate 检查动作引用的分类都存在且类型正确
+func (idx Index) Validate(exercise *models.Exercise) error {
+	for _, ref := range references(exercise) {
+		if ref.id != nil {
+			if *ref.id != "" && !idx.has(ref.kind, *ref.id) {
+				return &UnknownTermError{Field: ref.field, ID: *ref.id}
+			}
+			continue
+		}
+		for _, id := range *ref.ids {
+			if !idx.has(ref.kind, id) {
+				return &UnknownTermError{Field: ref.field, ID: id}
+			}
+		}
+	}
+	return nil
+}
+
+// Prune 去掉动作引用的不存在的分类，返回被去掉的 ID
+func (idx Index) Prune(exercise *models.Exercise) []string {
+	var removed []string
+	for _, ref := range references(exercise) {
+		if ref.id != nil {
+			if *ref.id != "" && !idx.has(ref.kind, *ref.id) {
+				removed = append(removed, *ref.id)
+				*ref.id = ""
+			}
+			continue
+		}
+		if *ref.ids == nil {
+			continue
+		}
+		kept := []string{}
+		for _, id := range *ref.ids {
+			if idx.has(ref.kind, id) {
+				kept = append(kept, id)
+			} else {
+				removed = append(removed, id)
+			}
+		}
+		*ref.ids = kept
+	}
+	return removed
+}
+
+// Uses 动作是否引用了该分类
+func Uses(exercise models.Exercise, id string) bool {
+	for _, ref := range references(&exercise) {
+		if ref.id != nil {
+			if *ref.id == id {
+				return true
+			}
+			continue
+		}
+		for _, termID := range *ref.ids {
+			if termID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Resolve 保存动作前调用：没有提交 primaryMuscles 字段的旧客户端按 bodyPart 填写主要肌群，
+// 然后检查引用的分类是否存在。提交空列表表示明确不设置，不会再按 bodyPart 填写
+func Resolve(repo *repository.FileRepository, exercise *models.Exercise) error {
+	terms, err := repo.GetAllTaxonomyTerms()
+	if err != nil {
+		return err
+	}
+	index := NewIndex(terms)
+	if exercise.PrimaryMuscles == nil && exercise.BodyPart != "" {
+		exercise.PrimaryMuscles = index.MapBodyPart(exercise.BodyPart)
+	}
+	return index.Validate(exercise)
+}
@@ -0,0 +1,212 @@
+package taxonomy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+func defaultIndex() Index {
+	return NewIndex(Defaults(time.Now()))
+}
+
+func TestTermRequestNormalize(t *testing.T) {
+	req := TermRequest{Name: "  胸肌 ", Aliases: []string{" 胸 ", "", "胸", "chest", "  "}}
+	req.Normalize()
+	if req.Name != "胸肌" || !reflect.DeepEqual(req.Aliases, []string{"胸", "chest"}) {
+		t.Errorf("normalized = %+v", req)
+	}
+}
+
+func TestMapBodyPart(t *testing.T) {
+	index := defaultIndex()
+	tests := []struct {
+		bodyPart  string
+		muscles   []string
+		unmatched []string
+	}{
+		{"胸", []string{"chest"}, nil},
+		{" Chest ", []string{"chest"}, nil},
+		{"胸部、肩部", []string{"chest", "shoulders"}, nil},
+		{"腿", []string{"quads", "hamstrings", "glutes"}, nil},
+		{"胸/胸部", []string{"chest"}, nil},
+		{"胸+翅膀", []string{"chest"}, []string{"翅膀"}},
+		{"翅膀", nil, []string{"翅膀"}},
+		{"", nil, nil},
+	}
+	for _, tt := range tests {
+		muscles, unmatched := index.mapBodyPart(tt.bodyPart)
+		if !reflect.DeepEqual(muscles, tt.muscles) || !reflect.DeepEqual(unmatched, tt.unmatched) {
+			t.Errorf("mapBodyPart(%q) = %v, %v, want %v, %v", tt.bodyPart, muscles, unmatched, tt.muscles, tt.unmatched)
+		}
+	}
+
+	// 多部位写法中的肌群被删除后不再匹配
+	delete(index, "glutes")
+	if got := index.MapBodyPart("腿"); !reflect.DeepEqual(got, []string{"quads", "hamstrings"}) {
+		t.Errorf("without glutes = %v", got)
+	}
+}
+
+func TestValidateAndPrune(t *testing.T) {
+	index := defaultIndex()
+	tests := []struct {
+		name     string
+		exercise models.Exercise
+		field    string // 校验失败的字段，空表示通过
+		removed  []string
+	}{
+		{"valid", models.Exercise{PrimaryMuscles: []string{"chest"}, Equipment: []string{"barbell"}, MovementPattern: "push", Difficulty: "beginner"}, "", nil},
+		{"empty", models.Exercise{}, "", nil},
+		{"unknown muscle", models.Exercise{PrimaryMuscles: []string{"chest", "wings"}}, "primaryMuscles", []string{"wings"}},
+		{"term of another kind", models.Exercise{SecondaryMuscles: []string{"barbell"}}, "secondaryMuscles", []string{"barbell"}},
+		{"unknown pattern", models.Exercise{MovementPattern: "fly"}, "movementPattern", []string{"fly"}},
+		{"unknown difficulty", models.Exercise{Difficulty: "expert"}, "difficulty", []string{"expert"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exercise := tt.exercise
+			err := index.Validate(&exercise)
+			var unknown *UnknownTermError
+			if tt.field == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if !errors.As(err, &unknown) || unknown.Field != tt.field {
+				t.Fatalf("err = %v, want unknown %s", err, tt.field)
+			}
+
+			removed := index.Prune(&exercise)
+			if !reflect.DeepEqual(removed, tt.removed) {
+				t.Errorf("removed = %v, want %v", removed, tt.removed)
+			}
+			if err := index.Validate(&exercise); err != nil {
+				t.Errorf("invalid after prune: %v", err)
+			}
+		})
+	}
+}
+
+func TestUses(t *testing.T) {
+	exercise := models.Exercise{PrimaryMuscles: []string{"chest"}, Equipment: []string{"barbell"}, Difficulty: "beginner"}
+	for id, want := range map[string]bool{"chest": true, "barbell": true, "beginner": true, "lats": false} {
+		if got := Uses(exercise, id); got != want {
+			t.Errorf("Uses(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	if _, err := Seed(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		exercise models.Exercise
+		muscles  []string
+		ok       bool
+	}{
+		{"legacy client", models.Exercise{BodyPart: "背"}, []string{"lats", "upper-back"}, true},
+		{"explicitly empty", models.Exercise{BodyPart: "背", PrimaryMuscles: []string{}}, []string{}, true},
+		{"muscles take precedence", models.Exercise{BodyPart: "背", PrimaryMuscles: []string{"chest"}}, []string{"chest"}, true},
+		{"unknown term", models.Exercise{PrimaryMuscles: []string{"wings"}}, []string{"wings"}, false},
+	}
+	for _, tt := range tests {
+		exercise := tt.exercise
+		err := Resolve(repo, &exercise)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(exercise.PrimaryMuscles, tt.muscles) {
+			t.Errorf("%s: muscles = %v, err = %v", tt.name, exercise.PrimaryMuscles, err)
+		}
+	}
+}
+
+func TestSeedOnlyOnce(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	seeded, err := Seed(repo)
+	if err != nil || !seeded {
+		t.Fatalf("first seed = %v, %v", seeded, err)
+	}
+	if err := repo.DeleteTaxonomyTerm(models.TermMuscle, "chest"); err != nil {
+		t.Fatal(err)
+	}
+	// 删除过的默认分类不会被重新写入
+	seeded, err = Seed(repo)
+	if err != nil || seeded {
+		t.Fatalf("second seed = %v, %v", seeded, err)
+	}
+	if _, err := repo.GetTaxonomyTerm(models.TermMuscle, "chest"); err == nil {
+		t.Error("deleted term restored")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	repo := repository.NewFileRepository(t.TempDir())
+	if _, err := Seed(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, exercise := range []models.Exercise{
+		{ID: "bench", Name: "卧推", BodyPart: "胸部、三头肌"},
+		{ID: "curl", Name: "弯举", BodyPart: "二头肌+翅膀"},
+		{ID: "wing", Name: "展翅", BodyPart: "翅膀"},
+		{ID: "done", Name: "深蹲", BodyPart: "腿", PrimaryMuscles: []string{"quads"}},
+		{ID: "cleared", Name: "拉伸", BodyPart: "腿", PrimaryMuscles: []string{}},
+		{ID: "blank", Name: "冥想", BodyPart: " "},
+	} {
+		if err := repo.SaveExercise(exercise); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrated := func(report *MigrationReport) map[string][]string {
+		result := map[string][]string{}
+		for _, m := range report.Migrated {
+			result[m.ExerciseID] = m.PrimaryMuscles
+		}
+		return result
+	}
+	want := map[string][]string{"bench": {"chest", "triceps"}, "curl": {"biceps"}}
+
+	report, err := Migrate(repo, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 3 || !reflect.DeepEqual(migrated(report), want) ||
+		!reflect.DeepEqual(report.Unmapped, map[string][]string{"翅膀": {"curl", "wing"}}) {
+		t.Errorf("dry run = %+v", report)
+	}
+	if exercise, _ := repo.GetExerciseByID("bench"); exercise.PrimaryMuscles != nil {
+		t.Error("dry run saved exercise")
+	}
+
+	if report, err = Migrate(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(migrated(report), want) {
+		t.Errorf("migrated = %v", migrated(report))
+	}
+	for id, muscles := range map[string][]string{"bench": {"chest", "triceps"}, "done": {"quads"}, "cleared": {}, "wing": nil} {
+		exercise, err := repo.GetExerciseByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(exercise.PrimaryMuscles, muscles) {
+			t.Errorf("%s muscles = %v, want %v", id, exercise.PrimaryMuscles, muscles)
+		}
+		if exercise.BodyPart == "" {
+			t.Errorf("%s bodyPart cleared", id)
+		}
+	}
+
+	// 再次迁移只剩无法匹配的动作
+	if report, err = Migrate(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 1 || len(report.Migrated) != 0 {
+		t.Errorf("second migration = %+v", report)
+	}
+}
@@ -0,0 +1,83 @@
+package tracking
+
+import (
+	"fmt"
+	"workout-tracker/models"
+)
+
+// Modes 所有记录方式
+var Modes = []string{models.TrackReps, models.TrackRepsWeight, models.TrackTime, models.TrackDistance, models.TrackTimeDistance}
+
+// Fields 一种记录方式使用的字段
+type Fields struct {
+	Reps     bool
+	Weight   bool
+	Duration bool
+	Distance bool
+}
+
+// IsMode 是否为支持的记录方式
+func IsMode(mode string) bool {
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Mode 动作的记录方式，未设置时为次数+重量，与旧版本的训练计划一致
+func Mode(exercise models.Exercise) string {
+	if exercise.Tracking == "" {
+		return models.TrackRepsWeight
+	}
+	return exercise.Tracking
+}
+
+// FieldsOf 记录方式使用的字段，未知的记录方式按次数+重量处理
+func FieldsOf(mode string) Fields {
+	switch mode {
+	case models.TrackReps:
+		return Fields{Reps: true}
+	case models.TrackTime:
+		return Fields{Duration: true}
+	case models.TrackDistance:
+		return Fields{Distance: true}
+	case models.TrackTimeDistance:
+		return Fields{Duration: true, Distance: true}
+	}
+	return Fields{Reps: true, Weight: true}
+}
+
+// Timed 是否按时长计算卡路里
+func (f Fields) Timed() bool {
+	return !f.Reps
+}
+
+// Validate 检查记录方式并填写默认值
+func Validate(exercise *models.Exercise) error {
+	if exercise.Tracking == "" {
+		exercise.Tracking = models.TrackRepsWeight
+	}
+	if !IsMode(exercise.Tracking) {
+		return fmt.Errorf("unknown tracking mode %q", exercise.Tracking)
+	}
+	return nil
+}
+
+// ByExercise 动作ID -> 记录方式
+func ByExercise(exercises []models.Exercise) map[string]string {
+	modes := make(map[string]string, len(exercises))
+	for _, exercise := range exercises {
+		modes[exercise.ID] = Mode(exercise)
+	}
+	return modes
+}
+
+// Calories 一组消耗的卡路里：按次数记录的动作使用每次消耗，其余使用每分钟消耗和时长
+func Calories(exercise models.Exercise, reps, seconds int) float64 {
+	if FieldsOf(Mode(exercise)).Timed() {
+		return float64(seconds) / 60 * exercise.CaloriesPerMinute
+	}
+	return float64(reps) * exercise.CaloriesPerRep
+}
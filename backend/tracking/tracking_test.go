@@ -0,0 +1,76 @@
+package tracking
+
+import (
+	"testing"
+	"workout-tracker/models"
+)
+
+func TestFieldsOf(t *testing.T) {
+	tests := []struct {
+		mode   string
+		fields Fields
+		timed  bool
+	}{
+		{models.TrackReps, Fields{Reps: true}, false},
+		{models.TrackRepsWeight, Fields{Reps: true, Weight: true}, false},
+		{models.TrackTime, Fields{Duration: true}, true},
+		{models.TrackDistance, Fields{Distance: true}, true},
+		{models.TrackTimeDistance, Fields{Duration: true, Distance: true}, true},
+		// 未知的记录方式按次数+重量处理
+		{"", Fields{Reps: true, Weight: true}, false},
+		{"laps", Fields{Reps: true, Weight: true}, false},
+	}
+	for _, tt := range tests {
+		fields := FieldsOf(tt.mode)
+		if fields != tt.fields || fields.Timed() != tt.timed {
+			t.Errorf("FieldsOf(%q) = %+v, timed %v", tt.mode, fields, fields.Timed())
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		tracking string
+		want     string
+		ok       bool
+	}{
+		{"", models.TrackRepsWeight, true},
+		{models.TrackTimeDistance, models.TrackTimeDistance, true},
+		{"laps", "laps", false},
+	}
+	for _, tt := range tests {
+		exercise := models.Exercise{Tracking: tt.tracking}
+		err := Validate(&exercise)
+		if (err == nil) != tt.ok || exercise.Tracking != tt.want {
+			t.Errorf("Validate(%q) = %q, %v", tt.tracking, exercise.Tracking, err)
+		}
+	}
+}
+
+func TestByExercise(t *testing.T) {
+	modes := ByExercise([]models.Exercise{{ID: "plank", Tracking: models.TrackTime}, {ID: "squat"}})
+	if modes["plank"] != models.TrackTime || modes["squat"] != models.TrackRepsWeight || len(modes) != 2 {
+		t.Errorf("modes = %v", modes)
+	}
+}
+
+func TestCalories(t *testing.T) {
+	tests := []struct {
+		name     string
+		exercise models.Exercise
+		reps     int
+		seconds  int
+		want     float64
+	}{
+		{"per rep", models.Exercise{CaloriesPerRep: 0.5, CaloriesPerMinute: 10}, 12, 90, 6},
+		{"legacy exercise counts reps", models.Exercise{CaloriesPerRep: 0.5}, 10, 0, 5},
+		{"per minute", models.Exercise{Tracking: models.TrackTime, CaloriesPerRep: 0.5, CaloriesPerMinute: 8}, 0, 90, 12},
+		{"distance uses duration", models.Exercise{Tracking: models.TrackDistance, CaloriesPerMinute: 12}, 0, 300, 60},
+		{"no duration recorded", models.Exercise{Tracking: models.TrackTimeDistance, CaloriesPerMinute: 12}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := Calories(tt.exercise, tt.reps, tt.seconds); got != tt.want {
+			t.Errorf("%s: calories = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"time"
+)
+
+// ErrNotFound 存储中没有该文件
+var ErrNotFound = errors.New("upload not found")
+
+// Object 存储中的一个文件
+type Object struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend 上传文件的存储后端，文件名不含目录
+type Backend interface {
+	// Put 写入文件，写入完成前读取方看不到半个文件
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// Open 读取文件，返回的内容可以 Seek，用于 Range 请求
+	Open(ctx context.Context, name string) (io.ReadSeekCloser, Object, error)
+	Stat(ctx context.Context, name string) (Object, error)
+	Delete(ctx context.Context, name string) error
+	// List 遍历全部文件
+	List(ctx context.Context, fn func(Object) error) error
+	// Touch 刷新修改时间，使文件重新进入清理的保留期
+	Touch(ctx context.Context, name string) error
+	// Check 就绪检查：存储可以访问和写入
+	Check(ctx context.Context) error
+}
+
+// Presigner 可以生成临时访问地址的后端，返回空字符串表示由服务端代理读取
+type Presigner interface {
+	PresignGet(ctx context.Context, name string) (string, error)
+}
+
+// ContentHeaders 文件对外提供时的 Content-Type 和 Content-Disposition：
+// 只有允许的图片和视频按原类型内联显示，其他文件一律作为附件下载
+func ContentHeaders(name string) (string, string) {
+	contentType, disposition := "application/octet-stream", "attachment"
+	if t, ok := ByExtension(name); ok {
+		contentType, disposition = t.MIME, "inline"
+	}
+	return contentType, mime.FormatMediaType(disposition, map[string]string{"filename": name})
+}
@@ -0,0 +1,149 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+	"workout-tracker/logging"
+	"workout-tracker/repository"
+)
+
+// SweptFile 被清理（或 dry-run 时将被清理）的文件
+type SweptFile struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// SweepReport 清理结果
+type SweepReport struct {
+	DryRun     bool        `json:"dryRun"`
+	Scanned    int         `json:"scanned"`
+	Referenced int         `json:"referenced"`
+	Retained   int         `json:"retained"` // 未被引用但仍在保留期内
+	Removed    []SweptFile `json:"removed"`
+	FreedBytes int64       `json:"freedBytes"`
+}
+
+// Sweeper 清理超过保留期且未被引用的上传文件
+type Sweeper struct {
+	repo  *repository.FileRepository
+	store *Store
+	grace time.Duration
+}
+
+func NewSweeper(repo *repository.FileRepository, store *Store, grace time.Duration) *Sweeper {
+	return &Sweeper{repo: repo, store: store, grace: grace}
+}
+
+// Referenced 收集被数据引用的上传文件名：动作图片及其缩放版本、演示视频及其封面
+func Referenced(repo *repository.FileRepository) (map[string]bool, error) {
+	exercises, err := repo.GetAllExercises()
+	if err != nil {
+		return nil, err
+	}
+	media, err := repo.GetAllMedia()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	add := func(url string) {
+		if name, ok := NameFromURL(url); ok {
+			referenced[name] = true
+		}
+	}
+	for _, exercise := range exercises {
+		add(exercise.ImageURL)
+		for _, variant := range exercise.ImageVariants {
+			add(variant.URL)
+		}
+		for _, clip := range exercise.Clips {
+			add(clip.URL)
+			add(clip.Poster)
+		}
+	}
+	// 原文件被引用时，记录中的缩放版本和封面也被引用
+	for _, item := range media {
+		if name, ok := NameFromURL(item.URL); ok && referenced[name] {
+			for _, variant := range item.Variants {
+				add(variant.URL)
+			}
+			add(item.Poster)
+		}
+	}
+	return referenced, nil
+}
+
+// Sweep 删除未被引用且修改时间早于保留期的文件，dryRun 时只生成报告
+func (s *Sweeper) Sweep(ctx context.Context, dryRun bool) (*SweepReport, error) {
+	repo := s.repo.WithContext(ctx)
+	referenced, err := Referenced(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	report := &SweepReport{DryRun: dryRun, Removed: []SweptFile{}}
+	cutoff := time.Now().Add(-s.grace)
+	var removedURLs []string
+	err = s.store.backend.List(ctx, func(obj Object) error {
+		report.Scanned++
+		if referenced[obj.Name] {
+			report.Referenced++
+			return nil
+		}
+		if obj.ModTime.After(cutoff) {
+			report.Retained++
+			return nil
+		}
+
+		if !dryRun {
+			if err := s.store.backend.Delete(ctx, obj.Name); err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			removedURLs = append(removedURLs, URL(obj.Name))
+		}
+		report.Removed = append(report.Removed, SweptFile{Name: obj.Name, Size: obj.Size, ModTime: obj.ModTime})
+		report.FreedBytes += obj.Size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(report.Removed, func(i, j int) bool {
+		return report.Removed[i].Name < report.Removed[j].Name
+	})
+
+	if len(removedURLs) > 0 {
+		if err := repo.DeleteMedia(removedURLs...); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// Run 定期清理，直到 stop 被关闭
+func (s *Sweeper) Run(interval time.Duration, stop <-chan struct{}) {
+	log := logging.Component("uploads")
+	ctx := logging.NewContext(context.Background(), log)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			report, err := s.Sweep(ctx, false)
+			if err != nil {
+				log.Error("sweep uploads failed", "error", err)
+				continue
+			}
+			log.Info("uploads swept", "scanned", report.Scanned, "removed", len(report.Removed), "freed_bytes", report.FreedBytes, "retained", report.Retained)
+		case <-stop:
+			return
+		}
+	}
+}
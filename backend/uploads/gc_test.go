@@ -0,0 +1,113 @@
+package uploads
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewFileRepository(t.TempDir())
+	dir := t.TempDir()
+	store := NewStore(NewLocal(dir))
+
+	old := time.Now().Add(-48 * time.Hour)
+	write := func(name string, modTime time.Time) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 被引用的图片及其缩放版本、演示视频及其封面，未被引用的旧文件和新文件
+	for _, name := range []string{"img.jpg", "img-320w.jpg", "img-320w.webp", "clip.mp4", "clip-poster.jpg", "orphan.jpg", "orphan-320w.jpg"} {
+		write(name, old)
+	}
+	write("fresh.jpg", time.Now())
+
+	if err := repo.SaveExercise(models.Exercise{
+		ID:       "ex1",
+		ImageURL: URL("img.jpg"),
+		Clips:    []models.Clip{{URL: URL("clip.mp4"), Poster: URL("clip-poster.jpg")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []models.Media{
+		{URL: URL("img.jpg"), Variants: []models.ImageVariant{{URL: URL("img-320w.jpg")}, {URL: URL("img-320w.webp")}}},
+		{URL: URL("orphan.jpg"), Variants: []models.ImageVariant{{URL: URL("orphan-320w.jpg")}}},
+	} {
+		if err := repo.SaveMedia(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sweeper := NewSweeper(repo, store, 24*time.Hour)
+	removed := []string{"orphan-320w.jpg", "orphan.jpg"}
+	check := func(report *SweepReport, dryRun bool) {
+		t.Helper()
+		if report.DryRun != dryRun || report.Scanned != 8 || report.Referenced != 5 || report.Retained != 1 {
+			t.Errorf("report = %+v", report)
+		}
+		if len(report.Removed) != len(removed) {
+			t.Fatalf("removed = %+v, want %v", report.Removed, removed)
+		}
+		for i, file := range report.Removed {
+			if file.Name != removed[i] {
+				t.Errorf("removed[%d] = %s, want %s", i, file.Name, removed[i])
+			}
+		}
+		if want := int64(len("orphan.jpg") + len("orphan-320w.jpg")); report.FreedBytes != want {
+			t.Errorf("freed = %d, want %d", report.FreedBytes, want)
+		}
+	}
+
+	// dry run 只报告，不删除文件和记录
+	report, err := sweeper.Sweep(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(report, true)
+	if !store.Exists(ctx, "orphan.jpg") {
+		t.Fatal("dry run removed a file")
+	}
+
+	report, err = sweeper.Sweep(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(report, false)
+	for _, name := range removed {
+		if store.Exists(ctx, name) {
+			t.Errorf("%s not removed", name)
+		}
+	}
+	for _, name := range []string{"img.jpg", "img-320w.webp", "clip-poster.jpg", "fresh.jpg"} {
+		if !store.Exists(ctx, name) {
+			t.Errorf("%s removed", name)
+		}
+	}
+	media, err := repo.GetAllMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(media) != 1 || media[0].URL != URL("img.jpg") {
+		t.Errorf("media records = %+v", media)
+	}
+
+	// 再次清理没有可删除的文件
+	report, err = sweeper.Sweep(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 0 || report.Scanned != 6 {
+		t.Errorf("second sweep = %+v", report)
+	}
+}
@@ -0,0 +1,123 @@
+package uploads
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Local 本地目录存储
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+func (l *Local) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(l.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (l *Local) Open(ctx context.Context, name string) (io.ReadSeekCloser, Object, error) {
+	f, err := os.Open(filepath.Join(l.dir, name))
+	if err != nil {
+		return nil, Object{}, notFound(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Object{}, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, Object{}, ErrNotFound
+	}
+	return f, Object{Name: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (l *Local) Stat(ctx context.Context, name string) (Object, error) {
+	info, err := os.Stat(filepath.Join(l.dir, name))
+	if err != nil {
+		return Object{}, notFound(err)
+	}
+	if info.IsDir() {
+		return Object{}, ErrNotFound
+	}
+	return Object{Name: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (l *Local) Delete(ctx context.Context, name string) error {
+	return notFound(os.Remove(filepath.Join(l.dir, name)))
+}
+
+func (l *Local) List(ctx context.Context, fn func(Object) error) error {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if err := fn(Object{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Local) Touch(ctx context.Context, name string) error {
+	now := time.Now()
+	return notFound(os.Chtimes(filepath.Join(l.dir, name), now, now))
+}
+
+// Check 目录可以创建文件，目录不存在时与写入逻辑一样先创建
+func (l *Local) Check(ctx context.Context) error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(l.dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func notFound(err error) error {
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
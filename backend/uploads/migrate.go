@@ -0,0 +1,157 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"io"
+	"workout-tracker/logging"
+)
+
+// MigrationReport 迁移结果
+type MigrationReport struct {
+	Scanned  int      `json:"scanned"`
+	Migrated []string `json:"migrated"`
+	Skipped  int      `json:"skipped"` // 已经存在于新存储中
+	Bytes    int64    `json:"bytes"`
+}
+
+// Migrating 切换存储后兼容原有文件：新文件写入 primary，
+// primary 中找不到的文件从 fallback（通常是原来的本地目录）读取并复制过去
+type Migrating struct {
+	primary  Backend
+	fallback Backend
+}
+
+func NewMigrating(primary, fallback Backend) *Migrating {
+	return &Migrating{primary: primary, fallback: fallback}
+}
+
+func (m *Migrating) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	return m.primary.Put(ctx, name, r, size)
+}
+
+func (m *Migrating) Open(ctx context.Context, name string) (io.ReadSeekCloser, Object, error) {
+	if _, err := m.Stat(ctx, name); err != nil {
+		return nil, Object{}, err
+	}
+	return m.primary.Open(ctx, name)
+}
+
+// Stat 只在 fallback 中存在的文件先迁移到 primary
+func (m *Migrating) Stat(ctx context.Context, name string) (Object, error) {
+	obj, err := m.primary.Stat(ctx, name)
+	if !errors.Is(err, ErrNotFound) {
+		return obj, err
+	}
+	if _, err := m.migrate(ctx, name); err != nil {
+		return Object{}, err
+	}
+	return m.primary.Stat(ctx, name)
+}
+
+// Delete 同时删除两边的文件
+func (m *Migrating) Delete(ctx context.Context, name string) error {
+	err := m.primary.Delete(ctx, name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := m.fallback.Delete(ctx, name); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// List 列出两边的文件，同名文件以 primary 为准
+func (m *Migrating) List(ctx context.Context, fn func(Object) error) error {
+	seen := make(map[string]bool)
+	err := m.primary.List(ctx, func(obj Object) error {
+		seen[obj.Name] = true
+		return fn(obj)
+	})
+	if err != nil {
+		return err
+	}
+	return m.fallback.List(ctx, func(obj Object) error {
+		if seen[obj.Name] {
+			return nil
+		}
+		return fn(obj)
+	})
+}
+
+func (m *Migrating) Touch(ctx context.Context, name string) error {
+	if _, err := m.Stat(ctx, name); err != nil {
+		return err
+	}
+	return m.primary.Touch(ctx, name)
+}
+
+func (m *Migrating) Check(ctx context.Context) error {
+	return m.primary.Check(ctx)
+}
+
+// PresignGet 先确保文件已经迁移，预签名地址指向 primary
+func (m *Migrating) PresignGet(ctx context.Context, name string) (string, error) {
+	presigner, ok := m.primary.(Presigner)
+	if !ok {
+		return "", nil
+	}
+	if _, err := m.Stat(ctx, name); err != nil {
+		return "", err
+	}
+	return presigner.PresignGet(ctx, name)
+}
+
+// MigrateAll 把 fallback 中剩余的文件全部复制到 primary，原文件保留
+func (m *Migrating) MigrateAll(ctx context.Context) (*MigrationReport, error) {
+	var names []string
+	err := m.fallback.List(ctx, func(obj Object) error {
+		names = append(names, obj.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MigrationReport{Migrated: []string{}}
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Scanned++
+		_, err := m.primary.Stat(ctx, name)
+		if err == nil {
+			report.Skipped++
+			continue
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		size, err := m.migrate(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.Migrated = append(report.Migrated, name)
+		report.Bytes += size
+	}
+	return report, nil
+}
+
+// 把 fallback 中的文件复制到 primary
+func (m *Migrating) migrate(ctx context.Context, name string) (int64, error) {
+	src, obj, err := m.fallback.Open(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	if err := m.primary.Put(ctx, name, src, obj.Size); err != nil {
+		return 0, err
+	}
+	logging.FromContext(ctx).Info("upload migrated", "name", name, "bytes", obj.Size)
+	return obj.Size, nil
+}
@@ -0,0 +1,144 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestMigrating(t *testing.T, files map[string]string) (*Migrating, *fakeS3, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s3, fake := newTestS3(t, S3Config{})
+	return NewMigrating(s3, NewLocal(dir)), fake, dir
+}
+
+func TestMigratingReadsFallBackToLocal(t *testing.T) {
+	ctx := context.Background()
+	m, fake, dir := newTestMigrating(t, map[string]string{"old.jpg": "old bytes"})
+
+	r, obj, err := m.Open(ctx, "old.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old bytes" || obj.Size != 9 {
+		t.Errorf("open = %q, %+v", data, obj)
+	}
+	// 读取时复制到对象存储，本地文件保留
+	if copied := fake.objects["old.jpg"]; copied == nil || string(copied.data) != "old bytes" {
+		t.Errorf("not copied to primary: %v", fake.objects)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.jpg")); err != nil {
+		t.Errorf("local file removed: %v", err)
+	}
+
+	if _, err := m.Stat(ctx, "missing.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stat missing = %v, want ErrNotFound", err)
+	}
+	if _, _, err := m.Open(ctx, "missing.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("open missing = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMigratingWritesAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	m, fake, dir := newTestMigrating(t, map[string]string{"old.jpg": "old", "both.jpg": "local copy"})
+	fake.put("both.jpg", "s3 copy")
+
+	// 新文件只写入对象存储
+	if err := m.Put(ctx, "new.jpg", strings.NewReader("new"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if fake.objects["new.jpg"] == nil {
+		t.Error("new file not in primary")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.jpg")); !os.IsNotExist(err) {
+		t.Errorf("new file written locally: %v", err)
+	}
+
+	// 同名文件以对象存储为准
+	sizes := map[string]int64{}
+	if err := m.List(ctx, func(obj Object) error {
+		if _, ok := sizes[obj.Name]; ok {
+			t.Errorf("%s listed twice", obj.Name)
+		}
+		sizes[obj.Name] = obj.Size
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 3 || sizes["both.jpg"] != int64(len("s3 copy")) || sizes["old.jpg"] != 3 {
+		t.Errorf("list = %v", sizes)
+	}
+
+	// Touch 先迁移只在本地的文件
+	if err := m.Touch(ctx, "old.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.objects["old.jpg"] == nil {
+		t.Error("touch did not migrate")
+	}
+
+	if err := m.Delete(ctx, "both.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.objects["both.jpg"] != nil {
+		t.Error("not deleted from primary")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "both.jpg")); !os.IsNotExist(err) {
+		t.Errorf("not deleted locally: %v", err)
+	}
+	if err := m.Delete(ctx, "missing.jpg"); err != nil {
+		t.Errorf("delete missing = %v", err)
+	}
+}
+
+func TestMigrateAll(t *testing.T) {
+	ctx := context.Background()
+	m, fake, dir := newTestMigrating(t, map[string]string{"a.jpg": "aaa", "b.jpg": "bbbb", "done.jpg": "done"})
+	fake.put("done.jpg", "done")
+
+	report, err := m.MigrateAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 3 || report.Skipped != 1 || report.Bytes != 7 || strings.Join(report.Migrated, ",") != "a.jpg,b.jpg" {
+		t.Errorf("report = %+v", report)
+	}
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		if fake.objects[name] == nil {
+			t.Errorf("%s not migrated", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s removed locally: %v", name, err)
+		}
+	}
+
+	// 再次迁移时全部跳过
+	report, err = m.MigrateAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Skipped != 3 || len(report.Migrated) != 0 {
+		t.Errorf("second migration = %+v", report)
+	}
+
+	// 只有迁移中的存储才能迁移
+	if _, err := NewStore(NewLocal(dir)).Migrate(ctx); !errors.Is(err, ErrNoMigration) {
+		t.Errorf("migrate local store = %v", err)
+	}
+}
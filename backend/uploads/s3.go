@@ -0,0 +1,193 @@
+package uploads
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config S3 兼容对象存储（AWS S3、MinIO 等）的配置
+type S3Config struct {
+	Endpoint  string // 例如 s3.amazonaws.com 或 localhost:9000
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	Prefix    string // 对象键前缀，例如 uploads/
+
+	// 大于 0 时读取重定向到有效期为该时长的预签名地址，否则由服务端代理读取
+	PresignExpiry time.Duration
+	// 浏览器访问对象存储的地址，与服务端访问的地址不同时（例如容器内外）用于生成预签名地址
+	PublicEndpoint string
+	PublicUseSSL   bool
+}
+
+// S3 对象存储
+type S3 struct {
+	client  *minio.Client
+	presign *minio.Client
+	bucket  string
+	prefix  string
+	expiry  time.Duration
+}
+
+func NewS3(config S3Config) (*S3, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("s3 endpoint and bucket are required")
+	}
+	newClient := func(endpoint string, secure bool) (*minio.Client, error) {
+		return minio.New(endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+			Secure: secure,
+			Region: config.Region,
+		})
+	}
+
+	client, err := newClient(config.Endpoint, config.UseSSL)
+	if err != nil {
+		return nil, err
+	}
+	presign := client
+	if config.PublicEndpoint != "" {
+		if presign, err = newClient(config.PublicEndpoint, config.PublicUseSSL); err != nil {
+			return nil, err
+		}
+	}
+	return &S3{
+		client:  client,
+		presign: presign,
+		bucket:  config.Bucket,
+		prefix:  config.Prefix,
+		expiry:  config.PresignExpiry,
+	}, nil
+}
+
+// EnsureBucket 存储桶不存在时创建
+func (s *S3) EnsureBucket(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil || exists {
+		return err
+	}
+	return s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{})
+}
+
+// Put 对象带上与本地读取相同的 Content-Type 和 Content-Disposition，预签名读取时由对象存储返回
+func (s *S3) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	contentType, disposition := ContentHeaders(name)
+	_, err := s.client.PutObject(ctx, s.bucket, s.key(name), r, size, minio.PutObjectOptions{
+		ContentType:        contentType,
+		ContentDisposition: disposition,
+	})
+	return err
+}
+
+func (s *S3) Open(ctx context.Context, name string) (io.ReadSeekCloser, Object, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, Object{}, s3Error(err)
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, Object{}, s3Error(err)
+	}
+	return obj, Object{Name: name, Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (s *S3) Stat(ctx context.Context, name string) (Object, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, s.key(name), minio.StatObjectOptions{})
+	if err != nil {
+		return Object{}, s3Error(err)
+	}
+	return Object{Name: name, Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+// Delete 对象不存在时 S3 同样返回成功
+func (s *S3) Delete(ctx context.Context, name string) error {
+	return s3Error(s.client.RemoveObject(ctx, s.bucket, s.key(name), minio.RemoveObjectOptions{}))
+}
+
+func (s *S3) List(ctx context.Context, fn func(Object) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix, Recursive: true}) {
+		if info.Err != nil {
+			return info.Err
+		}
+		// 只处理前缀下直接存放的文件
+		name := strings.TrimPrefix(info.Key, s.prefix)
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		if err := fn(Object{Name: name, Size: info.Size, ModTime: info.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Touch 对象不能修改时间，复制到自身并替换元数据来刷新 LastModified
+func (s *S3) Touch(ctx context.Context, name string) error {
+	contentType, disposition := ContentHeaders(name)
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{
+			Bucket:          s.bucket,
+			Object:          s.key(name),
+			ReplaceMetadata: true,
+			UserMetadata: map[string]string{
+				"Content-Type":        contentType,
+				"Content-Disposition": disposition,
+				"Touched":             time.Now().UTC().Format(time.RFC3339Nano),
+			},
+		},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: s.key(name)},
+	)
+	return s3Error(err)
+}
+
+// Check 存储桶可以访问
+func (s *S3) Check(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", s.bucket)
+	}
+	return nil
+}
+
+// PresignGet 未配置有效期时返回空字符串，由服务端代理读取
+func (s *S3) PresignGet(ctx context.Context, name string) (string, error) {
+	if s.expiry <= 0 {
+		return "", nil
+	}
+	u, err := s.presign.PresignedGetObject(ctx, s.bucket, s.key(name), s.expiry, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s *S3) key(name string) string {
+	return s.prefix + name
+}
+
+func s3Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return ErrNotFound
+	}
+	return err
+}
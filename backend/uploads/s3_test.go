@@ -0,0 +1,337 @@
+package uploads
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeObject struct {
+	data        []byte
+	modTime     time.Time
+	contentType string
+	disposition string
+}
+
+// fakeS3 只实现上传存储用到的 S3 接口，使用路径形式的地址，不校验签名
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	now     time.Time
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	fake := &fakeS3{bucket: bucket, objects: make(map[string]*fakeObject), now: time.Now().UTC().Truncate(time.Second)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// 直接放入对象，模拟存储桶中已有的文件
+func (f *fakeS3) put(key, data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = &fakeObject{data: []byte(data), modTime: f.tick()}
+}
+
+// 每次写入时间前进一秒，便于判断 Touch 是否刷新了修改时间
+func (f *fakeS3) tick() time.Time {
+	f.now = f.now.Add(time.Second)
+	return f.now
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		s3ErrorResponse(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		switch r.Method {
+		case http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			f.list(w, r.URL.Query().Get("prefix"))
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			f.copy(w, r, source, key)
+			return
+		}
+		data, err := readPayload(r)
+		if err != nil {
+			s3ErrorResponse(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = &fakeObject{data: data, modTime: f.tick(), contentType: r.Header.Get("Content-Type"), disposition: r.Header.Get("Content-Disposition")}
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodHead, http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			s3ErrorResponse(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Disposition", obj.disposition)
+		http.ServeContent(w, r, "", obj.modTime, bytes.NewReader(obj.data))
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) copy(w http.ResponseWriter, r *http.Request, source, key string) {
+	source, _ = url.PathUnescape(source)
+	_, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	obj, ok := f.objects[sourceKey]
+	if !ok {
+		s3ErrorResponse(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	copied := *obj
+	copied.modTime = f.tick()
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		copied.contentType = r.Header.Get("Content-Type")
+		copied.disposition = r.Header.Get("Content-Disposition")
+	}
+	f.objects[key] = &copied
+	fmt.Fprintf(w, `<CopyObjectResult><LastModified>%s</LastModified><ETag>"etag"</ETag></CopyObjectResult>`, copied.modTime.Format(time.RFC3339))
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		Size         int64
+		LastModified string
+		ETag         string
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		IsTruncated bool
+		Contents    []content
+	}{Name: f.bucket, Prefix: prefix}
+	for key, obj := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, content{key, int64(len(obj.data)), obj.modTime.Format(time.RFC3339), `"etag"`})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	xml.NewEncoder(w).Encode(result)
+}
+
+func s3ErrorResponse(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+// 非 TLS 连接时客户端使用 aws-chunked 分块上传：每块为 "十六进制长度;chunk-signature=...\r\n数据\r\n"
+func readPayload(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func newTestS3(t *testing.T, config S3Config) (*S3, *fakeS3) {
+	t.Helper()
+	fake, server := newFakeS3(t, "uploads")
+	config.Endpoint = strings.TrimPrefix(server.URL, "http://")
+	config.Bucket = "uploads"
+	config.Region = "us-east-1"
+	config.AccessKey, config.SecretKey = "access", "secret"
+	s3, err := NewS3(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s3, fake
+}
+
+func TestS3Backend(t *testing.T) {
+	ctx := context.Background()
+	s3, fake := newTestS3(t, S3Config{Prefix: "media/"})
+
+	if err := s3.Check(ctx); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if _, err := s3.Stat(ctx, "a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("stat missing = %v, want ErrNotFound", err)
+	}
+	if _, _, err := s3.Open(ctx, "a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("open missing = %v, want ErrNotFound", err)
+	}
+	if err := s3.Touch(ctx, "a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("touch missing = %v, want ErrNotFound", err)
+	}
+
+	content := "jpeg bytes"
+	if err := s3.Put(ctx, "a.jpg", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s3.Put(ctx, "page.html", strings.NewReader("<html>"), 6); err != nil {
+		t.Fatal(err)
+	}
+	// 对象键带前缀，并保存对外提供时的响应头
+	stored := fake.objects["media/a.jpg"]
+	if stored == nil || string(stored.data) != content {
+		t.Fatalf("stored objects = %v", fake.objects)
+	}
+	if stored.contentType != "image/jpeg" || stored.disposition != "inline; filename=a.jpg" {
+		t.Errorf("headers = %q, %q", stored.contentType, stored.disposition)
+	}
+	if html := fake.objects["media/page.html"]; html.contentType != "application/octet-stream" || !strings.HasPrefix(html.disposition, "attachment") {
+		t.Errorf("html headers = %q, %q", html.contentType, html.disposition)
+	}
+
+	obj, err := s3.Stat(ctx, "a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Name != "a.jpg" || obj.Size != int64(len(content)) {
+		t.Errorf("stat = %+v", obj)
+	}
+
+	r, opened, err := s3.Open(ctx, "a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Range 请求需要 Seek
+	if _, err := r.Seek(5, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != content[5:] || opened.Size != int64(len(content)) {
+		t.Errorf("open = %q, %+v", rest, opened)
+	}
+
+	if err := s3.Touch(ctx, "a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	touched, err := s3.Stat(ctx, "a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !touched.ModTime.After(obj.ModTime) {
+		t.Errorf("touch did not refresh mod time: %v -> %v", obj.ModTime, touched.ModTime)
+	}
+	if stored := fake.objects["media/a.jpg"]; string(stored.data) != content || stored.contentType != "image/jpeg" {
+		t.Errorf("touch changed object: %q %q", stored.data, stored.contentType)
+	}
+
+	// 前缀之外和子目录中的对象不列出
+	fake.put("other/b.jpg", "b")
+	fake.put("media/partial/c.jpg", "c")
+	var names []string
+	if err := s3.List(ctx, func(obj Object) error {
+		names = append(names, obj.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "a.jpg,page.html" {
+		t.Errorf("list = %v", names)
+	}
+
+	if err := s3.Delete(ctx, "a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s3.Stat(ctx, "a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stat after delete = %v", err)
+	}
+	// 删除不存在的对象同样成功
+	if err := s3.Delete(ctx, "a.jpg"); err != nil {
+		t.Errorf("delete missing = %v", err)
+	}
+}
+
+func TestS3MissingBucket(t *testing.T) {
+	ctx := context.Background()
+	_, server := newFakeS3(t, "other")
+	s3, err := NewS3(S3Config{Endpoint: strings.TrimPrefix(server.URL, "http://"), Bucket: "uploads", Region: "us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s3.Check(ctx); err == nil {
+		t.Error("check passed without the bucket")
+	}
+	if _, err := NewS3(S3Config{Bucket: "uploads"}); err == nil {
+		t.Error("config without endpoint accepted")
+	}
+}
+
+func TestS3PresignGet(t *testing.T) {
+	ctx := context.Background()
+	proxied, _ := newTestS3(t, S3Config{})
+	if u, err := proxied.PresignGet(ctx, "a.jpg"); err != nil || u != "" {
+		t.Errorf("presign without expiry = %q, %v", u, err)
+	}
+
+	s3, _ := newTestS3(t, S3Config{Prefix: "media/", PresignExpiry: time.Minute, PublicEndpoint: "cdn.example.com", PublicUseSSL: true})
+	u, err := s3.PresignGet(ctx, "a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 预签名地址使用浏览器访问的地址
+	if parsed.Scheme != "https" || parsed.Host != "cdn.example.com" || parsed.Path != "/uploads/media/a.jpg" || parsed.Query().Get("X-Amz-Expires") != "60" {
+		t.Errorf("presigned url = %s", u)
+	}
+}
@@ -0,0 +1,224 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"workout-tracker/logging"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+
+	"github.com/google/uuid"
+)
+
+// MaxChunkSize 单个分片的大小上限
+const MaxChunkSize = 16 << 20
+
+// DefaultSessionTTL 分片上传会话在最后一次写入后保留的时间
+const DefaultSessionTTL = 24 * time.Hour
+
+var (
+	ErrSessionNotFound = errors.New("upload session not found")
+	ErrSessionBusy     = errors.New("another chunk is being written to this upload session")
+	ErrSessionComplete = errors.New("upload session is already complete")
+	ErrOffsetMismatch  = errors.New("chunk does not start at the current offset")
+	ErrChunkOutOfRange = errors.New("chunk exceeds the declared file size")
+)
+
+// SessionRequest 创建分片上传会话的请求
+type SessionRequest struct {
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required,gt=0"` // 文件总大小
+}
+
+// Sessions 分片上传：分片按顺序追加到本地的临时文件，记录已接收的字节数，
+// 连接中断后客户端查询 offset 继续上传
+type Sessions struct {
+	repo *repository.FileRepository
+	dir  string
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	active map[string]bool // 正在写入分片的会话
+}
+
+func NewSessions(repo *repository.FileRepository, dir string, ttl time.Duration) *Sessions {
+	return &Sessions{repo: repo, dir: dir, ttl: ttl, active: make(map[string]bool)}
+}
+
+// Create 创建会话，size 为文件总大小
+func (s *Sessions) Create(ctx context.Context, filename string, size int64) (*models.UploadSession, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := models.UploadSession{
+		ID:        uuid.New().String(),
+		Filename:  filepath.Base(filename),
+		Size:      size,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	f, err := os.OpenFile(s.path(session.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.repo.WithContext(ctx).SaveUploadSession(session); err != nil {
+		os.Remove(s.path(session.ID))
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Get 获取会话
+func (s *Sessions) Get(ctx context.Context, id string) (*models.UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(ctx, id)
+}
+
+func (s *Sessions) get(ctx context.Context, id string) (*models.UploadSession, error) {
+	session, err := s.repo.WithContext(ctx).GetUploadSessionByID(id)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	if session.ExpiresAt.Before(time.Now()) {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// Append 写入从 start 开始、长度为 length 的分片。start 必须等于已接收的字节数；
+// 写入失败时已接收的字节数不变，客户端可以重试同一分片
+func (s *Sessions) Append(ctx context.Context, id string, start, length int64, r io.Reader) (*models.UploadSession, error) {
+	s.mu.Lock()
+	session, err := s.get(ctx, id)
+	if err == nil && s.active[id] {
+		err = ErrSessionBusy
+	}
+	if err == nil {
+		s.active[id] = true
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		s.mu.Lock()
+		delete(s.active, id)
+		s.mu.Unlock()
+	}()
+
+	switch {
+	case session.Media != nil:
+		return session, ErrSessionComplete
+	case start != session.Offset:
+		return session, ErrOffsetMismatch
+	case start+length > session.Size:
+		return session, ErrChunkOutOfRange
+	}
+
+	if err := s.write(id, start, length, r); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session.Offset += length
+	session.ExpiresAt = time.Now().Add(s.ttl)
+	if err := s.repo.WithContext(ctx).SaveUploadSession(*session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// 截掉上次失败时残留的数据后写入分片
+func (s *Sessions) write(id string, start, length int64, r io.Reader) error {
+	f, err := os.OpenFile(s.path(id), os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := f.Truncate(start); err != nil {
+		return err
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	written, err := io.CopyN(f, r, length)
+	if err != nil {
+		return fmt.Errorf("chunk incomplete after %d of %d bytes: %w", written, length, err)
+	}
+	return f.Close()
+}
+
+// Open 打开已经接收完整的文件
+func (s *Sessions) Open(id string) (*os.File, error) {
+	return os.Open(s.path(id))
+}
+
+// Complete 记录上传结果并删除临时文件，会话保留到过期，客户端没有收到响应时可以再次查询
+func (s *Sessions) Complete(ctx context.Context, id string, item *models.Media) (*models.UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	session.Media = item
+	if err := s.repo.WithContext(ctx).SaveUploadSession(*session); err != nil {
+		return nil, err
+	}
+	os.Remove(s.path(id))
+	return session, nil
+}
+
+// Delete 取消上传
+func (s *Sessions) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.repo.WithContext(ctx).DeleteUploadSession(id); err != nil {
+		return ErrSessionNotFound
+	}
+	os.Remove(s.path(id))
+	return nil
+}
+
+// Cleanup 定期删除过期的会话和临时文件，直到 stop 被关闭
+func (s *Sessions) Cleanup(interval time.Duration, stop <-chan struct{}) {
+	log := logging.Component("uploads")
+	ctx := logging.NewContext(context.Background(), log)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			removed, err := s.repo.WithContext(ctx).DeleteUploadSessionsExpiredBefore(time.Now())
+			for _, id := range removed {
+				os.Remove(s.path(id))
+			}
+			s.mu.Unlock()
+			if err != nil {
+				log.Error("clean up upload sessions failed", "error", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Sessions) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
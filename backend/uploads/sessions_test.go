@@ -0,0 +1,153 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+func newTestSessions(t *testing.T, ttl time.Duration) *Sessions {
+	t.Helper()
+	return NewSessions(repository.NewFileRepository(t.TempDir()), filepath.Join(t.TempDir(), ".partial"), ttl)
+}
+
+func TestSessionAppend(t *testing.T) {
+	ctx := context.Background()
+	sessions := newTestSessions(t, time.Hour)
+	session, err := sessions.Create(ctx, "../clips/demo.mp4", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Filename != "demo.mp4" || session.Offset != 0 {
+		t.Fatalf("session = %+v", session)
+	}
+	id := session.ID
+
+	tests := []struct {
+		name   string
+		start  int64
+		chunk  string
+		length int64 // 声明的分片长度，0 表示与内容一致
+		err    error
+		offset int64
+	}{
+		{"first chunk", 0, "0123", 0, nil, 4},
+		{"chunk not at offset", 2, "2345", 0, ErrOffsetMismatch, 4},
+		{"chunk past declared size", 4, "4567890", 0, ErrChunkOutOfRange, 4},
+		// 连接中断时已接收的字节数不变，重试同一分片
+		{"interrupted chunk", 4, "45", 6, io.EOF, 4},
+		{"retried chunk", 4, "456789", 0, nil, 10},
+		{"after last chunk", 10, "x", 0, ErrChunkOutOfRange, 10},
+	}
+	for _, tt := range tests {
+		length := tt.length
+		if length == 0 {
+			length = int64(len(tt.chunk))
+		}
+		_, err := sessions.Append(ctx, id, tt.start, length, strings.NewReader(tt.chunk))
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		got, err := sessions.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Offset != tt.offset {
+			t.Fatalf("%s: offset = %d, want %d", tt.name, got.Offset, tt.offset)
+		}
+	}
+
+	f, err := sessions.Open(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "0123456789" {
+		t.Errorf("file = %q", data)
+	}
+
+	// 完成后删除临时文件，会话保留结果，不能再写入
+	completed, err := sessions.Complete(ctx, id, &models.Media{URL: URL("abc.mp4")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.Media == nil || completed.Media.URL != URL("abc.mp4") {
+		t.Errorf("completed = %+v", completed)
+	}
+	if _, err := os.Stat(sessions.path(id)); !os.IsNotExist(err) {
+		t.Errorf("partial file kept: %v", err)
+	}
+	if _, err := sessions.Append(ctx, id, 10, 0, strings.NewReader("")); !errors.Is(err, ErrSessionComplete) {
+		t.Errorf("append after complete = %v", err)
+	}
+
+	if err := sessions.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Get(ctx, id); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("get after delete = %v", err)
+	}
+	if err := sessions.Delete(ctx, id); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("delete twice = %v", err)
+	}
+}
+
+func TestSessionRejectsConcurrentChunks(t *testing.T) {
+	ctx := context.Background()
+	sessions := newTestSessions(t, time.Hour)
+	session, err := sessions.Create(ctx, "demo.mp4", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := sessions.Append(ctx, session.ID, 0, 4, r)
+		done <- err
+	}()
+	// 第一个分片开始写入后，同一会话的其他分片被拒绝
+	w.Write([]byte("01"))
+	if _, err := sessions.Append(ctx, session.ID, 0, 4, strings.NewReader("0123")); !errors.Is(err, ErrSessionBusy) {
+		t.Errorf("concurrent append = %v, want ErrSessionBusy", err)
+	}
+	w.Write([]byte("23"))
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	got, err := sessions.Get(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Offset != 4 {
+		t.Errorf("offset = %d, want 4", got.Offset)
+	}
+}
+
+func TestSessionExpires(t *testing.T) {
+	ctx := context.Background()
+	sessions := newTestSessions(t, -time.Second)
+	session, err := sessions.Create(ctx, "demo.mp4", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Get(ctx, session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("get expired = %v", err)
+	}
+	if _, err := sessions.Append(ctx, session.ID, 0, 4, strings.NewReader("0123")); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("append expired = %v", err)
+	}
+	if _, err := sessions.Get(ctx, "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("get missing = %v", err)
+	}
+}
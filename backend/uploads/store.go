@@ -0,0 +1,159 @@
+package uploads
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"workout-tracker/metrics"
+)
+
+// URLPrefix 上传文件对外的地址前缀
+const URLPrefix = "/uploads/"
+
+// URL 文件名对应的访问地址
+func URL(name string) string {
+	return URLPrefix + name
+}
+
+// NameFromURL 从 /uploads/xxx 形式的地址中取出文件名
+func NameFromURL(url string) (string, bool) {
+	if !strings.HasPrefix(url, URLPrefix) {
+		return "", false
+	}
+	name := path.Base(strings.TrimPrefix(url, URLPrefix))
+	if name == "." || name == "/" || name == ".." {
+		return "", false
+	}
+	return name, true
+}
+
+// Hash 内容寻址使用的 SHA-256 十六进制值
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashReader 读取全部内容并计算 SHA-256，用于不适合读入内存的视频
+func HashReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Store 上传文件存储，文件名由内容的 SHA-256 决定，相同内容只保存一份
+type Store struct {
+	backend Backend
+
+	// 清理时独占，避免刚被复用或写入的文件在同一时刻被清理
+	mu sync.RWMutex
+}
+
+func NewStore(backend Backend) *Store {
+	return &Store{backend: backend}
+}
+
+// Exists 文件是否已存在
+func (s *Store) Exists(ctx context.Context, name string) bool {
+	_, err := s.backend.Stat(ctx, name)
+	return err == nil
+}
+
+// Open 读取文件
+func (s *Store) Open(ctx context.Context, name string) (io.ReadSeekCloser, Object, error) {
+	return s.backend.Open(ctx, name)
+}
+
+// ReadURL 后端支持时返回文件的预签名地址，返回空字符串表示由服务端代理读取
+func (s *Store) ReadURL(ctx context.Context, name string) (string, error) {
+	presigner, ok := s.backend.(Presigner)
+	if !ok {
+		return "", nil
+	}
+	return presigner.PresignGet(ctx, name)
+}
+
+// Check 就绪检查
+func (s *Store) Check(ctx context.Context) error {
+	return s.backend.Check(ctx)
+}
+
+// ErrNoMigration 当前存储没有需要迁移的原有文件
+var ErrNoMigration = errors.New("uploads are not being migrated")
+
+// Migrate 把原有存储中的文件全部迁移到当前存储
+func (s *Store) Migrate(ctx context.Context) (*MigrationReport, error) {
+	migrating, ok := s.backend.(*Migrating)
+	if !ok {
+		return nil, ErrNoMigration
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return migrating.MigrateAll(ctx)
+}
+
+// Reuse 复用已存在的文件，刷新修改时间使其重新进入保留期；任一文件不存在时返回 false
+func (s *Store) Reuse(ctx context.Context, names ...string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, name := range names {
+		if err := s.backend.Touch(ctx, name); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Save 以指定文件名保存
+func (s *Store) Save(ctx context.Context, name string, r io.Reader, size int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.backend.Put(ctx, name, r, size); err != nil {
+		return err
+	}
+	metrics.AddUpload(size)
+	return nil
+}
+
+// SaveContent 按内容的 SHA-256 命名保存，已有相同内容时直接复用，返回文件名
+func (s *Store) SaveContent(ctx context.Context, ext string, r io.Reader) (string, int64, error) {
+	// 先写入临时文件计算哈希，文件名确定后再写入存储
+	tmp, err := os.CreateTemp("", "workout-upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	name := hex.EncodeToString(hash.Sum(nil)) + ext
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.backend.Touch(ctx, name); err == nil {
+		return name, written, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return "", 0, err
+	}
+	if err := s.backend.Put(ctx, name, tmp, written); err != nil {
+		return "", 0, err
+	}
+	metrics.AddUpload(written)
+	return name, written, nil
+}
@@ -0,0 +1,92 @@
+package uploads
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNameFromURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		name string
+		ok   bool
+	}{
+		{"/uploads/abc.jpg", "abc.jpg", true},
+		{"/uploads/nested/abc.jpg", "abc.jpg", true},
+		{"/uploads/", "", false},
+		{"/uploads/..", "", false},
+		{"https://example.com/abc.jpg", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := NameFromURL(tt.url)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("NameFromURL(%q) = %q, %v", tt.url, name, ok)
+		}
+	}
+}
+
+func TestSaveContentDeduplicates(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(NewLocal(dir))
+	ctx := context.Background()
+
+	name, size, err := store.SaveContent(ctx, ".jpg", strings.NewReader("same bytes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != Hash([]byte("same bytes"))+".jpg" || size != 10 {
+		t.Fatalf("name = %s, size = %d", name, size)
+	}
+
+	// 相同内容再次保存时复用原文件，并刷新修改时间
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := store.SaveContent(ctx, ".jpg", strings.NewReader("same bytes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != name {
+		t.Errorf("second save = %s, want %s", again, name)
+	}
+	info, err := os.Stat(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ModTime().Before(time.Now().Add(-time.Hour)) {
+		t.Errorf("mod time not refreshed: %v", info.ModTime())
+	}
+
+	other, _, err := store.SaveContent(ctx, ".jpg", strings.NewReader("other bytes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == name {
+		t.Error("different content got the same name")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("files = %d, want 2", len(entries))
+	}
+}
+
+func TestReuse(t *testing.T) {
+	store := NewStore(NewLocal(t.TempDir()))
+	ctx := context.Background()
+	if err := store.Save(ctx, "a.jpg", strings.NewReader("a"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if !store.Reuse(ctx, "a.jpg") {
+		t.Error("existing file not reused")
+	}
+	// 缩放版本等任一文件缺失时需要重新生成
+	if store.Reuse(ctx, "a.jpg", "a-320w.jpg") {
+		t.Error("reused with a missing file")
+	}
+}
@@ -0,0 +1,126 @@
+package uploads
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SniffLen 判断文件类型需要读取的字节数
+const SniffLen = 512
+
+// DefaultMaxSize 默认单个文件大小上限
+const DefaultMaxSize = 20 << 20
+
+// DefaultMaxVideoSize 默认单个视频的大小上限
+const DefaultMaxVideoSize = 200 << 20
+
+// Limits 单个文件的大小上限，视频单独设置
+type Limits struct {
+	File  int64
+	Video int64
+}
+
+// Max 指定类型的大小上限
+func (l Limits) Max(mime string) int64 {
+	if strings.HasPrefix(mime, "video/") {
+		return l.Video
+	}
+	return l.File
+}
+
+// Largest 所有类型中最大的上限，用于在判断类型之前限制请求体
+func (l Limits) Largest() int64 {
+	return max(l.File, l.Video)
+}
+
+// Type 允许上传的文件类型
+type Type struct {
+	MIME       string
+	Extensions []string // 第一个为保存时使用的扩展名
+}
+
+// Types 允许上传的图片和视频，按文件内容判断，不信任客户端的扩展名
+var Types = []Type{
+	{MIME: "image/jpeg", Extensions: []string{".jpg", ".jpeg"}},
+	{MIME: "image/png", Extensions: []string{".png"}},
+	{MIME: "image/gif", Extensions: []string{".gif"}},
+	{MIME: "image/webp", Extensions: []string{".webp"}},
+	{MIME: "video/mp4", Extensions: []string{".mp4", ".m4v"}},
+	{MIME: "video/webm", Extensions: []string{".webm"}},
+}
+
+var (
+	ErrUnsupportedType   = errors.New("unsupported file type")
+	ErrExtensionMismatch = errors.New("file extension does not match file content")
+)
+
+// Extension 保存时使用的扩展名
+func (t *Type) Extension() string {
+	return t.Extensions[0]
+}
+
+func (t *Type) hasExtension(ext string) bool {
+	for _, e := range t.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect 根据文件开头的内容判断类型，文件名带扩展名时必须与内容一致
+func Detect(head []byte, filename string) (*Type, error) {
+	mime := http.DetectContentType(head)
+	var detected *Type
+	for i := range Types {
+		if Types[i].MIME == mime {
+			detected = &Types[i]
+			break
+		}
+	}
+	if detected == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, mime)
+	}
+
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" && !detected.hasExtension(ext) {
+		return nil, fmt.Errorf("%w: %s is %s", ErrExtensionMismatch, ext, detected.MIME)
+	}
+	return detected, nil
+}
+
+// ByExtension 根据已保存文件的扩展名查找类型
+func ByExtension(filename string) (*Type, bool) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for i := range Types {
+		if Types[i].hasExtension(ext) {
+			return &Types[i], true
+		}
+	}
+	return nil, false
+}
+
+// ParseSize 解析 10MB、512KB、1048576 形式的大小
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,120 @@
+package uploads
+
+import (
+	"errors"
+	"testing"
+)
+
+var (
+	jpegHead = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	pngHead  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	mp4Head  = []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     []byte
+		filename string
+		mime     string
+		err      error
+	}{
+		{"jpeg", jpegHead, "photo.jpg", "image/jpeg", nil},
+		{"alternate extension", jpegHead, "photo.JPEG", "image/jpeg", nil},
+		{"no extension", pngHead, "blob", "image/png", nil},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "a.gif", "image/gif", nil},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "a.webp", "image/webp", nil},
+		{"mp4", mp4Head, "clip.m4v", "video/mp4", nil},
+		{"webm", []byte("\x1a\x45\xdf\xa3\x00\x00"), "clip.webm", "video/webm", nil},
+		{"renamed image", pngHead, "photo.jpg", "", ErrExtensionMismatch},
+		{"video named as image", mp4Head, "clip.png", "", ErrExtensionMismatch},
+		// 内容不是图片时不看扩展名
+		{"html", []byte("<!DOCTYPE html><script>alert(1)</script>"), "photo.jpg", "", ErrUnsupportedType},
+		{"svg", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`), "icon.svg", "", ErrUnsupportedType},
+		{"empty", nil, "a.png", "", ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(tt.head, tt.filename)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.MIME != tt.mime {
+				t.Errorf("mime = %s, want %s", got.MIME, tt.mime)
+			}
+		})
+	}
+}
+
+func TestByExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		mime string
+		ok   bool
+	}{
+		{"abc.JPG", "image/jpeg", true},
+		{"abc.m4v", "video/mp4", true},
+		{"abc.html", "", false},
+		{"abc", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ByExtension(tt.name)
+		if ok != tt.ok || ok && got.MIME != tt.mime {
+			t.Errorf("ByExtension(%q) = %v, %v", tt.name, got, ok)
+		}
+	}
+}
+
+func TestContentHeaders(t *testing.T) {
+	tests := []struct {
+		name, contentType, disposition string
+	}{
+		{"abc.png", "image/png", `inline; filename=abc.png`},
+		{"abc.mp4", "video/mp4", `inline; filename=abc.mp4`},
+		{"page.html", "application/octet-stream", `attachment; filename=page.html`},
+	}
+	for _, tt := range tests {
+		contentType, disposition := ContentHeaders(tt.name)
+		if contentType != tt.contentType || disposition != tt.disposition {
+			t.Errorf("ContentHeaders(%q) = %q, %q", tt.name, contentType, disposition)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		size  int64
+		ok    bool
+	}{
+		{"1048576", 1 << 20, true},
+		{"10MB", 10 << 20, true},
+		{" 512 kb ", 512 << 10, true},
+		{"2GB", 2 << 30, true},
+		{"100B", 100, true},
+		{"", 0, false},
+		{"0", 0, false},
+		{"-5MB", 0, false},
+		{"1.5MB", 0, false},
+		{"ten", 0, false},
+	}
+	for _, tt := range tests {
+		size, err := ParseSize(tt.value)
+		if (err == nil) != tt.ok || size != tt.size {
+			t.Errorf("ParseSize(%q) = %d, %v", tt.value, size, err)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{File: 10, Video: 100}
+	if limits.Max("image/png") != 10 || limits.Max("video/webm") != 100 || limits.Largest() != 100 {
+		t.Errorf("limits = %d %d %d", limits.Max("image/png"), limits.Max("video/webm"), limits.Largest())
+	}
+}
@@ -0,0 +1,348 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"workout-tracker/events"
+	"workout-tracker/logging"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+
+	"github.com/google/uuid"
+)
+
+// 投递请求头
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	maxAttempts = 6
+	baseDelay   = 10 * time.Second
+	// 成功的投递记录保留7天
+	deliveryRetention = 7 * 24 * time.Hour
+	// 同时投递的订阅数
+	maxWorkers = 4
+)
+
+// Dispatcher 订阅领域事件并投递给 Webhook，失败时按指数退避重试
+type Dispatcher struct {
+	repo   *repository.FileRepository
+	client *http.Client
+	log    *slog.Logger
+
+	// 保护投递记录文件的读写
+	mu   sync.Mutex
+	wake chan struct{}
+
+	// 正在投递的订阅，同一订阅的记录依次投递
+	busyMu   sync.Mutex
+	busy     map[string]bool
+	workers  chan struct{}
+	inflight sync.WaitGroup
+}
+
+func NewDispatcher(repo *repository.FileRepository) *Dispatcher {
+	log := logging.Component("webhooks")
+	return &Dispatcher{
+		// 后台任务没有请求上下文，仓库日志带上组件名
+		repo:    repo.WithContext(logging.NewContext(context.Background(), log)),
+		client:  newClient(),
+		log:     log,
+		wake:    make(chan struct{}, 1),
+		busy:    make(map[string]bool),
+		workers: make(chan struct{}, maxWorkers),
+	}
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body)
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run 为事件创建投递记录，直到 stop 被关闭。投递在单独的 goroutine 中进行，
+// 接收方响应慢时不会阻塞事件订阅，避免总线因缓冲区已满丢弃后续事件
+func (d *Dispatcher) Run(bus *events.Bus, stop <-chan struct{}) {
+	ch, unsubscribe := bus.Subscribe(256)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.deliver(stop)
+	}()
+	defer func() { <-done }()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case event := <-ch:
+			if err := d.enqueue(event); err != nil {
+				d.log.Error("enqueue webhook deliveries failed", "event_id", event.ID, "event_type", event.Type, "error", err)
+			}
+			d.notify()
+		case <-cleanup.C:
+			if _, err := d.repo.DeleteSucceededDeliveriesBefore(time.Now().Add(-deliveryRetention)); err != nil {
+				d.log.Error("clean up webhook deliveries failed", "error", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// 投递到期的记录，直到 stop 被关闭。关闭时取消进行中的请求并等待投递 goroutine 退出
+func (d *Dispatcher) deliver(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer d.inflight.Wait()
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.wake:
+			d.deliverDue(ctx)
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// 唤醒投递 goroutine，已有待处理的唤醒时不再重复
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 为订阅了该事件的 Webhook 创建投递记录
+func (d *Dispatcher) enqueue(event events.Event) error {
+	subscriptions, err := d.repo.GetAllWebhooks()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, sub := range subscriptions {
+		if !sub.Active || !subscribed(sub, event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  event.Time,
+			CreatedAt:      time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.repo.SaveWebhookDeliveries(deliveries...)
+}
+
+func subscribed(sub models.WebhookSubscription, eventType string) bool {
+	for _, t := range sub.Events {
+		if t == "*" || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// 按订阅分组投递到期的记录。每个订阅在单独的 goroutine 中依次投递，最多同时投递
+// maxWorkers 个订阅，接收方响应慢或无响应时只会推迟它自己的投递
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	d.mu.Lock()
+	deliveries, err := d.repo.GetAllWebhookDeliveries()
+	d.mu.Unlock()
+	if err != nil {
+		d.log.Error("load webhook deliveries failed", "error", err)
+		return
+	}
+
+	now := time.Now()
+	var order []string
+	due := make(map[string][]models.WebhookDelivery)
+	for _, delivery := range deliveries {
+		if delivery.Status != models.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		if _, ok := due[delivery.SubscriptionID]; !ok {
+			order = append(order, delivery.SubscriptionID)
+		}
+		due[delivery.SubscriptionID] = append(due[delivery.SubscriptionID], delivery)
+	}
+
+	for _, subscriptionID := range order {
+		// 订阅正在投递或没有空闲的 worker 时留到下一轮
+		if !d.claim(subscriptionID) {
+			continue
+		}
+		d.inflight.Add(1)
+		go func(subscriptionID string, deliveries []models.WebhookDelivery) {
+			defer d.inflight.Done()
+			defer d.release(subscriptionID)
+			for _, delivery := range deliveries {
+				if ctx.Err() != nil {
+					return
+				}
+				d.save(d.attempt(ctx, delivery))
+			}
+		}(subscriptionID, due[subscriptionID])
+	}
+}
+
+func (d *Dispatcher) claim(subscriptionID string) bool {
+	d.busyMu.Lock()
+	defer d.busyMu.Unlock()
+	if d.busy[subscriptionID] {
+		return false
+	}
+	select {
+	case d.workers <- struct{}{}:
+		d.busy[subscriptionID] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// 释放 worker 并唤醒投递，处理因没有空闲 worker 而等待的订阅
+func (d *Dispatcher) release(subscriptionID string) {
+	d.busyMu.Lock()
+	delete(d.busy, subscriptionID)
+	<-d.workers
+	d.busyMu.Unlock()
+	d.notify()
+}
+
+func (d *Dispatcher) save(delivery models.WebhookDelivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.repo.SaveWebhookDeliveries(delivery); err != nil {
+		d.log.Error("save webhook delivery failed", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) models.WebhookDelivery {
+	sub, err := d.repo.GetWebhookByID(delivery.SubscriptionID)
+	if err != nil {
+		delivery.Attempts++
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = "subscription removed"
+		return delivery
+	}
+
+	statusCode, err := d.send(ctx, sub, delivery)
+	if ctx.Err() != nil {
+		// 服务关闭时取消的请求不计入尝试次数
+		return delivery
+	}
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return delivery
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = models.DeliveryDead
+		return delivery
+	}
+	// 10s, 20s, 40s, 80s, 160s
+	delivery.NextAttemptAt = time.Now().Add(baseDelay << (delivery.Attempts - 1))
+	return delivery
+}
+
+func (d *Dispatcher) send(ctx context.Context, sub *models.WebhookSubscription, delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "workout-tracker-webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Retry 将死信记录重新放回投递队列
+func (d *Dispatcher) Retry(id string) (*models.WebhookDelivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery, err := d.repo.GetWebhookDeliveryByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status != models.DeliveryDead {
+		return nil, fmt.Errorf("only dead deliveries can be retried")
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := d.repo.SaveWebhookDeliveries(*delivery); err != nil {
+		return nil, err
+	}
+
+	d.notify()
+	return delivery, nil
+}
@@ -0,0 +1,396 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"workout-tracker/events"
+	"workout-tracker/models"
+	"workout-tracker/repository"
+)
+
+func newTestDispatcher(t *testing.T, url string, eventTypes ...string) (*Dispatcher, *repository.FileRepository) {
+	t.Helper()
+	// 测试的接收方监听在 127.0.0.1
+	AllowPrivateTargets = true
+	t.Cleanup(func() { AllowPrivateTargets = false })
+	repo := repository.NewFileRepository(t.TempDir())
+	if len(eventTypes) == 0 {
+		eventTypes = []string{"*"}
+	}
+	sub := models.WebhookSubscription{ID: "hook", URL: url, Events: eventTypes, Secret: "s3cret", Active: true, CreatedAt: time.Now()}
+	if err := repo.SaveWebhook(sub); err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher(repo), repo
+}
+
+// 投递到期的记录并等待投递完成
+func deliverNow(d *Dispatcher) {
+	d.deliverDue(context.Background())
+	d.inflight.Wait()
+}
+
+func onlyDelivery(t *testing.T, repo *repository.FileRepository) models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := repo.GetAllWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func TestSignatureVerifiesOnReceiver(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		verified bool
+		header   http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		header = r.Header.Clone()
+		verified = r.Header.Get(SignatureHeader) == Sign("s3cret", r.Header.Get(TimestampHeader), body)
+	}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL)
+	if err := d.enqueue(events.New(events.SessionStarted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	deliverNow(d)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !verified {
+		t.Fatalf("signature %q does not verify", header.Get(SignatureHeader))
+	}
+	if header.Get(EventHeader) != events.SessionStarted || header.Get(DeliveryHeader) == "" {
+		t.Errorf("missing event headers: %v", header)
+	}
+	delivery := onlyDelivery(t, repo)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusOK {
+		t.Errorf("delivery = %+v", delivery)
+	}
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		secret, timestamp, body string
+		want                    string
+	}{
+		// python: hmac.new(b"key", b"1700000000." + body, hashlib.sha256).hexdigest()
+		{"key", "1700000000", `{"a":1}`, "sha256=a438e398bfafc57e4396bb7fc2304422f0f768e965d073ca313cb52e22e6ad03"},
+	}
+	for _, tt := range tests {
+		if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+			t.Errorf("Sign(%q, %q, %q) = %q, want %q", tt.secret, tt.timestamp, tt.body, got, tt.want)
+		}
+		if Sign("other", tt.timestamp, []byte(tt.body)) == tt.want || Sign(tt.secret, "1700000001", []byte(tt.body)) == tt.want {
+			t.Error("signature does not depend on secret and timestamp")
+		}
+	}
+}
+
+func TestBackoffAndDeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL)
+	if err := d.enqueue(events.New(events.SessionStarted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	delivery := onlyDelivery(t, repo)
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		before := time.Now()
+		delivery = d.attempt(context.Background(), delivery)
+		if delivery.Attempts != attempt || delivery.LastStatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d: %+v", attempt, delivery)
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		if delivery.Status != models.DeliveryPending {
+			t.Fatalf("attempt %d: status = %s, want pending", attempt, delivery.Status)
+		}
+		want := baseDelay << (attempt - 1)
+		if wait := delivery.NextAttemptAt.Sub(before); wait < want || wait > want+time.Second {
+			t.Errorf("attempt %d: next attempt in %v, want %v", attempt, wait, want)
+		}
+	}
+	if delivery.Status != models.DeliveryDead {
+		t.Fatalf("status after %d attempts = %s, want dead", maxAttempts, delivery.Status)
+	}
+}
+
+func TestDueDeliveriesOnly(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL)
+	if err := d.enqueue(events.New(events.SessionStarted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	deliverNow(d)
+	// 第一次失败后 10 秒内不会重试
+	deliverNow(d)
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("receiver hit %d times, want 1", n)
+	}
+	if delivery := onlyDelivery(t, repo); delivery.Status != models.DeliveryPending || delivery.Attempts != 1 {
+		t.Errorf("delivery = %+v", delivery)
+	}
+}
+
+func TestRetryDeadDelivery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL)
+	dead := models.WebhookDelivery{ID: "d1", SubscriptionID: "hook", Payload: "{}", Status: models.DeliveryDead, Attempts: maxAttempts}
+	pending := models.WebhookDelivery{ID: "d2", SubscriptionID: "hook", Payload: "{}", Status: models.DeliveryPending, NextAttemptAt: time.Now().Add(time.Hour)}
+	if err := repo.SaveWebhookDeliveries(dead, pending); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Retry("d2"); err == nil {
+		t.Error("retrying a pending delivery should fail")
+	}
+	retried, err := d.Retry("d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != models.DeliveryPending || retried.Attempts != 0 {
+		t.Errorf("retried = %+v", retried)
+	}
+	deliverNow(d)
+	delivery, err := repo.GetWebhookDeliveryByID("d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != models.DeliverySucceeded {
+		t.Errorf("status after retry = %s", delivery.Status)
+	}
+}
+
+func TestSlowReceiverDoesNotBlockEnqueue(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan struct{}, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL)
+	bus := events.NewBus()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(bus, stop)
+	}()
+	// 先放行接收方，再等待 Run 退出
+	defer func() {
+		close(release)
+		close(stop)
+		<-done
+	}()
+
+	// 等待订阅生效后发布第一个事件，接收方会一直不返回
+	waitFor(t, func() bool {
+		bus.Publish(events.New(events.SessionStarted, "first", nil))
+		select {
+		case <-received:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	})
+
+	// 投递被阻塞时，后续事件仍然要立即创建投递记录
+	bus.Publish(events.New(events.SessionCompleted, "second", nil))
+	waitFor(t, func() bool {
+		deliveries, err := repo.GetAllWebhookDeliveries()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, delivery := range deliveries {
+			if delivery.EventType == events.SessionCompleted {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func waitFor(t *testing.T, ok func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSubscriptionFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL, events.SessionCompleted)
+	if err := d.enqueue(events.New(events.SessionStarted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.enqueue(events.New(events.SessionCompleted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if delivery := onlyDelivery(t, repo); delivery.EventType != events.SessionCompleted {
+		t.Errorf("delivered %s", delivery.EventType)
+	}
+}
+
+func TestSlowSubscriberDoesNotDelayOthers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	d, repo := newTestDispatcher(t, slow.URL)
+	// 慢的投递在接收方返回后才会写入投递记录
+	t.Cleanup(d.inflight.Wait)
+	if err := repo.SaveWebhook(models.WebhookSubscription{ID: "fast", URL: fast.URL, Events: []string{"*"}, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.enqueue(events.New(events.SessionCompleted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	d.deliverDue(context.Background())
+
+	waitFor(t, func() bool {
+		deliveries, err := repo.GetAllWebhookDeliveries()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, delivery := range deliveries {
+			if delivery.SubscriptionID == "fast" {
+				return delivery.Status == models.DeliverySucceeded
+			}
+		}
+		return false
+	})
+}
+
+func TestDeliveryWorkersAreBounded(t *testing.T) {
+	var active, peak atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		<-release
+	}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL)
+	for i := 0; i < maxWorkers+2; i++ {
+		sub := models.WebhookSubscription{ID: "hook" + strconv.Itoa(i), URL: server.URL, Events: []string{"*"}, Active: true}
+		if err := repo.SaveWebhook(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.enqueue(events.New(events.SessionCompleted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	d.deliverDue(context.Background())
+	waitFor(t, func() bool { return active.Load() == maxWorkers })
+	// 没有空闲的 worker 时不会再开始新的投递
+	d.deliverDue(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	if n := active.Load(); n != maxWorkers {
+		t.Fatalf("%d deliveries in flight, want %d", n, maxWorkers)
+	}
+
+	close(release)
+	// 释放的 worker 继续投递剩下的订阅
+	waitFor(t, func() bool {
+		d.deliverDue(context.Background())
+		deliveries, err := repo.GetAllWebhookDeliveries()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, delivery := range deliveries {
+			if delivery.Status != models.DeliverySucceeded {
+				return false
+			}
+		}
+		return len(deliveries) == maxWorkers+3
+	})
+	d.inflight.Wait()
+	if n := peak.Load(); n != maxWorkers {
+		t.Errorf("peak concurrency %d, want %d", n, maxWorkers)
+	}
+}
+
+func TestSubscriptionDeliveriesAreSequential(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	var active atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if active.Add(1) > 1 {
+			t.Error("concurrent deliveries to one subscription")
+		}
+		defer active.Add(-1)
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		order = append(order, r.Header.Get(EventHeader))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	d, _ := newTestDispatcher(t, server.URL)
+	sent := []string{events.SessionStarted, events.SetLogged, events.SessionCompleted}
+	for _, eventType := range sent {
+		if err := d.enqueue(events.New(eventType, "s1", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliverNow(d)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(order, ",") != strings.Join(sent, ",") {
+		t.Errorf("delivered %v, want %v", order, sent)
+	}
+}
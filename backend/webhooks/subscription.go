@@ -0,0 +1,34 @@
+package webhooks
+
+import (
+	"fmt"
+	"net/url"
+	"workout-tracker/events"
+)
+
+// SubscriptionRequest 创建或更新 Webhook 订阅的请求
+type SubscriptionRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+func (req SubscriptionRequest) Validate() error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if err := checkTarget(u.Hostname()); err != nil {
+		return err
+	}
+	if len(req.Events) == 0 {
+		return fmt.Errorf("events must not be empty")
+	}
+	for _, t := range req.Events {
+		if t != "*" && !events.IsType(t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	return nil
+}
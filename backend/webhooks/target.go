@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// AllowPrivateTargets 是否允许投递到回环、内网和链路本地地址，由 WEBHOOK_ALLOW_PRIVATE 设置，
+// 默认不允许，避免订阅被用来访问服务器所在网络中的内部服务
+var AllowPrivateTargets = false
+
+var errPrivateTarget = errors.New("webhook target is a private, loopback or link-local address")
+
+// 除 net.IP 自带判断外不允许的网段：本网络和运营商级 NAT
+var blockedNetworks = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),
+	mustCIDR("100.64.0.0/10"),
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// 是否为不允许投递的地址
+func blockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsMulticast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 检查订阅地址的主机，主机名解析出的任一地址不允许时拒绝
+func checkTarget(host string) error {
+	if AllowPrivateTargets {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if blockedIP(ip) {
+			return errPrivateTarget
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %q", host)
+	}
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return errPrivateTarget
+		}
+	}
+	return nil
+}
+
+// 投递时再检查实际连接的地址，防止主机名在创建订阅后被解析到内网地址，或被重定向到内网
+func dialControl(network, address string, _ syscall.RawConn) error {
+	if AllowPrivateTargets {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+		return errPrivateTarget
+	}
+	return nil
+}
+
+// 投递使用的客户端，不使用代理，以便检查的是接收方的地址
+func newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: dialControl}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost:   2,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"workout-tracker/events"
+	"workout-tracker/models"
+)
+
+func TestValidateRejectsPrivateTargets(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://93.184.216.34/hook", true},
+		{"http://[2606:4700:4700::1111]:8080/hook", true},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://localhost/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.3.4/hook", false},
+		{"http://192.168.1.10/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"ftp://93.184.216.34/hook", false},
+	}
+	for _, tt := range tests {
+		err := SubscriptionRequest{URL: tt.url, Events: []string{"*"}}.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("Validate(%s) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func TestAllowPrivateTargets(t *testing.T) {
+	AllowPrivateTargets = true
+	defer func() { AllowPrivateTargets = false }()
+	if err := (SubscriptionRequest{URL: "http://192.168.1.10/hook", Events: []string{"*"}}).Validate(); err != nil {
+		t.Errorf("private target rejected with WEBHOOK_ALLOW_PRIVATE: %v", err)
+	}
+}
+
+// 订阅创建后目标变为内网地址时，投递在连接前被拒绝
+func TestDeliveryRefusesPrivateAddress(t *testing.T) {
+	var hit bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+
+	d, repo := newTestDispatcher(t, server.URL)
+	AllowPrivateTargets = false
+	if err := d.enqueue(events.New(events.SessionStarted, "s1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	deliverNow(d)
+
+	if hit {
+		t.Fatal("receiver on loopback was reached")
+	}
+	delivery := onlyDelivery(t, repo)
+	if delivery.Status != models.DeliveryPending || !strings.Contains(delivery.LastError, errPrivateTarget.Error()) {
+		t.Errorf("delivery = %+v", delivery)
+	}
+	if !errors.Is(dialControl("tcp", "10.1.2.3:80", nil), errPrivateTarget) || dialControl("tcp", "93.184.216.34:443", nil) != nil {
+		t.Error("dialControl does not check the connected address")
+	}
+}